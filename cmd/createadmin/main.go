@@ -0,0 +1,111 @@
+// Command createadmin creates a super admin account, or promotes an existing one.
+// It is needed when BOOTSTRAP_SUPERADMIN=false, since the first sign-up then stays an ordinary user.
+//
+// Usage:
+//
+//	go run ./cmd/createadmin -email admin@example.com -name "Admin" -password "..."
+//
+// The password may also be supplied through the ADMIN_PASSWORD environment variable.
+// Without a password the account can still sign in with a magic link or OAuth.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/noruj-official/full-stack-go-template/internal/config"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/password"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	email := flag.String("email", "", "email address of the super admin (required)")
+	name := flag.String("name", "Admin", "display name for a newly created account")
+	pass := flag.String("password", os.Getenv("ADMIN_PASSWORD"), "password for a newly created account")
+	flag.Parse()
+
+	if strings.TrimSpace(*email) == "" {
+		flag.Usage()
+		return fmt.Errorf("-email is required")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx := context.Background()
+
+	db, err := postgres.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.RunMigrations(ctx); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	userRepo := postgres.NewUserRepository(db)
+
+	existing, err := userRepo.GetByEmail(ctx, *email)
+	if err != nil && !domain.IsNotFoundError(err) {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if existing != nil {
+		if existing.Role == domain.RoleSuperAdmin {
+			log.Printf("%s is already a super admin", existing.Email)
+			return nil
+		}
+		existing.Role = domain.RoleSuperAdmin
+		if err := userRepo.Update(ctx, existing); err != nil {
+			return fmt.Errorf("failed to promote user: %w", err)
+		}
+		log.Printf("Promoted %s to super admin", existing.Email)
+		return nil
+	}
+
+	var passwordHash string
+	if *pass != "" {
+		settings, err := postgres.NewSettingsRepository(db).GetAll(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load password policy: %w", err)
+		}
+		if err := domain.PasswordPolicyFromMap(settings).Check("password", *pass); err != nil {
+			return err
+		}
+		hasher, err := password.New(cfg.Auth.PasswordHasher, cfg.Auth.BcryptCost)
+		if err != nil {
+			return fmt.Errorf("invalid password hasher: %w", err)
+		}
+		passwordHash, err = hasher.Hash(*pass)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+	}
+
+	user := domain.NewUser(*email, *name, passwordHash, domain.RoleSuperAdmin)
+	user.EmailVerified = true
+	if err := user.Validate(); err != nil {
+		return err
+	}
+
+	if err := userRepo.Create(ctx, user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	log.Printf("Created super admin %s", user.Email)
+	return nil
+}
@@ -0,0 +1,65 @@
+// Command maintenance runs one-off data maintenance tasks against the database.
+//
+// Usage:
+//
+//	go run ./cmd/maintenance -task media-dimensions
+//
+// Tasks:
+//
+//	media-dimensions  record the width and height of images uploaded before they were captured
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/noruj-official/full-stack-go-template/internal/config"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	task := flag.String("task", "", "maintenance task to run (required): media-dimensions")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx := context.Background()
+
+	db, err := postgres.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.RunMigrations(ctx); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	switch *task {
+	case "media-dimensions":
+		mediaService := service.NewMediaService(postgres.NewMediaRepository(db))
+		n, err := mediaService.BackfillDimensions(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to backfill media dimensions: %w", err)
+		}
+		log.Printf("Recorded dimensions for %d images", n)
+		return nil
+	case "":
+		flag.Usage()
+		return fmt.Errorf("-task is required")
+	default:
+		return fmt.Errorf("unknown task %q", *task)
+	}
+}
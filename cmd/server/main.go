@@ -3,12 +3,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,17 +18,99 @@ import (
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/handler"
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/captcha"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/password"
+	redisclient "github.com/noruj-official/full-stack-go-template/internal/pkg/redis"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/startup"
+	"github.com/noruj-official/full-stack-go-template/internal/repository"
 	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	redisrepo "github.com/noruj-official/full-stack-go-template/internal/repository/redis"
 	"github.com/noruj-official/full-stack-go-template/internal/service"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/time/rate"
 )
 
+// options are the command-line flags; with none set the server runs normally.
+type options struct {
+	migrateOnly bool
+	// migrateDown reverts the most recently applied migration and exits
+	migrateDown bool
+	// migrateDownTo, when set, reverts every migration above this version and exits
+	migrateDownTo string
+}
+
+// Startup steps that must finish before /ready reports the instance ready.
+const (
+	startupMigrations  = "migrations"
+	startupFeatureSync = "feature sync"
+)
+
+// featureSyncRetryInterval is how long to wait before retrying a failed feature flag sync.
+const featureSyncRetryInterval = 5 * time.Second
+
 func main() {
-	if err := run(); err != nil {
+	var opts options
+	flag.BoolVar(&opts.migrateOnly, "migrate", false, "run database migrations and exit without starting the server (or set MIGRATE_ONLY=true)")
+	flag.BoolVar(&opts.migrateDown, "migrate-down", false, "revert the most recently applied migration and exit")
+	flag.StringVar(&opts.migrateDownTo, "migrate-down-to", "", "revert every migration above `version` (0 reverts all) and exit")
+	flag.Parse()
+
+	if err := run(opts); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run() error {
+// retryFeatureSync keeps syncing feature flags after the first attempt at startup failed,
+// marking the step done once it succeeds. Until then the instance serves requests but
+// /ready reports it not ready, so a load balancer keeps traffic away.
+func retryFeatureSync(ctx context.Context, featureService service.FeatureService, features map[string]domain.FeatureConfig, tracker *startup.Tracker) {
+	ticker := time.NewTicker(featureSyncRetryInterval)
+	defer ticker.Stop()
+
+	for attempt := 2; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := featureService.SyncFeatures(ctx, features); err != nil {
+			log.Printf("Startup step pending: %s (attempt %d failed, retrying in %s): %v", startupFeatureSync, attempt, featureSyncRetryInterval, err)
+			continue
+		}
+		log.Printf("Feature flags synced after %d attempts", attempt)
+		tracker.Done(startupFeatureSync)
+		return
+	}
+}
+
+// migrateDown reverts migrations as requested by -migrate-down or -migrate-down-to.
+func migrateDown(ctx context.Context, db *postgres.DB, opts options) error {
+	var reverted []int
+	var err error
+	if opts.migrateDownTo != "" {
+		target, convErr := strconv.Atoi(opts.migrateDownTo)
+		if convErr != nil {
+			return fmt.Errorf("invalid -migrate-down-to %q: must be a version number", opts.migrateDownTo)
+		}
+		reverted, err = db.MigrateDown(ctx, target)
+	} else {
+		reverted, err = db.MigrateDownOne(ctx)
+	}
+
+	for _, v := range reverted {
+		log.Printf("Reverted migration %03d", v)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revert migrations: %w", err)
+	}
+	if len(reverted) == 0 {
+		log.Println("No migrations to revert")
+	}
+	return nil
+}
+
+func run(opts options) error {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -38,8 +121,8 @@ func run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Connect to database
-	db, err := postgres.New(ctx, cfg.Database.URL)
+	// Connect to database, waiting for it to come up if the server started first
+	db, err := postgres.NewWithRetry(ctx, cfg.Database.URL, cfg.Database.ConnectAttempts, cfg.Database.ConnectInterval)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -47,16 +130,44 @@ func run() error {
 
 	log.Println("Connected to database")
 
+	if opts.migrateDown || opts.migrateDownTo != "" {
+		return migrateDown(ctx, db, opts)
+	}
+
+	startupTracker := startup.New(startupMigrations, startupFeatureSync)
+
 	// Run migrations
 	if err := db.RunMigrations(ctx); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
 	log.Println("Database migrations completed")
+	startupTracker.Done(startupMigrations)
+
+	// In migrate-only mode (e.g. a pre-deploy job or init container) stop here
+	if opts.migrateOnly || cfg.Database.MigrateOnly {
+		return nil
+	}
 
 	// Initialize repositories
 	userRepo := postgres.NewUserRepository(db)
 	sessionRepo := postgres.NewSessionRepository(db)
+
+	// Sessions are read on every authenticated request; optionally cache them in Redis
+	var sessionStore repository.SessionStore = sessionRepo
+	if cfg.Database.RedisURL != "" {
+		redisClient, err := redisclient.New(cfg.Database.RedisURL)
+		if err != nil {
+			return err
+		}
+		defer redisClient.Close()
+
+		if err := redisClient.Ping(ctx); err != nil {
+			log.Printf("Redis is unreachable, sessions will be read from PostgreSQL until it recovers: %v", err)
+		}
+		sessionStore = redisrepo.NewSessionStore(redisClient, sessionRepo, cfg.Auth.SessionCacheTTL)
+		log.Println("Session cache enabled (Redis)")
+	}
 	passwordResetRepo := postgres.NewPasswordResetRepository(db)
 	activityRepo := postgres.NewActivityLogRepository(db)
 	auditRepo := postgres.NewAuditLogRepository(db)
@@ -64,19 +175,39 @@ func run() error {
 	oauthRepo := postgres.NewOAuthRepository(db, cfg.Auth.Secret)
 	blogRepo := postgres.NewBlogRepository(db)
 	mediaRepo := postgres.NewMediaRepository(db)
+	settingsRepo := postgres.NewSettingsRepository(db)
+	twoFactorRepo := postgres.NewTwoFactorRepository(db, cfg.Auth.Secret)
+	emailChangeRepo := postgres.NewEmailChangeRepository(db)
 
 	// Initialize services
-	emailService := service.NewResendEmailService(cfg.Email.ResendAPIKey, cfg.Email.ResendFromEmail, cfg.App.URL)
-	userService := service.NewUserService(userRepo)
+	passwordHasher, err := password.New(cfg.Auth.PasswordHasher, cfg.Auth.BcryptCost)
+	if err != nil {
+		return fmt.Errorf("invalid password hasher: %w", err)
+	}
+
+	emailService := service.NewResendEmailService(cfg.Email.ResendAPIKey, cfg.Email.ResendFromEmail, cfg.App.PublicURL)
 	featureService := service.NewFeatureService(featureRepo, oauthRepo)
-	authService := service.NewAuthService(userRepo, sessionRepo, passwordResetRepo, oauthRepo, emailService, featureService, cfg.App.URL, cfg.Auth.Secret)
+	sessionCache := service.NewSessionCache(cfg.Auth.SessionValidationCacheTTL)
+	settingsService := service.NewSettingsService(settingsRepo)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, featureService, cfg.App.Name)
+	authService := service.NewAuthService(userRepo, sessionStore, sessionCache, passwordResetRepo, oauthRepo, emailService, featureService, settingsService, twoFactorService, passwordHasher, cfg.App.PublicURL, cfg.Auth.Secret, cfg.Auth.SessionTTL, cfg.Auth.BootstrapSuperAdmin)
 	activityService := service.NewActivityService(activityRepo)
 	auditService := service.NewAuditService(auditRepo)
+	userService := service.NewUserService(userRepo, oauthRepo, emailChangeRepo, sessionStore, sessionCache, activityService, settingsService, featureService, emailService, passwordHasher)
 	mediaService := service.NewMediaService(mediaRepo)
 	blogService := service.NewBlogService(blogRepo, mediaService)
 
-	// SyncFeatures feature flags
-	err = featureService.SyncFeatures(context.Background(), map[string]domain.FeatureConfig{
+	// Start background jobs; they stop when ctx is cancelled on shutdown
+	if cfg.Email.ActivityDigestInterval > 0 {
+		service.NewActivityDigestJob(userRepo, activityService, emailService, cfg.Email.ActivityDigestInterval).Start(ctx)
+	}
+	if cfg.Auth.SessionCleanupInterval > 0 {
+		service.NewSessionCleanupJob(sessionRepo, cfg.Auth.SessionCleanupInterval).Start(ctx)
+	}
+
+	// Sync feature flags. A failure doesn't stop the server, but it stays not ready until
+	// a retry succeeds.
+	features := map[string]domain.FeatureConfig{
 		domain.FeatureThemeManagement: {
 			Description:    "Enables theme switching logic",
 			DefaultEnabled: true,
@@ -97,103 +228,180 @@ func run() error {
 			Description:    "Enables OAuth authentication with third-party providers",
 			DefaultEnabled: true,
 		},
-	})
+		domain.FeatureWelcomeEmail: {
+			Description:    "Sends a welcome email once a new user verifies their email address",
+			DefaultEnabled: true,
+		},
+		domain.FeatureBlog: {
+			Description:    "Enables the public blog and blog management",
+			DefaultEnabled: true,
+		},
+		domain.FeatureTwoFactor: {
+			Description:    "Lets users require an authenticator app code when signing in",
+			DefaultEnabled: false,
+		},
+	}
+	if err := featureService.SyncFeatures(ctx, features); err != nil {
+		log.Printf("Startup step pending: %s (attempt 1 failed, retrying in %s): %v", startupFeatureSync, featureSyncRetryInterval, err)
+		go retryFeatureSync(ctx, featureService, features, startupTracker)
+	} else {
+		startupTracker.Done(startupFeatureSync)
+	}
+
+	// Rate limit buckets, shared by every limiter below
+	var rateLimitStore middleware.RateLimitStore
+	switch cfg.Server.RateLimitStore {
+	case "memory", "":
+		rateLimitStore = middleware.NewMemoryRateLimitStore(cfg.Server.RateLimitIdleTTL)
+	case "postgres":
+		rateLimitStore = postgres.NewRateLimitRepository(db)
+	default:
+		return fmt.Errorf("invalid RATE_LIMIT_STORE %q: must be memory or postgres", cfg.Server.RateLimitStore)
+	}
+	go middleware.RunRateLimitCleanup(ctx, rateLimitStore)
+
+	if err := middleware.SetClientIPStrategy(cfg.Server.XFFStrategy, cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid XFF_STRATEGY or TRUSTED_PROXIES: %w", err)
+	}
+
+	// Password reset throttling: 5 attempts per IP then one every 30s, 20/s across all
+	// clients, and a lockout after 10 invalid tokens from one IP (one more every 6 minutes).
+	resetIPLimiter := middleware.NewIPRateLimiterWithStore(rate.Every(30*time.Second), 5, rateLimitStore)
+	resetIPLimiter.SetKeyPrefix("reset:")
+	resetGlobalLimiter := middleware.NewIPRateLimiterWithStore(20, 40, rateLimitStore)
+	resetGlobalLimiter.SetKeyPrefix("reset-global:")
+	resetLockout := middleware.NewIPRateLimiterWithStore(rate.Every(6*time.Minute), 10, rateLimitStore)
+	resetLockout.SetKeyPrefix("reset-failed:")
+	// Sign-in link and password reset emails: 3 per address per hour, shared between both
+	// flows, so one victim's inbox can't be flooded from rotating IPs.
+	emailLimiter := middleware.NewKeyedRateLimiter(rate.Every(20*time.Minute), 3, rateLimitStore, "email:", middleware.EmailKey("email"))
+	// Resent verification emails: 3 per address per hour, counted separately so resending
+	// doesn't use up the address's sign-in link and password reset allowance.
+	verificationLimiter := middleware.NewKeyedRateLimiter(rate.Every(20*time.Minute), 3, rateLimitStore, "verify:", middleware.EmailKey("email"))
+	for _, limiter := range []*middleware.IPRateLimiter{resetIPLimiter, resetGlobalLimiter} {
+		if err := limiter.SetAllowlist(cfg.Server.RateLimitAllowlist); err != nil {
+			return fmt.Errorf("invalid RATE_LIMIT_ALLOWLIST: %w", err)
+		}
+	}
+	// Sign-in friction: after repeated failed sign-ins from one IP, require a CAPTCHA
+	// (if configured) or delay its attempts instead of blocking it outright.
+	loginFriction := middleware.NewLoginFriction(rateLimitStore, cfg.Auth.LoginFrictionAfter, cfg.Auth.LoginFrictionWindow, cfg.Auth.LoginFrictionMaxDelay)
+	if err := loginFriction.SetAllowlist(cfg.Server.RateLimitAllowlist); err != nil {
+		return fmt.Errorf("invalid RATE_LIMIT_ALLOWLIST: %w", err)
+	}
+	captchaVerifier, err := captcha.New(cfg.Auth.CaptchaProvider, cfg.Auth.CaptchaSiteKey, cfg.Auth.CaptchaSecretKey)
 	if err != nil {
-		return fmt.Errorf("failed to sync feature flags: %w", err)
+		return fmt.Errorf("invalid CAPTCHA configuration: %w", err)
 	}
 
 	// Initialize handlers
-	baseHandler := handler.NewHandler(cfg.App.Name, cfg.App.Logo, featureService)
+	baseHandler := handler.NewHandler(cfg.App.Name, cfg.App.Logo, cfg.App.MaxPageSize, featureService)
 
-	homeHandler := handler.NewHomeHandler(baseHandler, db)
-	userHandler := handler.NewUserHandler(baseHandler, userService, auditService)
-	authHandler := handler.NewAuthHandler(baseHandler, authService, userService, activityService)
+	homeHandler := handler.NewHomeHandler(baseHandler, db, settingsService, startupTracker)
+	userHandler := handler.NewUserHandler(baseHandler, userService, authService, auditService)
+	authHandler := handler.NewAuthHandler(baseHandler, authService, userService, activityService, resetLockout, emailLimiter, verificationLimiter, loginFriction, captchaVerifier)
 	activityHandler := handler.NewActivityHandler(baseHandler, activityService)
 	profileHandler := handler.NewProfileHandler(baseHandler, userService, activityService, mediaService)
-	settingsHandler := handler.NewSettingsHandler(baseHandler, userService, activityService)
-	analyticsHandler := handler.NewAnalyticsHandler(baseHandler, db)
-	auditHandler := handler.NewAuditHandler(baseHandler, auditService, db, cfg)
+	settingsHandler := handler.NewSettingsHandler(baseHandler, userService, authService, activityService)
+	twoFactorHandler := handler.NewTwoFactorHandler(baseHandler, twoFactorService, activityService)
+	analyticsHandler := handler.NewAnalyticsHandler(baseHandler, db, auditService)
+	auditHandler := handler.NewAuditHandler(baseHandler, auditService, emailService, db, cfg)
 	auditHandler.StartMonitoring(ctx)
 	featureHandler := handler.NewFeatureHandler(baseHandler, featureService, auditService)
-	adminOAuthHandler := handler.NewAdminOAuthHandler(baseHandler, oauthRepo, auditService, cfg.App.URL)
-	blogHandler := handler.NewBlogHandler(baseHandler, blogService, mediaService)
-	mediaHandler := handler.NewMediaHandler(baseHandler, mediaService)
+	adminOAuthHandler := handler.NewAdminOAuthHandler(baseHandler, oauthRepo, auditService, userService, emailService, cfg.App.PublicURL, cfg.IsDevelopment())
+	blogHandler := handler.NewBlogHandler(baseHandler, blogService, mediaService, activityService)
+	mediaHandler := handler.NewMediaHandler(baseHandler, mediaService, activityService)
+	seoHandler := handler.NewSEOHandler(baseHandler, blogService, cfg.App.PublicURL, cfg.IsProduction())
 
 	// Initialize auth middleware
+	middleware.SetCookieSecureMode(cfg.Auth.CookieSecure)
+	middleware.SetSessionSameSite(cfg.Auth.CookieSameSite)
+	middleware.SetSessionHostPrefix(cfg.Auth.CookieHostPrefix)
+	middleware.SetSessionCookieName(cfg.Auth.SessionCookieName)
+	if err := middleware.SetSessionCookieDomain(cfg.Auth.CookieDomain); err != nil {
+		return fmt.Errorf("invalid cookie domain: %w", err)
+	}
+	middleware.SetForbiddenHandler(http.HandlerFunc(homeHandler.Forbidden))
 	authMiddleware := middleware.NewAuth(authService)
+	featureGate := middleware.NewFeatureGate(featureService, http.HandlerFunc(homeHandler.FeatureDisabled))
 
 	// Setup routes
 	mux := http.NewServeMux()
 
 	// Static files
-	// Debug: Check if vendor files exist
 	assetsPath := "web/assets"
 	if _, err := os.Stat("/app/web/assets"); err == nil {
 		assetsPath = "/app/web/assets"
 	}
 
+	// One-time startup sanity check; the file listing is only useful while developing
 	if _, err := os.Stat(assetsPath + "/vendor/htmx.min.js"); os.IsNotExist(err) {
 		log.Printf("WARNING: %s/vendor/htmx.min.js does not exist!", assetsPath)
-		// List web/assets/vendor content
-		files, _ := os.ReadDir(assetsPath + "/vendor")
-		log.Printf("%s/vendor content: %d files", assetsPath, len(files))
-		for _, f := range files {
-			log.Printf(" - %s", f.Name())
+		if cfg.IsDevelopment() {
+			files, _ := os.ReadDir(assetsPath + "/vendor")
+			log.Printf("%s/vendor content: %d files", assetsPath, len(files))
+			for _, f := range files {
+				log.Printf(" - %s", f.Name())
+			}
 		}
-	} else {
-		log.Printf("SUCCESS: %s/vendor/htmx.min.js found", assetsPath)
 	}
 
-	// Static files debug handler
 	log.Printf("Serving static files from: %s", assetsPath)
+	assetServer := http.StripPrefix("/assets/", http.FileServer(http.Dir(assetsPath)))
 	mux.HandleFunc("/assets/", func(w http.ResponseWriter, r *http.Request) {
-		// Log the raw request
-		path := r.URL.Path
-		// Strip prefix manually to be sure
-		cleanPath := strings.TrimPrefix(path, "/assets/")
-
-		// Construct full file path
-		fullPath := filepath.Join(assetsPath, cleanPath)
-
-		log.Printf("[DEBUG_ASSETS] Request: %s -> FilePath: %s", path, fullPath)
-
-		// Check if file exists
-		info, err := os.Stat(fullPath)
-		if err != nil {
-			log.Printf("[DEBUG_ASSETS] ERROR: os.Stat failed for %s: %v", fullPath, err)
-			http.Error(w, fmt.Sprintf("File not found: %s, Error: %v", cleanPath, err), http.StatusNotFound)
-			return
-		}
-
-		if info.IsDir() {
-			log.Printf("[DEBUG_ASSETS] ERROR: %s is a directory", fullPath)
+		if strings.HasSuffix(r.URL.Path, "/") {
 			http.Error(w, "Directory listing forbidden", http.StatusForbidden)
 			return
 		}
 
-		log.Printf("[DEBUG_ASSETS] Serving file: %s (Size: %d, Mode: %s)", fullPath, info.Size(), info.Mode())
+		if cfg.IsDevelopment() {
+			log.Printf("[DEBUG_ASSETS] Request: %s", r.URL.Path)
+		}
 
-		// Serve the file
-		http.ServeFile(w, r, fullPath)
+		assetServer.ServeHTTP(w, r)
 	})
 
+	// Anonymous renders of the home and blog pages are cached briefly; changes to posts,
+	// settings (the home page content) and feature flags clear them
+	pageCache := middleware.NewPageCache(cfg.Server.PageCacheSize, cfg.Server.PageCacheTTL)
+	blogService.OnChange(pageCache.Purge)
+	settingsService.OnChange(pageCache.Purge)
+	featureService.OnChange(pageCache.Purge)
+
 	// Public routes (no auth required)
-	mux.HandleFunc("GET /{$}", homeHandler.Index)
+	mux.Handle("GET /{$}", pageCache.Middleware(http.HandlerFunc(homeHandler.Index)))
 	mux.HandleFunc("GET /health", homeHandler.HealthCheck)
+	mux.HandleFunc("HEAD /health", homeHandler.HealthCheckHead)
+	mux.HandleFunc("GET /ready", homeHandler.Readiness)
+	mux.HandleFunc("HEAD /ready", homeHandler.ReadinessHead)
+	mux.HandleFunc("GET /robots.txt", seoHandler.Robots)
+	mux.HandleFunc("GET /sitemap.xml", seoHandler.Sitemap)
 
 	// Blog Public Routes
-	mux.HandleFunc("GET /blogs", blogHandler.List)
-	mux.HandleFunc("GET /blogs/{slug}", blogHandler.View)
-	mux.HandleFunc("GET /blogs/{slug}/cover", blogHandler.GetCoverImage)
+	blogOnly := featureGate.RequireFeature(domain.FeatureBlog)
+	mux.Handle("GET /blogs", blogOnly(pageCache.Middleware(http.HandlerFunc(blogHandler.List))))
+	mux.Handle("GET /blogs/{slug}", blogOnly(blogHandler.CountViews(pageCache.Middleware(http.HandlerFunc(blogHandler.View)))))
+	mux.Handle("GET /blogs/{slug}/cover", blogOnly(http.HandlerFunc(blogHandler.GetCoverImage)))
+	mux.Handle("GET /api/blogs", blogOnly(http.HandlerFunc(blogHandler.APIList)))
+	mux.Handle("GET /api/blogs/{slug}", blogOnly(http.HandlerFunc(blogHandler.APIView)))
 
 	// Media Routes
 	mux.Handle("GET /media/{filename}", http.HandlerFunc(mediaHandler.Serve))
+	mux.Handle("HEAD /media/{filename}", http.HandlerFunc(mediaHandler.Head))
 
 	// Public profile images (for blog author avatars, etc.)
 	mux.HandleFunc("GET /api/users/{id}/image", profileHandler.GetUserProfileImage)
 
 	// Rate limiter for auth routes (5 reqs/10s roughly, burst 5)
-	authLimiter := middleware.RateLimitMiddleware(0.5, 5)
+	authRateLimiter := middleware.NewIPRateLimiterWithStore(0.5, 5, rateLimitStore)
+	authRateLimiter.SetKeyPrefix("auth:")
+	if err := authRateLimiter.SetAllowlist(cfg.Server.RateLimitAllowlist); err != nil {
+		return fmt.Errorf("invalid RATE_LIMIT_ALLOWLIST: %w", err)
+	}
+	authLimiter := authRateLimiter.Middleware
+	rateLimitHandler := handler.NewRateLimitHandler(baseHandler, authRateLimiter)
+	onlineHandler := handler.NewOnlineHandler(baseHandler, sessionRepo, auditService)
+	siteSettingsHandler := handler.NewSiteSettingsHandler(baseHandler, settingsService, auditService)
 
 	// Auth routes
 	mux.Handle("GET /signin", authLimiter(http.HandlerFunc(authHandler.SignInPage)))
@@ -202,10 +410,12 @@ func run() error {
 	mux.Handle("POST /signup", authLimiter(http.HandlerFunc(authHandler.Signup)))
 	mux.HandleFunc("POST /logout", authHandler.Logout)
 	mux.HandleFunc("GET /verify-email", authHandler.VerifyEmailPage)
+	mux.HandleFunc("GET /verify-email-change", authHandler.VerifyEmailChange)
+	mux.Handle("POST /auth/resend-verification", authLimiter(http.HandlerFunc(authHandler.ResendVerification)))
 	mux.Handle("GET /forgot-password", authLimiter(http.HandlerFunc(authHandler.ForgotPasswordPage)))
 	mux.Handle("POST /forgot-password", authLimiter(http.HandlerFunc(authHandler.ForgotPassword)))
 	mux.Handle("GET /reset-password", authLimiter(http.HandlerFunc(authHandler.ResetPasswordPage)))
-	mux.Handle("POST /reset-password", authLimiter(http.HandlerFunc(authHandler.ResetPassword)))
+	mux.Handle("POST /reset-password", authLimiter(resetGlobalLimiter.GlobalMiddleware(resetIPLimiter.Middleware(http.HandlerFunc(authHandler.ResetPassword)))))
 
 	// Email Auth routes
 	mux.Handle("POST /auth/email/request", authLimiter(http.HandlerFunc(authHandler.HandleEmailAuthRequest)))
@@ -214,45 +424,76 @@ func run() error {
 	mux.Handle("POST /auth/complete-profile", middleware.RequireAuth(http.HandlerFunc(authHandler.CompleteProfile)))
 
 	// OAuth routes
-	mux.Handle("GET /auth/{provider}", authLimiter(http.HandlerFunc(authHandler.HandleOAuthLogin)))
-	mux.Handle("GET /auth/{provider}/callback", authLimiter(http.HandlerFunc(authHandler.HandleOAuthCallback)))
+	oauthOnly := featureGate.RequireFeature(domain.FeatureOAuth)
+	mux.Handle("GET /auth/{provider}", oauthOnly(authLimiter(http.HandlerFunc(authHandler.HandleOAuthLogin))))
+	mux.Handle("GET /auth/{provider}/callback", oauthOnly(authLimiter(http.HandlerFunc(authHandler.HandleOAuthCallback))))
+
+	// Two-factor sign-in step
+	twoFactorOnly := featureGate.RequireFeature(domain.FeatureTwoFactor)
+	mux.Handle("GET /signin/2fa", twoFactorOnly(authLimiter(http.HandlerFunc(authHandler.TwoFactorPage))))
+	mux.Handle("POST /signin/2fa", twoFactorOnly(authLimiter(http.HandlerFunc(authHandler.TwoFactor))))
 
 	// Backwards compatible redirect from /login to /signin
 	mux.HandleFunc("GET /login", authHandler.LoginRedirect)
 
+	// Admin and super admin routes; with REQUIRE_2FA_FOR_ADMINS, admins must also have
+	// two-factor authentication turned on
+	adminTwoFactor := func(next http.Handler) http.Handler { return next }
+	if cfg.Auth.RequireTwoFactorForAdmins {
+		adminTwoFactor = middleware.RequireAdminTwoFactor(twoFactorService)
+	}
+	requireRole := func(roles ...domain.Role) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return middleware.RequireRole(roles...)(adminTwoFactor(next))
+		}
+	}
+	adminOnly := requireRole(domain.RoleAdmin, domain.RoleSuperAdmin)
+	superAdminOnly := requireRole(domain.RoleSuperAdmin)
+
 	// Role-based dashboard routes
 	mux.Handle("GET /u/dashboard", middleware.RequireAuth(http.HandlerFunc(homeHandler.UserDashboard)))
-	mux.Handle("GET /a/dashboard", middleware.RequireRole(domain.RoleAdmin, domain.RoleSuperAdmin)(http.HandlerFunc(homeHandler.AdminDashboard)))
-	mux.Handle("GET /s/dashboard", middleware.RequireRole(domain.RoleSuperAdmin)(http.HandlerFunc(homeHandler.SuperAdminDashboard)))
+	mux.Handle("GET /a/dashboard", adminOnly(http.HandlerFunc(homeHandler.AdminDashboard)))
+	mux.Handle("GET /s/dashboard", superAdminOnly(http.HandlerFunc(homeHandler.SuperAdminDashboard)))
 
 	// Backwards compatible redirect from /dashboard to role-appropriate dashboard
 	mux.Handle("GET /dashboard", middleware.RequireAuth(http.HandlerFunc(homeHandler.DashboardRedirect)))
 
 	// User routes (authenticated users)
 	userOnly := middleware.RequireAuth
+	verified := middleware.NewVerifiedEmailGate(cfg.Auth.RequireVerifiedEmailFor)
 	mux.Handle("GET /components/sidebar", userOnly(http.HandlerFunc(homeHandler.Sidebar)))
 	mux.Handle("GET /u/activity", userOnly(http.HandlerFunc(activityHandler.UserActivity)))
 	mux.Handle("GET /u/profile", userOnly(http.HandlerFunc(profileHandler.ProfilePage)))
-	mux.Handle("POST /u/profile", userOnly(http.HandlerFunc(profileHandler.UpdateProfile)))
-	mux.Handle("POST /u/profile/image", userOnly(http.HandlerFunc(profileHandler.UploadProfileImage)))
+	mux.Handle("POST /u/profile", userOnly(verified.For(middleware.VerifiedActionProfileUpdate)(http.HandlerFunc(profileHandler.UpdateProfile))))
+	mux.Handle("POST /u/profile/image", userOnly(verified.For(middleware.VerifiedActionProfileUpdate)(http.HandlerFunc(profileHandler.UploadProfileImage))))
 	mux.Handle("GET /u/profile/image", userOnly(http.HandlerFunc(profileHandler.GetMyProfileImage)))
 	mux.Handle("GET /u/settings", userOnly(http.HandlerFunc(settingsHandler.Settings)))
 	mux.Handle("POST /u/settings", userOnly(http.HandlerFunc(settingsHandler.Settings)))
-	mux.Handle("POST /u/settings/password", userOnly(http.HandlerFunc(settingsHandler.UpdatePassword)))
+	mux.Handle("POST /u/settings/password", userOnly(verified.For(middleware.VerifiedActionPasswordChange)(http.HandlerFunc(settingsHandler.UpdatePassword))))
+	mux.Handle("POST /u/settings/oauth/{provider}/unlink", userOnly(http.HandlerFunc(settingsHandler.UnlinkOAuth)))
+	mux.Handle("GET /u/settings/2fa", twoFactorOnly(userOnly(http.HandlerFunc(twoFactorHandler.Status))))
+	mux.Handle("GET /u/settings/2fa/enable", twoFactorOnly(userOnly(http.HandlerFunc(twoFactorHandler.EnablePage))))
+	mux.Handle("POST /u/settings/2fa/enable", twoFactorOnly(userOnly(http.HandlerFunc(twoFactorHandler.Enable))))
+	mux.Handle("POST /u/settings/2fa/recovery-codes", twoFactorOnly(userOnly(http.HandlerFunc(twoFactorHandler.RegenerateRecoveryCodes))))
+	mux.Handle("POST /u/settings/2fa/disable", twoFactorOnly(userOnly(http.HandlerFunc(twoFactorHandler.Disable))))
+	mux.Handle("GET /u/sessions", userOnly(http.HandlerFunc(authHandler.Sessions)))
+	mux.Handle("POST /u/sessions/{id}/revoke", userOnly(http.HandlerFunc(authHandler.RevokeSession)))
 	mux.Handle("POST /u/signout-all", userOnly(http.HandlerFunc(authHandler.SignOutAllDevices)))
 
-	// API routes for Media Upload (Authenticated)
-	mux.Handle("POST /api/media/upload", userOnly(http.HandlerFunc(mediaHandler.Upload)))
+	// API routes (Authenticated)
+	mux.Handle("GET /api/me", userOnly(http.HandlerFunc(profileHandler.Me)))
+	mux.Handle("POST /api/media/upload", userOnly(verified.For(middleware.VerifiedActionMediaUpload)(http.HandlerFunc(mediaHandler.Upload))))
 
 	// Admin routes (require admin role)
-	adminOnly := middleware.RequireRole(domain.RoleAdmin, domain.RoleSuperAdmin)
 	mux.Handle("GET /a/users", adminOnly(http.HandlerFunc(userHandler.List)))
 	mux.Handle("GET /a/users/create", adminOnly(http.HandlerFunc(userHandler.Create)))
 	mux.Handle("POST /a/users/create", adminOnly(http.HandlerFunc(userHandler.Create)))
 	mux.Handle("GET /a/users/{id}/edit", adminOnly(http.HandlerFunc(userHandler.Edit)))
 	mux.Handle("POST /a/users/{id}/edit", adminOnly(http.HandlerFunc(userHandler.Edit)))
 	mux.Handle("POST /a/users/{id}/status", adminOnly(http.HandlerFunc(userHandler.UpdateStatus)))
-	mux.Handle("DELETE /a/users/{id}", middleware.RequireRole(domain.RoleSuperAdmin)(http.HandlerFunc(userHandler.Delete)))
+	mux.Handle("POST /a/users/{id}/secure", adminOnly(http.HandlerFunc(userHandler.Secure)))
+	mux.Handle("POST /a/users/{id}/oauth/{provider}/revoke", adminOnly(http.HandlerFunc(userHandler.RevokeOAuth)))
+	mux.Handle("DELETE /a/users/{id}", superAdminOnly(http.HandlerFunc(userHandler.Delete)))
 
 	// Feature Flags Admin
 	mux.Handle("GET /a/features", adminOnly(http.HandlerFunc(featureHandler.List)))
@@ -267,29 +508,49 @@ func run() error {
 	mux.Handle("GET /a/activity", adminOnly(http.HandlerFunc(analyticsHandler.SystemActivity)))
 
 	// Admin Blog routes
-	mux.Handle("GET /a/blogs", adminOnly(http.HandlerFunc(blogHandler.AdminList)))
-	mux.Handle("GET /a/blogs/create", adminOnly(http.HandlerFunc(blogHandler.CreatePage)))
-	mux.Handle("POST /a/blogs/create", adminOnly(http.HandlerFunc(blogHandler.Create)))
-	mux.Handle("GET /a/blogs/{id}/edit", adminOnly(http.HandlerFunc(blogHandler.EditPage)))
-	mux.Handle("POST /a/blogs/{id}/edit", adminOnly(http.HandlerFunc(blogHandler.Edit)))
-	mux.Handle("GET /a/blogs/{id}", adminOnly(http.HandlerFunc(blogHandler.GetBlogJSON)))
-	mux.Handle("DELETE /a/blogs/{id}", adminOnly(http.HandlerFunc(blogHandler.Delete)))
+	mux.Handle("GET /a/blogs", adminOnly(blogOnly(http.HandlerFunc(blogHandler.AdminList))))
+	mux.Handle("GET /a/blogs/create", adminOnly(blogOnly(http.HandlerFunc(blogHandler.CreatePage))))
+	mux.Handle("POST /a/blogs/create", adminOnly(blogOnly(verified.For(middleware.VerifiedActionBlogWrite)(http.HandlerFunc(blogHandler.Create)))))
+	mux.Handle("GET /a/blogs/{id}/edit", adminOnly(blogOnly(http.HandlerFunc(blogHandler.EditPage))))
+	mux.Handle("POST /a/blogs/{id}/edit", adminOnly(blogOnly(verified.For(middleware.VerifiedActionBlogWrite)(http.HandlerFunc(blogHandler.Edit)))))
+	mux.Handle("GET /a/blogs/{id}", adminOnly(blogOnly(http.HandlerFunc(blogHandler.GetBlogJSON))))
+	mux.Handle("DELETE /a/blogs/{id}", adminOnly(blogOnly(http.HandlerFunc(blogHandler.Delete))))
+
+	// Admin Media routes
+	mux.Handle("GET /a/media", adminOnly(http.HandlerFunc(mediaHandler.AdminList)))
+	mux.Handle("DELETE /a/media/{id}", adminOnly(http.HandlerFunc(mediaHandler.AdminDelete)))
 
 	// Super Admin routes (require super admin role)
-	superAdminOnly := middleware.RequireRole(domain.RoleSuperAdmin)
 	mux.Handle("GET /s/audit", superAdminOnly(http.HandlerFunc(auditHandler.AuditLogs)))
+	mux.Handle("GET /s/audit/{entry}", superAdminOnly(http.HandlerFunc(auditHandler.AuditLogJSON))) // {entry} is "<id>.json"
 	mux.Handle("GET /s/system", superAdminOnly(http.HandlerFunc(auditHandler.SystemHealth)))
 	mux.Handle("GET /s/system/metrics", superAdminOnly(http.HandlerFunc(auditHandler.SystemMetricsJSON)))
-
-	// Catch-all for 404s (must be added last if using patterns that might overlap, but "/" is most general)
-	mux.HandleFunc("/", homeHandler.NotFound)
+	mux.Handle("GET /s/system/info.json", superAdminOnly(http.HandlerFunc(auditHandler.SystemInfoJSON)))
+	mux.Handle("POST /s/system/test-email", superAdminOnly(http.HandlerFunc(auditHandler.SendTestEmail)))
+	mux.Handle("GET /s/ratelimit", superAdminOnly(http.HandlerFunc(rateLimitHandler.List)))
+	mux.Handle("GET /s/online", superAdminOnly(http.HandlerFunc(onlineHandler.List)))
+	mux.Handle("GET /s/settings/home", superAdminOnly(http.HandlerFunc(siteSettingsHandler.HomePage)))
+	mux.Handle("POST /s/settings/home", superAdminOnly(http.HandlerFunc(siteSettingsHandler.UpdateHomePage)))
+	mux.Handle("GET /s/settings/email", superAdminOnly(http.HandlerFunc(siteSettingsHandler.WelcomeEmail)))
+	mux.Handle("POST /s/settings/email", superAdminOnly(http.HandlerFunc(siteSettingsHandler.UpdateWelcomeEmail)))
+	mux.Handle("GET /s/settings/password-policy", superAdminOnly(http.HandlerFunc(siteSettingsHandler.PasswordPolicy)))
+	mux.Handle("POST /s/settings/password-policy", superAdminOnly(http.HandlerFunc(siteSettingsHandler.UpdatePasswordPolicy)))
+
+	// Catch-all for 404s (must be added last if using patterns that might overlap, but "/" is most general).
+	// Paths served by another route under a different method get a 405 with an Allow header instead.
+	mux.Handle("/", middleware.MethodNotAllowed(mux, http.HandlerFunc(homeHandler.MethodNotAllowed))(http.HandlerFunc(homeHandler.NotFound)))
 
 	// Apply middleware stack
 	var h http.Handler = mux
 	h = authMiddleware.Handler(h) // Auth middleware (loads user into context)
+	h = featureGate.Handler(h)    // Feature gate (lets templates check feature flags)
+	// CSRF tokens for unsafe methods; OAuth providers redirect back to the callback without one
+	h = middleware.NewCSRF("/auth/*/callback").Handler(h)
 	h = middleware.Logging(h)
-	h = middleware.Recovery(h)
+	h = middleware.RouteMetrics(mux, cfg.Server.SlowRequestThreshold)(h)
+	h = middleware.Recovery(mux, http.HandlerFunc(homeHandler.ServerError))(h)
 	h = middleware.CORS(h)
+	h = middleware.TrackInFlight(h)
 
 	// Create server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -301,10 +562,45 @@ func run() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// With ACME enabled, serve TLS on :443 using Let's Encrypt certificates, and run a
+	// plain HTTP server on :80 for HTTP-01 challenges that redirects everything else.
+	var challengeServer *http.Server
+	if len(cfg.Server.ACMEDomains) > 0 {
+		certManager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.Server.ACMEDomains...),
+			Cache:      autocert.DirCache(cfg.Server.ACMECacheDir),
+			Email:      cfg.Server.ACMEEmail,
+		}
+		server.Addr = fmt.Sprintf("%s:443", cfg.Server.Host)
+		server.TLSConfig = certManager.TLSConfig()
+
+		challengeServer = &http.Server{
+			Addr:         fmt.Sprintf("%s:80", cfg.Server.Host),
+			Handler:      certManager.HTTPHandler(nil),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+		go func() {
+			log.Printf("ACME challenge server starting on http://%s", challengeServer.Addr)
+			if err := challengeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("ACME challenge server error: %v", err)
+			}
+		}()
+	}
+
 	// Start server in goroutine
 	go func() {
-		log.Printf("Server starting on http://%s", addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			log.Printf("Server starting on https://%s for %s", server.Addr, strings.Join(cfg.Server.ACMEDomains, ", "))
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Server starting on http://%s", addr)
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -315,12 +611,18 @@ func run() error {
 	<-quit
 
 	log.Println("Shutting down server...")
+	cancel()
 
 	// Graceful shutdown with timeout
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
 
+	if challengeServer != nil {
+		_ = challengeServer.Shutdown(shutdownCtx)
+	}
+
 	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown timeout of %s reached with %d requests still in flight", cfg.Server.ShutdownTimeout, middleware.InFlight())
 		return fmt.Errorf("server forced to shutdown: %w", err)
 	}
 
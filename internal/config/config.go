@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -23,12 +25,55 @@ type Config struct {
 // AuthConfig contains authentication settings.
 type AuthConfig struct {
 	Secret string
+	// PasswordHasher selects the password hashing algorithm: "bcrypt" or "argon2id"
+	PasswordHasher string
+	// BcryptCost is the bcrypt work factor; raising it upgrades hashes on next login
+	BcryptCost int
+	// CookieSecure controls the cookie Secure flag: "auto", "always" or "never"
+	CookieSecure string
+	// CookieSameSite sets SameSite for session cookies: "lax" or "strict"
+	CookieSameSite string
+	// CookieHostPrefix names the session cookie __Host-<name> on HTTPS requests
+	CookieHostPrefix bool
+	// SessionCookieName is the session cookie name, so deployments on sibling subdomains don't collide
+	SessionCookieName string
+	// CookieDomain shares the session cookie with subdomains (e.g. "example.com"); HTTPS only
+	CookieDomain string
+	// RequireVerifiedEmailFor lists actions that require a verified email (e.g. "media_upload")
+	RequireVerifiedEmailFor []string
+	// BootstrapSuperAdmin makes the first account to sign up a super admin
+	BootstrapSuperAdmin bool
+	// RequireTwoFactorForAdmins sends admins and super admins without two-factor
+	// authentication to set it up before they can use admin pages
+	RequireTwoFactorForAdmins bool
+	// SessionTTL is how long a session lasts; active sessions are extended once less than a fifth of it remains
+	SessionTTL time.Duration
+	// SessionCleanupInterval is how often expired sessions are purged; 0 disables the purge
+	SessionCleanupInterval time.Duration
+	// SessionCacheTTL is how long a session stays in the Redis cache (never past its expiry)
+	SessionCacheTTL time.Duration
+	// SessionValidationCacheTTL is how long a validated session's user is cached in process; 0 disables it
+	SessionValidationCacheTTL time.Duration
+	// LoginFrictionAfter is how many failed sign-ins from one IP, within LoginFrictionWindow,
+	// trigger a CAPTCHA or a growing delay on its next attempts; 0 disables it
+	LoginFrictionAfter int
+	// LoginFrictionWindow is the period over which an IP's failed sign-ins are counted
+	LoginFrictionWindow time.Duration
+	// LoginFrictionMaxDelay caps the delay added to sign-ins when no CAPTCHA is configured
+	LoginFrictionMaxDelay time.Duration
+	// CaptchaProvider is "turnstile" or "hcaptcha"; empty means sign-in friction uses delays only
+	CaptchaProvider string
+	// CaptchaSiteKey and CaptchaSecretKey are the CAPTCHA provider's keys
+	CaptchaSiteKey   string
+	CaptchaSecretKey string
 }
 
 // EmailConfig contains email service settings.
 type EmailConfig struct {
 	ResendAPIKey    string
 	ResendFromEmail string
+	// ActivityDigestInterval is how often opted-in users get an activity digest; 0 disables digests
+	ActivityDigestInterval time.Duration
 }
 
 // ServerConfig contains HTTP server settings.
@@ -38,11 +83,47 @@ type ServerConfig struct {
 	ReadTimeout  string
 	WriteTimeout string
 	IdleTimeout  string
+	// ShutdownTimeout is how long graceful shutdown waits for in-flight requests
+	ShutdownTimeout time.Duration
+	// SlowRequestThreshold logs requests that take longer than this; 0 disables the log
+	SlowRequestThreshold time.Duration
+	// RateLimitAllowlist lists IPs/CIDRs that bypass rate limiting (e.g. uptime monitors)
+	RateLimitAllowlist []string
+	// RateLimitIdleTTL is how long an in-memory rate limit bucket may sit unused before it is dropped
+	RateLimitIdleTTL time.Duration
+	// XFFStrategy selects how the client IP is read: "leftmost", "rightmost-trusted" or "direct"
+	XFFStrategy string
+	// TrustedProxies lists proxy IPs/CIDRs for the rightmost-trusted strategy (default: private ranges)
+	TrustedProxies []string
+	// RateLimitStore selects where rate limit buckets live: "memory" or "postgres" (shared across instances)
+	RateLimitStore string
+	// GoroutineAlertThreshold raises a system alert above this many goroutines; 0 disables it
+	GoroutineAlertThreshold int
+	// RAMAlertPercent raises a system alert above this RAM usage percentage; 0 disables it
+	RAMAlertPercent float64
+	// PageCacheSize is how many rendered public pages are cached for anonymous visitors; 0 disables the cache
+	PageCacheSize int
+	// PageCacheTTL is how long a cached page is served before it is rendered again
+	PageCacheTTL time.Duration
+	// ACMEDomains enables automatic Let's Encrypt TLS for these hostnames; empty disables it
+	ACMEDomains []string
+	// ACMECacheDir stores issued certificates and the ACME account key between restarts
+	ACMECacheDir string
+	// ACMEEmail is the optional contact address registered with Let's Encrypt
+	ACMEEmail string
 }
 
 // DatabaseConfig contains database connection settings.
 type DatabaseConfig struct {
 	URL string
+	// MigrateOnly makes the server run migrations and exit, like the -migrate flag
+	MigrateOnly bool
+	// ConnectAttempts is how many times startup tries to reach the database before giving up
+	ConnectAttempts int
+	// ConnectInterval is the wait after the first failed attempt; it doubles after each one
+	ConnectInterval time.Duration
+	// RedisURL enables a Redis session cache in front of PostgreSQL; empty disables it
+	RedisURL string
 }
 
 // AppConfig contains general application settings.
@@ -51,6 +132,11 @@ type AppConfig struct {
 	Name string
 	Logo string
 	URL  string
+	// PublicURL is the user-facing base URL used in emailed links and OAuth callbacks.
+	// It defaults to URL and only needs setting when a proxy serves the app under another host.
+	PublicURL string
+	// MaxPageSize caps the ?limit= a client can request on any paginated list
+	MaxPageSize int
 }
 
 // StorageConfig contains file/image storage settings.
@@ -74,13 +160,124 @@ func Load() (*Config, error) {
 		port = 3000
 	}
 
+	bcryptCost, err := strconv.Atoi(getEnv("BCRYPT_COST", "10"))
+	if err != nil {
+		bcryptCost = 10
+	}
+
+	goroutineAlert, err := strconv.Atoi(getEnv("ALERT_GOROUTINE_THRESHOLD", "10000"))
+	if err != nil {
+		goroutineAlert = 10000
+	}
+
+	ramAlert, err := strconv.ParseFloat(getEnv("ALERT_RAM_PERCENT", "90"), 64)
+	if err != nil {
+		ramAlert = 90
+	}
+
+	shutdownTimeout, err := time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil || shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+
+	slowRequestThreshold, err := time.ParseDuration(getEnv("SLOW_REQUEST_THRESHOLD", "1s"))
+	if err != nil || slowRequestThreshold < 0 {
+		slowRequestThreshold = time.Second
+	}
+
+	pageCacheSize, err := strconv.Atoi(getEnv("PAGE_CACHE_SIZE", "500"))
+	if err != nil || pageCacheSize < 0 {
+		pageCacheSize = 500
+	}
+
+	rateLimitIdleTTL, err := time.ParseDuration(getEnv("RATE_LIMIT_IDLE_TTL", "15m"))
+	if err != nil || rateLimitIdleTTL <= 0 {
+		rateLimitIdleTTL = 15 * time.Minute
+	}
+
+	pageCacheTTL, err := time.ParseDuration(getEnv("PAGE_CACHE_TTL", "30s"))
+	if err != nil || pageCacheTTL < 0 {
+		pageCacheTTL = 30 * time.Second
+	}
+
+	connectAttempts, err := strconv.Atoi(getEnv("DB_CONNECT_ATTEMPTS", "10"))
+	if err != nil || connectAttempts < 1 {
+		connectAttempts = 10
+	}
+
+	connectInterval, err := time.ParseDuration(getEnv("DB_CONNECT_INTERVAL", "1s"))
+	if err != nil || connectInterval <= 0 {
+		connectInterval = time.Second
+	}
+
+	sessionTTL, err := time.ParseDuration(getEnv("SESSION_TTL", "168h"))
+	if err != nil || sessionTTL <= 0 {
+		sessionTTL = 7 * 24 * time.Hour
+	}
+
+	sessionCleanupInterval, err := time.ParseDuration(getEnv("SESSION_CLEANUP_INTERVAL", "1h"))
+	if err != nil || sessionCleanupInterval < 0 {
+		sessionCleanupInterval = time.Hour
+	}
+
+	sessionCacheTTL, err := time.ParseDuration(getEnv("SESSION_CACHE_TTL", "10m"))
+	if err != nil || sessionCacheTTL <= 0 {
+		sessionCacheTTL = 10 * time.Minute
+	}
+
+	sessionValidationCacheTTL, err := time.ParseDuration(getEnv("SESSION_VALIDATION_CACHE_TTL", "30s"))
+	if err != nil || sessionValidationCacheTTL < 0 {
+		sessionValidationCacheTTL = 30 * time.Second
+	}
+
+	loginFrictionAfter, err := strconv.Atoi(getEnv("LOGIN_FRICTION_AFTER", "5"))
+	if err != nil || loginFrictionAfter < 0 {
+		loginFrictionAfter = 5
+	}
+
+	loginFrictionWindow, err := time.ParseDuration(getEnv("LOGIN_FRICTION_WINDOW", "15m"))
+	if err != nil || loginFrictionWindow <= 0 {
+		loginFrictionWindow = 15 * time.Minute
+	}
+
+	loginFrictionMaxDelay, err := time.ParseDuration(getEnv("LOGIN_FRICTION_MAX_DELAY", "10s"))
+	if err != nil || loginFrictionMaxDelay <= 0 {
+		loginFrictionMaxDelay = 10 * time.Second
+	}
+
+	appURL := getEnv("APP_URL", "http://localhost:3000")
+
+	maxPageSize, err := strconv.Atoi(getEnv("MAX_PAGE_SIZE", "100"))
+	if err != nil || maxPageSize < 1 {
+		maxPageSize = 100
+	}
+
+	digestInterval, err := time.ParseDuration(getEnv("ACTIVITY_DIGEST_INTERVAL", "168h"))
+	if err != nil || digestInterval < 0 {
+		digestInterval = 7 * 24 * time.Hour
+	}
+
 	return &Config{
 		Server: ServerConfig{
-			Host:         getEnv("SERVER_HOST", "0.0.0.0"),
-			Port:         port,
-			ReadTimeout:  getEnv("SERVER_READ_TIMEOUT", "15s"),
-			WriteTimeout: getEnv("SERVER_WRITE_TIMEOUT", "15s"),
-			IdleTimeout:  getEnv("SERVER_IDLE_TIMEOUT", "60s"),
+			Host:                    getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:                    port,
+			ReadTimeout:             getEnv("SERVER_READ_TIMEOUT", "15s"),
+			WriteTimeout:            getEnv("SERVER_WRITE_TIMEOUT", "15s"),
+			IdleTimeout:             getEnv("SERVER_IDLE_TIMEOUT", "60s"),
+			ShutdownTimeout:         shutdownTimeout,
+			SlowRequestThreshold:    slowRequestThreshold,
+			RateLimitAllowlist:      splitList(getEnv("RATE_LIMIT_ALLOWLIST", "")),
+			RateLimitStore:          getEnv("RATE_LIMIT_STORE", "memory"),
+			RateLimitIdleTTL:        rateLimitIdleTTL,
+			XFFStrategy:             getEnv("XFF_STRATEGY", "rightmost-trusted"),
+			TrustedProxies:          splitList(getEnv("TRUSTED_PROXIES", "")),
+			GoroutineAlertThreshold: goroutineAlert,
+			RAMAlertPercent:         ramAlert,
+			PageCacheSize:           pageCacheSize,
+			PageCacheTTL:            pageCacheTTL,
+			ACMEDomains:             splitList(getEnv("ACME_DOMAINS", "")),
+			ACMECacheDir:            getEnv("ACME_CACHE_DIR", "certs"),
+			ACMEEmail:               getEnv("ACME_EMAIL", ""),
 		},
 		Database: DatabaseConfig{
 			URL: fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
@@ -91,12 +288,18 @@ func Load() (*Config, error) {
 				getEnv("POSTGRES_DB", "app_db"),
 				getEnv("POSTGRES_SSLMODE", "disable"),
 			),
+			MigrateOnly:     getEnv("MIGRATE_ONLY", "false") == "true",
+			ConnectAttempts: connectAttempts,
+			ConnectInterval: connectInterval,
+			RedisURL:        getEnv("REDIS_URL", ""),
 		},
 		App: AppConfig{
-			Env:  getEnv("APP_ENV", "development"),
-			Name: getEnv("APP_NAME", "Full Stack Go Template"),
-			Logo: getEnv("APP_LOGO", "/static/img/logo.svg"),
-			URL:  getEnv("APP_URL", "http://localhost:3000"),
+			Env:         getEnv("APP_ENV", "development"),
+			Name:        getEnv("APP_NAME", "Full Stack Go Template"),
+			Logo:        getEnv("APP_LOGO", "/static/img/logo.svg"),
+			URL:         appURL,
+			PublicURL:   strings.TrimRight(getEnv("PUBLIC_URL", appURL), "/"),
+			MaxPageSize: maxPageSize,
 		},
 		Storage: StorageConfig{
 			Type:     getEnv("PROFILE_IMAGE_STORAGE", "database"),
@@ -104,11 +307,32 @@ func Load() (*Config, error) {
 			S3Region: getEnv("S3_REGION", "us-east-1"),
 		},
 		Auth: AuthConfig{
-			Secret: getEnv("AUTH_SECRET", ""),
+			Secret:                    getEnv("AUTH_SECRET", ""),
+			PasswordHasher:            getEnv("PASSWORD_HASHER", "bcrypt"),
+			BcryptCost:                bcryptCost,
+			CookieSecure:              getEnv("COOKIE_SECURE", "auto"),
+			CookieSameSite:            getEnv("COOKIE_SAMESITE", "lax"),
+			CookieHostPrefix:          getEnv("COOKIE_HOST_PREFIX", "false") == "true",
+			SessionCookieName:         getEnv("SESSION_COOKIE_NAME", "session_id"),
+			CookieDomain:              getEnv("COOKIE_DOMAIN", ""),
+			RequireVerifiedEmailFor:   splitList(getEnv("REQUIRE_VERIFIED_EMAIL_FOR", "")),
+			BootstrapSuperAdmin:       getEnv("BOOTSTRAP_SUPERADMIN", "true") == "true",
+			RequireTwoFactorForAdmins: getEnv("REQUIRE_2FA_FOR_ADMINS", "false") == "true",
+			SessionTTL:                sessionTTL,
+			SessionCleanupInterval:    sessionCleanupInterval,
+			SessionCacheTTL:           sessionCacheTTL,
+			SessionValidationCacheTTL: sessionValidationCacheTTL,
+			LoginFrictionAfter:        loginFrictionAfter,
+			LoginFrictionWindow:       loginFrictionWindow,
+			LoginFrictionMaxDelay:     loginFrictionMaxDelay,
+			CaptchaProvider:           getEnv("CAPTCHA_PROVIDER", ""),
+			CaptchaSiteKey:            getEnv("CAPTCHA_SITE_KEY", ""),
+			CaptchaSecretKey:          getEnv("CAPTCHA_SECRET_KEY", ""),
 		},
 		Email: EmailConfig{
-			ResendAPIKey:    getEnv("RESEND_API_KEY", ""),
-			ResendFromEmail: getEnv("RESEND_FROM_EMAIL", "onboarding@resend.dev"),
+			ResendAPIKey:           getEnv("RESEND_API_KEY", ""),
+			ResendFromEmail:        getEnv("RESEND_FROM_EMAIL", "onboarding@resend.dev"),
+			ActivityDigestInterval: digestInterval,
 		},
 	}, nil
 }
@@ -130,3 +354,14 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// splitList splits a comma-separated value into trimmed, non-empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
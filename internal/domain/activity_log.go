@@ -25,6 +25,19 @@ const (
 
 	// ActivitySettingsUpdate represents a settings update event.
 	ActivitySettingsUpdate ActivityType = "settings_update"
+
+	// ActivityMediaUpload represents a media upload event.
+	ActivityMediaUpload ActivityType = "media_upload"
+
+	// ActivityOAuthUnlink represents the user unlinking a social account.
+	ActivityOAuthUnlink ActivityType = "oauth_unlink"
+
+	// ActivityTwoFactorChange represents the user turning two-factor authentication on or
+	// off, or replacing their recovery codes.
+	ActivityTwoFactorChange ActivityType = "two_factor_change"
+
+	// ActivityEmailChange represents the user requesting or confirming a new email address.
+	ActivityEmailChange ActivityType = "email_change"
 )
 
 // ActivityLog represents a user activity log entry.
@@ -38,6 +51,20 @@ type ActivityLog struct {
 	CreatedAt    time.Time    `json:"created_at"`
 }
 
+// ActivityDigest summarizes a user's account activity over a period for the digest email.
+type ActivityDigest struct {
+	Since          time.Time
+	Until          time.Time
+	Logins         []*ActivityLog
+	NewDevices     []*ActivityLog // logins from a user agent not seen before the period
+	ProfileChanges []*ActivityLog // profile, password and settings updates
+}
+
+// IsEmpty reports whether the digest has nothing to report.
+func (d *ActivityDigest) IsEmpty() bool {
+	return len(d.Logins) == 0 && len(d.ProfileChanges) == 0
+}
+
 // AuditAction represents an administrative action type.
 type AuditAction string
 
@@ -51,18 +78,35 @@ const (
 	// AuditUserDelete represents user deletion.
 	AuditUserDelete AuditAction = "user.delete"
 
+	// AuditUserSecure represents revoking a user's sessions and tokens for incident response.
+	AuditUserSecure AuditAction = "user.secure"
+
+	// AuditOAuthRevoke represents an admin unlinking a user's OAuth account and revoking its tokens.
+	AuditOAuthRevoke AuditAction = "user.oauth_revoke"
+
 	// AuditRoleChange represents role change.
 	AuditRoleChange AuditAction = "user.role_change"
 
+	// AuditOAuthProviderUpdate represents an admin changing an OAuth provider's settings.
+	AuditOAuthProviderUpdate AuditAction = "update_oauth_provider"
+
 	// AuditSystemConfig represents system configuration change.
 	AuditSystemConfig AuditAction = "system.config_change"
+
+	// AuditViewOnlineUsers represents a super admin viewing who is currently online.
+	AuditViewOnlineUsers AuditAction = "system.view_online_users"
+
+	// AuditView represents an admin viewing sensitive data: the audit log, system health,
+	// or another user's activity. The resource type names what was viewed.
+	AuditView AuditAction = "audit.view"
 )
 
 // AuditLog represents an audit log entry for administrative actions.
 type AuditLog struct {
 	ID           uuid.UUID              `json:"id"`
 	AdminID      uuid.UUID              `json:"admin_id"`
-	AdminName    string                 `json:"admin_name,omitempty"` // Populated via join
+	AdminName    string                 `json:"admin_name,omitempty"`  // Populated via join
+	AdminEmail   string                 `json:"admin_email,omitempty"` // Populated via join by GetByID
 	Action       AuditAction            `json:"action"`
 	ResourceType string                 `json:"resource_type"`
 	ResourceID   *uuid.UUID             `json:"resource_id,omitempty"`
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,16 +32,71 @@ type Blog struct {
 	OGImageType     string `json:"og_image_type,omitempty"`
 	OGImageSize     int    `json:"og_image_size,omitempty"`
 
+	// ViewCount is only populated by queries that rank posts by views.
+	ViewCount int64 `json:"view_count,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// CanBeManagedBy reports whether the user may edit or delete the post.
+// Super admins can manage every post; everyone else only their own.
+func (b *Blog) CanBeManagedBy(u *User) bool {
+	if u == nil {
+		return false
+	}
+	return u.IsSuperAdmin() || b.AuthorID == u.ID
+}
+
+// CoverURL returns the URL the post's cover image is served from: the media's
+// direct URL when it has one, otherwise the blog cover route.
+func (b *Blog) CoverURL() string {
+	if b.CoverMedia != nil && b.CoverMedia.PublicURL != "" {
+		return b.CoverMedia.PublicURL
+	}
+	return "/blogs/" + b.Slug + "/cover"
+}
+
+// wordsPerMinute is the reading speed ReadingTime assumes.
+const wordsPerMinute = 200
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ReadingTime estimates how many minutes the post takes to read, at least one.
+func (b *Blog) ReadingTime() int {
+	words := len(strings.Fields(htmlTagPattern.ReplaceAllString(b.Content, " ")))
+	return max(1, (words+wordsPerMinute-1)/wordsPerMinute)
+}
+
+// Tags returns the post's comma-separated meta keywords as a list.
+func (b *Blog) Tags() []string {
+	tags := []string{}
+	for _, t := range strings.Split(b.MetaKeywords, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// CoverAlt returns the cover image's alt text, falling back to the post title.
+func (b *Blog) CoverAlt() string {
+	if b.CoverMedia != nil && b.CoverMedia.AltText != "" {
+		return b.CoverMedia.AltText
+	}
+	return b.Title
+}
+
 // BlogFilter defines criteria for listing blogs.
 type BlogFilter struct {
 	IsPublished *bool
 	AuthorID    *uuid.UUID
 	Limit       int
 	Offset      int
+
+	// WithCoverMedia joins each post's cover media metadata (never the file data)
+	// into CoverMedia, so list pages can render cover images without a lookup per post.
+	WithCoverMedia bool
 }
 
 // CreateBlogInput represents input for creating a blog.
@@ -81,11 +138,54 @@ type UpdateBlogInput struct {
 }
 
 func (i *CreateBlogInput) Validate() error {
+	if err := trimAndCheckLength("title", &i.Title, MaxBlogTitleLength); err != nil {
+		return err
+	}
 	if i.Title == "" {
 		return ErrValidation{Field: "title", Message: "title is required"}
 	}
+	if err := trimAndCheckLength("content", &i.Content, MaxBlogContentLength); err != nil {
+		return err
+	}
 	if i.Content == "" {
 		return ErrValidation{Field: "content", Message: "content is required"}
 	}
-	return nil
+	if err := trimAndCheckLength("excerpt", &i.Excerpt, MaxBlogExcerptLength); err != nil {
+		return err
+	}
+	return validateBlogMeta(&i.MetaTitle, &i.MetaDescription, &i.MetaKeywords)
+}
+
+// Validate trims the fields being updated and checks their lengths.
+func (i *UpdateBlogInput) Validate() error {
+	if err := trimAndCheckOptionalLength("title", i.Title, MaxBlogTitleLength); err != nil {
+		return err
+	}
+	if i.Title != nil && *i.Title == "" {
+		return ErrValidation{Field: "title", Message: "title is required"}
+	}
+	if err := trimAndCheckOptionalLength("content", i.Content, MaxBlogContentLength); err != nil {
+		return err
+	}
+	if i.Content != nil && *i.Content == "" {
+		return ErrValidation{Field: "content", Message: "content is required"}
+	}
+	if err := trimAndCheckOptionalLength("excerpt", i.Excerpt, MaxBlogExcerptLength); err != nil {
+		return err
+	}
+	if err := trimAndCheckOptionalLength("slug", i.Slug, MaxBlogTitleLength); err != nil {
+		return err
+	}
+	return validateBlogMeta(i.MetaTitle, i.MetaDescription, i.MetaKeywords)
+}
+
+// validateBlogMeta trims and length-checks the optional SEO fields.
+func validateBlogMeta(title, description, keywords *string) error {
+	if err := trimAndCheckOptionalLength("meta_title", title, MaxMetaTitleLength); err != nil {
+		return err
+	}
+	if err := trimAndCheckOptionalLength("meta_description", description, MaxMetaDescriptionLength); err != nil {
+		return err
+	}
+	return trimAndCheckOptionalLength("meta_keywords", keywords, MaxMetaKeywordsLength)
 }
@@ -14,4 +14,12 @@ type EmailAuthClaims struct {
 // Token purposes
 const (
 	TokenPurposeEmailAuth = "email_auth"
+	TokenPurposeTwoFactor = "two_factor"
 )
+
+// TwoFactorClaims identify a sign-in waiting on a two-factor code.
+type TwoFactorClaims struct {
+	Provider string `json:"provider,omitempty"`
+	Purpose  string `json:"purpose"`
+	jwt.RegisteredClaims
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChangeTTL is how long the link confirming a new email address stays valid.
+const EmailChangeTTL = 24 * time.Hour
+
+// PendingEmailChange is a new email address waiting for its owner to confirm it. The
+// account keeps its current address until the link sent to the new one is followed.
+type PendingEmailChange struct {
+	UserID    uuid.UUID `json:"user_id"`
+	NewEmail  string    `json:"new_email"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsExpired checks if the confirmation link has expired.
+func (c *PendingEmailChange) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// NewPendingEmailChange creates a pending change to newEmail, confirmed by the token with the given hash.
+func NewPendingEmailChange(userID uuid.UUID, newEmail, hash string) *PendingEmailChange {
+	now := time.Now()
+	return &PendingEmailChange{
+		UserID:    userID,
+		NewEmail:  NormalizeEmail(newEmail),
+		TokenHash: hash,
+		ExpiresAt: now.Add(EmailChangeTTL),
+		CreatedAt: now,
+	}
+}
@@ -18,6 +18,11 @@ var (
 	ErrTokenExpired                 = errors.New("token has expired")
 	ErrEmailNotVerified             = errors.New("email not verified")
 	ErrAtLeastOneAuthMethodRequired = errors.New("at least one authentication method must be enabled")
+	ErrOAuthProviderMisconfigured   = errors.New("oauth provider credentials could not be decrypted")
+	ErrOAuthNoRefreshToken          = errors.New("oauth link has no refresh token")
+	ErrLastSignInMethod             = errors.New("cannot remove the account's only sign-in method")
+	ErrTOTPRequired                 = errors.New("two-factor authentication code required")
+	ErrInvalidTOTPCode              = errors.New("invalid two-factor authentication code")
 )
 
 // ErrValidation represents a validation error for a specific field.
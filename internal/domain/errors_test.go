@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorHelpers(t *testing.T) {
+	helpers := map[string]struct {
+		is       func(error) bool
+		sentinel error
+	}{
+		"IsNotFoundError":           {IsNotFoundError, ErrNotFound},
+		"IsConflictError":           {IsConflictError, ErrConflict},
+		"IsUnauthorizedError":       {IsUnauthorizedError, ErrUnauthorized},
+		"IsForbiddenError":          {IsForbiddenError, ErrForbidden},
+		"IsInvalidCredentialsError": {IsInvalidCredentialsError, ErrInvalidCredentials},
+	}
+
+	for name, h := range helpers {
+		t.Run(name, func(t *testing.T) {
+			if !h.is(h.sentinel) {
+				t.Errorf("%s(%v) = false, want true", name, h.sentinel)
+			}
+			// Repositories and services wrap errors with context
+			wrapped := fmt.Errorf("get blog: %w", h.sentinel)
+			if !h.is(wrapped) {
+				t.Errorf("%s(%v) = false for a wrapped sentinel, want true", name, wrapped)
+			}
+			if h.is(nil) {
+				t.Errorf("%s(nil) = true, want false", name)
+			}
+			if h.is(errors.New(h.sentinel.Error())) {
+				t.Errorf("%s matched a different error with the same message", name)
+			}
+			for other, o := range helpers {
+				if other != name && h.is(o.sentinel) {
+					t.Errorf("%s(%v) = true, want false", name, o.sentinel)
+				}
+			}
+		})
+	}
+}
+
+func TestIsValidationError(t *testing.T) {
+	err := ErrValidation{Field: "email", Message: "email is required"}
+
+	if !IsValidationError(err) {
+		t.Error("IsValidationError() = false for ErrValidation")
+	}
+	if !IsValidationError(fmt.Errorf("register: %w", err)) {
+		t.Error("IsValidationError() = false for a wrapped ErrValidation")
+	}
+	if IsValidationError(ErrNotFound) {
+		t.Error("IsValidationError(ErrNotFound) = true")
+	}
+	if want := "validation error on field 'email': email is required"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
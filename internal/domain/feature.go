@@ -17,6 +17,9 @@ const (
 	FeatureEmailPasswordAuth = "email_password_auth"
 	FeatureEmailVerification = "email_verification"
 	FeatureOAuth             = "oauth"
+	FeatureWelcomeEmail      = "welcome_email"
+	FeatureBlog              = "blog"
+	FeatureTwoFactor         = "two_factor"
 )
 
 // FeatureConfig represents the initial configuration for a feature flag.
@@ -20,6 +20,8 @@ type Media struct {
 	Data            []byte     `json:"-"` // Binary data, not exposed in JSON (only if StorageProvider=database)
 	ContentType     string     `json:"content_type"`
 	SizeBytes       int        `json:"size_bytes"`
+	Width           int        `json:"width,omitempty"`  // Image width in pixels, 0 when unknown
+	Height          int        `json:"height,omitempty"` // Image height in pixels, 0 when unknown
 	AltText         string     `json:"alt_text"`
 	StorageProvider string     `json:"storage_provider"`     // database, s3, etc.
 	FileKey         string     `json:"file_key,omitempty"`   // S3 key or file path
@@ -28,6 +30,16 @@ type Media struct {
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// MediaFilter defines criteria for listing media.
+type MediaFilter struct {
+	UserID        *uuid.UUID
+	ContentType   string // Prefix match, e.g. "image/"
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Limit         int
+	Offset        int
+}
+
 // CreateMediaInput represents input for creating a new media item.
 type CreateMediaInput struct {
 	UserID          *uuid.UUID
@@ -35,6 +47,8 @@ type CreateMediaInput struct {
 	Data            []byte
 	ContentType     string
 	SizeBytes       int
+	Width           int // Filled in by MediaService.Upload for images it can decode
+	Height          int
 	AltText         string
 	StorageProvider string // Optional, defaults to "database"
 }
@@ -58,5 +72,35 @@ func (i *CreateMediaInput) Validate() error {
 	if i.SizeBytes > 10*1024*1024 {
 		return ErrValidation{Field: "size", Message: "file too large (max 10MB)"}
 	}
-	return nil
+	if err := trimAndCheckLength("filename", &i.Filename, MaxFilenameLength); err != nil {
+		return err
+	}
+	return trimAndCheckLength("alt_text", &i.AltText, MaxAltTextLength)
+}
+
+// UpdateMediaInput represents input for updating an existing media item's metadata.
+type UpdateMediaInput struct {
+	Filename        *string
+	AltText         *string
+	ContentType     *string
+	StorageProvider *string
+	FileKey         *string
+	PublicURL       *string
+}
+
+func (i *UpdateMediaInput) Validate() error {
+	if i.ContentType != nil && *i.ContentType == "" {
+		return ErrValidation{Field: "content_type", Message: "content type is required"}
+	}
+	if i.StorageProvider != nil {
+		switch *i.StorageProvider {
+		case StorageProviderDatabase, StorageProviderS3, StorageProviderLocal:
+		default:
+			return ErrValidation{Field: "storage_provider", Message: "invalid storage provider"}
+		}
+	}
+	if err := trimAndCheckOptionalLength("filename", i.Filename, MaxFilenameLength); err != nil {
+		return err
+	}
+	return trimAndCheckOptionalLength("alt_text", i.AltText, MaxAltTextLength)
 }
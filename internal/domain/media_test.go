@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateMediaInput_Validate(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		input     UpdateMediaInput
+		wantField string // "" for valid input
+	}{
+		{name: "no changes", input: UpdateMediaInput{}},
+		{name: "alt text only", input: UpdateMediaInput{AltText: ptr("A sunset")}},
+		{name: "move to s3", input: UpdateMediaInput{StorageProvider: ptr(StorageProviderS3), FileKey: ptr("media/1.png"), PublicURL: ptr("https://cdn.example.com/media/1.png")}},
+		{name: "empty content type", input: UpdateMediaInput{ContentType: ptr("")}, wantField: "content_type"},
+		{name: "unknown storage provider", input: UpdateMediaInput{StorageProvider: ptr("ftp")}, wantField: "storage_provider"},
+		{name: "filename too long", input: UpdateMediaInput{Filename: ptr(strings.Repeat("a", MaxFilenameLength+1))}, wantField: "filename"},
+		{name: "alt text too long", input: UpdateMediaInput{AltText: ptr(strings.Repeat("a", MaxAltTextLength+1))}, wantField: "alt_text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validationField(t, tt.input.Validate()); got != tt.wantField {
+				t.Errorf("Validate() failed on %q, want %q", got, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestUpdateMediaInput_ValidateTrims(t *testing.T) {
+	filename, alt := "  photo.png ", " A sunset  "
+	input := UpdateMediaInput{Filename: &filename, AltText: &alt}
+	if err := input.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if filename != "photo.png" || alt != "A sunset" {
+		t.Errorf("got filename %q, alt text %q; want them trimmed", filename, alt)
+	}
+}
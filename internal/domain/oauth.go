@@ -1,6 +1,10 @@
 package domain
 
 import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +19,41 @@ const (
 	OAuthProviderLinkedIn OAuthProviderType = "linkedin"
 )
 
+// OAuthProviderPreset holds the standard endpoints and default scopes for a known provider.
+type OAuthProviderPreset struct {
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	// RevokeURL is the provider's token revocation endpoint, if it has one.
+	RevokeURL string
+	Scopes    []string
+}
+
+// OAuthProviderPresets are the defaults offered in the admin UI so only the client credentials need entering.
+var OAuthProviderPresets = map[OAuthProviderType]OAuthProviderPreset{
+	OAuthProviderGoogle: {
+		AuthURL:     "https://accounts.google.com/o/oauth2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://www.googleapis.com/oauth2/v2/userinfo",
+		RevokeURL:   "https://oauth2.googleapis.com/revoke",
+		Scopes:      []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
+	},
+	OAuthProviderGitHub: {
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		RevokeURL:   "https://api.github.com/applications/{client_id}/token",
+		Scopes:      []string{"read:user", "user:email"},
+	},
+	OAuthProviderLinkedIn: {
+		AuthURL:     "https://www.linkedin.com/oauth/v2/authorization",
+		TokenURL:    "https://www.linkedin.com/oauth/v2/accessToken",
+		UserInfoURL: "https://api.linkedin.com/v2/userinfo",
+		RevokeURL:   "https://www.linkedin.com/oauth/v2/revoke",
+		Scopes:      []string{"openid", "profile", "email"},
+	},
+}
+
 // OAuthProvider represents an OAuth provider configuration.
 type OAuthProvider struct {
 	Provider     OAuthProviderType `json:"provider"`
@@ -25,8 +64,78 @@ type OAuthProvider struct {
 	AuthURL      string            `json:"auth_url"`
 	TokenURL     string            `json:"token_url"`
 	UserInfoURL  string            `json:"user_info_url"`
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
+	// EndSessionURL is the provider's OIDC end_session_endpoint. Setting it opts the provider
+	// into RP-initiated logout; left empty, signing out only ends the local session.
+	EndSessionURL string    `json:"end_session_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// DecryptionFailed is set when the stored credentials could not be decrypted,
+	// typically because AUTH_SECRET changed. The provider cannot be used until they are re-entered.
+	DecryptionFailed bool `json:"decryption_failed"`
+}
+
+// ValidateEndpoints checks that the auth, token, user info and end session URLs are absolute
+// HTTPS URLs. Plain HTTP is accepted for localhost when allowLocalhost is set (development).
+// URLs may only be left blank while the provider is disabled, except the optional end session URL.
+func (p *OAuthProvider) ValidateEndpoints(allowLocalhost bool) error {
+	endpoints := []struct {
+		field string
+		label string
+		value string
+	}{
+		{"auth_url", "Auth URL", p.AuthURL},
+		{"token_url", "Token URL", p.TokenURL},
+		{"user_info_url", "User info URL", p.UserInfoURL},
+		{"end_session_url", "End session URL", p.EndSessionURL},
+	}
+
+	for _, e := range endpoints {
+		if e.value == "" {
+			if p.Enabled && e.field != "end_session_url" {
+				return ErrValidation{Field: e.field, Message: e.label + " is required to enable the provider"}
+			}
+			continue
+		}
+		if err := validateEndpointURL(e.value, allowLocalhost); err != nil {
+			return ErrValidation{Field: e.field, Message: e.label + " " + err.Error()}
+		}
+	}
+	return nil
+}
+
+// CallbackURL returns the redirect URI the app uses for this provider under publicURL.
+// It must be registered with the provider exactly as returned.
+func (p *OAuthProvider) CallbackURL(publicURL string) string {
+	return strings.TrimRight(publicURL, "/") + "/auth/" + string(p.Provider) + "/callback"
+}
+
+// ValidateCallbackURL checks that the callback built from publicURL is an absolute
+// http(s) URL, which providers require of a redirect URI.
+func (p *OAuthProvider) ValidateCallbackURL(publicURL string) error {
+	u, err := url.Parse(p.CallbackURL(publicURL))
+	if err != nil || !u.IsAbs() || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return ErrValidation{Field: "callback_url", Message: "Callback URL must be an absolute http(s) URL; check PUBLIC_URL"}
+	}
+	return nil
+}
+
+func validateEndpointURL(raw string, allowLocalhost bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return errors.New("must be an absolute URL")
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if allowLocalhost && (host == "localhost" || net.ParseIP(host).IsLoopback()) {
+			return nil
+		}
+	}
+	return errors.New("must use https")
 }
 
 // UserOAuth represents a link between a user and an OAuth provider.
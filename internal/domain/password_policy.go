@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// App setting keys for the password policy.
+const (
+	SettingPasswordMinLength     = "password.min_length"
+	SettingPasswordRequireUpper  = "password.require_upper"
+	SettingPasswordRequireLower  = "password.require_lower"
+	SettingPasswordRequireDigit  = "password.require_digit"
+	SettingPasswordRequireSymbol = "password.require_symbol"
+)
+
+// Bounds for the configurable minimum password length. The upper bound stays well under
+// bcrypt's 72-byte input limit.
+const (
+	MinPasswordLength    = 8
+	MaxPasswordMinLength = 64
+)
+
+// Password rule IDs, used by forms to tell which rules a password failed.
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleUpper     = "upper"
+	PasswordRuleLower     = "lower"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+)
+
+// PasswordRule is one requirement of the password policy, with a description for forms.
+type PasswordRule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// PasswordPolicy is the set of rules every new password must meet, whether chosen at
+// signup, on reset, or from the settings page. It is the single definition both
+// validation and the forms' rule lists are built from.
+type PasswordPolicy struct {
+	MinLength     int  `json:"min_length"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+}
+
+// DefaultPasswordPolicy returns the policy used until a super admin changes it.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: MinPasswordLength}
+}
+
+// PasswordPolicyFromMap builds the policy from stored key/value pairs, using the
+// defaults for any key that has not been saved or can't be parsed.
+func PasswordPolicyFromMap(values map[string]string) PasswordPolicy {
+	p := DefaultPasswordPolicy()
+	if n, err := strconv.Atoi(values[SettingPasswordMinLength]); err == nil && n >= MinPasswordLength && n <= MaxPasswordMinLength {
+		p.MinLength = n
+	}
+	flags := map[string]*bool{
+		SettingPasswordRequireUpper:  &p.RequireUpper,
+		SettingPasswordRequireLower:  &p.RequireLower,
+		SettingPasswordRequireDigit:  &p.RequireDigit,
+		SettingPasswordRequireSymbol: &p.RequireSymbol,
+	}
+	for key, flag := range flags {
+		if v, err := strconv.ParseBool(values[key]); err == nil {
+			*flag = v
+		}
+	}
+	return p
+}
+
+// Map returns the policy as key/value pairs for storage.
+func (p PasswordPolicy) Map() map[string]string {
+	return map[string]string{
+		SettingPasswordMinLength:     strconv.Itoa(p.MinLength),
+		SettingPasswordRequireUpper:  strconv.FormatBool(p.RequireUpper),
+		SettingPasswordRequireLower:  strconv.FormatBool(p.RequireLower),
+		SettingPasswordRequireDigit:  strconv.FormatBool(p.RequireDigit),
+		SettingPasswordRequireSymbol: strconv.FormatBool(p.RequireSymbol),
+	}
+}
+
+// Validate checks the minimum length is within bounds.
+func (p *PasswordPolicy) Validate() error {
+	if p.MinLength < MinPasswordLength || p.MinLength > MaxPasswordMinLength {
+		return ErrValidation{Field: "min_length", Message: "minimum length must be between " + strconv.Itoa(MinPasswordLength) + " and " + strconv.Itoa(MaxPasswordMinLength)}
+	}
+	return nil
+}
+
+// Rules lists the policy's requirements in the order forms show them.
+func (p PasswordPolicy) Rules() []PasswordRule {
+	rules := []PasswordRule{{ID: PasswordRuleMinLength, Description: "At least " + strconv.Itoa(p.MinLength) + " characters"}}
+	if p.RequireUpper {
+		rules = append(rules, PasswordRule{ID: PasswordRuleUpper, Description: "An uppercase letter"})
+	}
+	if p.RequireLower {
+		rules = append(rules, PasswordRule{ID: PasswordRuleLower, Description: "A lowercase letter"})
+	}
+	if p.RequireDigit {
+		rules = append(rules, PasswordRule{ID: PasswordRuleDigit, Description: "A number"})
+	}
+	if p.RequireSymbol {
+		rules = append(rules, PasswordRule{ID: PasswordRuleSymbol, Description: "A symbol, such as ! or #"})
+	}
+	return rules
+}
+
+// Check returns a *PasswordPolicyError for field listing every rule password fails,
+// or nil if it meets them all.
+func (p PasswordPolicy) Check(field, password string) error {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	met := map[string]bool{
+		PasswordRuleMinLength: utf8.RuneCountInString(password) >= p.MinLength,
+		PasswordRuleUpper:     hasUpper,
+		PasswordRuleLower:     hasLower,
+		PasswordRuleDigit:     hasDigit,
+		PasswordRuleSymbol:    hasSymbol,
+	}
+
+	var failed []PasswordRule
+	for _, rule := range p.Rules() {
+		if !met[rule.ID] {
+			failed = append(failed, rule)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &PasswordPolicyError{Field: field, Violations: failed}
+}
+
+// PasswordPolicyError lists the rules a password failed, so forms can highlight them.
+// It also matches ErrValidation with errors.As, so callers that only show a message
+// need no special handling.
+type PasswordPolicyError struct {
+	Field      string
+	Violations []PasswordRule
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrValidation{Field: e.Field, Message: e.message()}.Error()
+}
+
+// As lets errors.As treat the error as an ErrValidation.
+func (e *PasswordPolicyError) As(target any) bool {
+	if v, ok := target.(*ErrValidation); ok {
+		*v = ErrValidation{Field: e.Field, Message: e.message()}
+		return true
+	}
+	return false
+}
+
+// ViolationIDs returns the IDs of the failed rules.
+func (e *PasswordPolicyError) ViolationIDs() []string {
+	ids := make([]string, len(e.Violations))
+	for i, rule := range e.Violations {
+		ids[i] = rule.ID
+	}
+	return ids
+}
+
+func (e *PasswordPolicyError) message() string {
+	descriptions := make([]string, len(e.Violations))
+	for i, rule := range e.Violations {
+		descriptions[i] = strings.ToLower(rule.Description)
+	}
+	return "password needs " + strings.Join(descriptions, ", ")
+}
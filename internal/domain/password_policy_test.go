@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 12, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		failed   []string // rule IDs, nil if the password passes
+	}{
+		{name: "default policy accepts 8 characters", policy: DefaultPasswordPolicy(), password: "abcdefgh"},
+		{name: "default policy rejects 7 characters", policy: DefaultPasswordPolicy(), password: "abcdefg", failed: []string{PasswordRuleMinLength}},
+		{name: "length counts characters not bytes", policy: DefaultPasswordPolicy(), password: "ééééééé", failed: []string{PasswordRuleMinLength}},
+		{name: "missing uppercase", policy: PasswordPolicy{MinLength: 8, RequireUpper: true}, password: "lowercase1", failed: []string{PasswordRuleUpper}},
+		{name: "missing lowercase", policy: PasswordPolicy{MinLength: 8, RequireLower: true}, password: "UPPERCASE1", failed: []string{PasswordRuleLower}},
+		{name: "missing digit", policy: PasswordPolicy{MinLength: 8, RequireDigit: true}, password: "NoDigitsHere", failed: []string{PasswordRuleDigit}},
+		{name: "missing symbol", policy: PasswordPolicy{MinLength: 8, RequireSymbol: true}, password: "NoSymbols123", failed: []string{PasswordRuleSymbol}},
+		{name: "space counts as a symbol", policy: PasswordPolicy{MinLength: 8, RequireSymbol: true}, password: "two words"},
+		{name: "strict policy accepts a strong password", policy: strict, password: "Correct-Horse-9"},
+		{
+			name:     "strict policy rejects a weak password with every failed rule",
+			policy:   strict,
+			password: "password",
+			failed:   []string{PasswordRuleMinLength, PasswordRuleUpper, PasswordRuleDigit, PasswordRuleSymbol},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check("password", tt.password)
+			if tt.failed == nil {
+				if err != nil {
+					t.Fatalf("Check() = %v, want nil", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Check() = %v, want *PasswordPolicyError", err)
+			}
+			if got := policyErr.ViolationIDs(); !slices.Equal(got, tt.failed) {
+				t.Errorf("ViolationIDs() = %v, want %v", got, tt.failed)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyError_MatchesErrValidation(t *testing.T) {
+	err := PasswordPolicy{MinLength: 10, RequireDigit: true}.Check("new_password", "short")
+
+	if !IsValidationError(err) {
+		t.Fatalf("IsValidationError(%v) = false, want true", err)
+	}
+	var validationErr ErrValidation
+	if !errors.As(err, &validationErr) {
+		t.Fatal("errors.As did not match ErrValidation")
+	}
+	if validationErr.Field != "new_password" {
+		t.Errorf("Field = %q, want new_password", validationErr.Field)
+	}
+	want := "password needs at least 10 characters, a number"
+	if validationErr.Message != want {
+		t.Errorf("Message = %q, want %q", validationErr.Message, want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Error() = %q, want it to contain %q", err.Error(), want)
+	}
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		minLength int
+		wantErr   bool
+	}{
+		{minLength: MinPasswordLength - 1, wantErr: true},
+		{minLength: MinPasswordLength},
+		{minLength: MaxPasswordMinLength},
+		{minLength: MaxPasswordMinLength + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		p := PasswordPolicy{MinLength: tt.minLength}
+		if err := p.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with min length %d = %v, want error %v", tt.minLength, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Package domain contains the core business entities and rules.
+package domain
+
+import "time"
+
+// RateLimitEntry is a point-in-time view of one rate-limited key (usually a client IP).
+type RateLimitEntry struct {
+	IP       string
+	Tokens   float64
+	Burst    int
+	LastSeen time.Time
+}
+
+// Limited reports whether the key currently has no tokens left.
+func (e RateLimitEntry) Limited() bool {
+	return e.Tokens < 1
+}
@@ -2,6 +2,8 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +18,8 @@ type Session struct {
 	ExpiresAt      time.Time `json:"expires_at"`
 	CreatedAt      time.Time `json:"created_at"`
 	LastActivityAt time.Time `json:"last_activity_at"`
+	// AuthProvider is the OAuth provider the session was signed in through, empty otherwise.
+	AuthProvider OAuthProviderType `json:"auth_provider,omitempty"`
 }
 
 // IsExpired checks if the session has expired.
@@ -23,11 +27,36 @@ func (s *Session) IsExpired() bool {
 	return time.Now().After(s.ExpiresAt)
 }
 
+// PublicID returns a stable handle for the session that is safe to show in pages and URLs.
+// The session ID itself is the cookie value and must never leave the cookie.
+func (s *Session) PublicID() string {
+	sum := sha256.Sum256([]byte(s.ID))
+	return hex.EncodeToString(sum[:12])
+}
+
 // SessionDuration is the default session lifetime.
 const SessionDuration = 24 * time.Hour * 7 // 7 days
 
-// NewSession creates a new session for a user.
-// NewSession creates a new session for a user.
+// NeedsRenewal reports whether less than a fifth of a session lifetime of ttl remains,
+// the point at which an active session is extended.
+func (s *Session) NeedsRenewal(ttl time.Duration) bool {
+	return time.Until(s.ExpiresAt) < ttl/5
+}
+
+// OnlineWindow is how recently a session must have been used for its user to count as online.
+const OnlineWindow = 5 * time.Minute
+
+// OnlineUser is a signed-in user with a recently active session.
+type OnlineUser struct {
+	UserID         uuid.UUID
+	Name           string
+	Role           Role
+	IPAddress      string
+	LastActivityAt time.Time
+	Sessions       int
+}
+
+// NewSession creates a new session for a user, lasting SessionDuration.
 func NewSession(userID uuid.UUID, ip, userAgent string) *Session {
 	now := time.Now()
 	return &Session{
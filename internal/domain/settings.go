@@ -0,0 +1,151 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// App setting keys for the landing page content.
+const (
+	SettingHomeHeroTitle     = "home.hero_title"
+	SettingHomeHeroHighlight = "home.hero_highlight"
+	SettingHomeHeroSubtitle  = "home.hero_subtitle"
+	SettingHomeCTALabel      = "home.cta_label"
+	SettingHomeCTAURL        = "home.cta_url"
+
+	SettingWelcomeEmailSubject = "email.welcome_subject"
+	SettingWelcomeEmailBody    = "email.welcome_body"
+)
+
+// Maximum lengths, in characters, for the landing page fields.
+const (
+	MaxHeroTitleLength    = 120
+	MaxHeroSubtitleLength = 500
+	MaxCTALabelLength     = 50
+	MaxCTAURLLength       = 500
+
+	MaxEmailSubjectLength = 200
+	MaxEmailBodyLength    = 20000
+)
+
+// HomePageSettings is the editable content of the public landing page.
+// The highlight is shown after the title in the accent gradient and may be empty.
+type HomePageSettings struct {
+	HeroTitle     string `json:"hero_title"`
+	HeroHighlight string `json:"hero_highlight"`
+	HeroSubtitle  string `json:"hero_subtitle"`
+	CTALabel      string `json:"cta_label"`
+	CTAURL        string `json:"cta_url"`
+}
+
+// DefaultHomePageSettings returns the landing page content used until a super admin edits it.
+func DefaultHomePageSettings() HomePageSettings {
+	return HomePageSettings{
+		HeroTitle:     "Build faster with",
+		HeroHighlight: "Full Stack Go Template",
+		HeroSubtitle:  "A professional full-stack Go application with clean architecture, HTMX for dynamic interactions, Alpine.js for reactivity, and Tailwind CSS with DaisyUI for modern styling.",
+		CTALabel:      "Get Started Free",
+		CTAURL:        "/signup",
+	}
+}
+
+// HomePageSettingsFromMap builds settings from stored key/value pairs, using the
+// defaults for any key that has not been saved.
+func HomePageSettingsFromMap(values map[string]string) HomePageSettings {
+	s := DefaultHomePageSettings()
+	fields := map[string]*string{
+		SettingHomeHeroTitle:     &s.HeroTitle,
+		SettingHomeHeroHighlight: &s.HeroHighlight,
+		SettingHomeHeroSubtitle:  &s.HeroSubtitle,
+		SettingHomeCTALabel:      &s.CTALabel,
+		SettingHomeCTAURL:        &s.CTAURL,
+	}
+	for key, field := range fields {
+		if v, ok := values[key]; ok {
+			*field = v
+		}
+	}
+	return s
+}
+
+// Map returns the settings as key/value pairs for storage.
+func (s HomePageSettings) Map() map[string]string {
+	return map[string]string{
+		SettingHomeHeroTitle:     s.HeroTitle,
+		SettingHomeHeroHighlight: s.HeroHighlight,
+		SettingHomeHeroSubtitle:  s.HeroSubtitle,
+		SettingHomeCTALabel:      s.CTALabel,
+		SettingHomeCTAURL:        s.CTAURL,
+	}
+}
+
+// Validate trims the fields and checks their lengths. The call-to-action link must be
+// a site-relative path or an absolute http(s) URL, so it can't carry a javascript: URL.
+func (s *HomePageSettings) Validate() error {
+	if err := trimAndCheckLength("hero_title", &s.HeroTitle, MaxHeroTitleLength); err != nil {
+		return err
+	}
+	if s.HeroTitle == "" {
+		return ErrValidation{Field: "hero_title", Message: "hero title is required"}
+	}
+	if err := trimAndCheckLength("hero_highlight", &s.HeroHighlight, MaxHeroTitleLength); err != nil {
+		return err
+	}
+	if err := trimAndCheckLength("hero_subtitle", &s.HeroSubtitle, MaxHeroSubtitleLength); err != nil {
+		return err
+	}
+	if err := trimAndCheckLength("cta_label", &s.CTALabel, MaxCTALabelLength); err != nil {
+		return err
+	}
+	if err := trimAndCheckLength("cta_url", &s.CTAURL, MaxCTAURLLength); err != nil {
+		return err
+	}
+	if s.CTALabel == "" || s.CTAURL == "" {
+		return ErrValidation{Field: "cta_label", Message: "call to action label and link are required"}
+	}
+
+	u, err := url.Parse(s.CTAURL)
+	switch {
+	case err != nil:
+		return ErrValidation{Field: "cta_url", Message: "call to action link is not a valid URL"}
+	case u.IsAbs():
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return ErrValidation{Field: "cta_url", Message: "call to action link must use http or https"}
+		}
+	case !strings.HasPrefix(s.CTAURL, "/") || strings.HasPrefix(s.CTAURL, "//"):
+		return ErrValidation{Field: "cta_url", Message: "call to action link must start with / or http(s)://"}
+	}
+	return nil
+}
+
+// WelcomeEmailSettings overrides the welcome email sent after a user verifies their email.
+// Empty fields use the built-in subject and body.
+type WelcomeEmailSettings struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// WelcomeEmailSettingsFromMap builds settings from stored key/value pairs.
+func WelcomeEmailSettingsFromMap(values map[string]string) WelcomeEmailSettings {
+	return WelcomeEmailSettings{
+		Subject: values[SettingWelcomeEmailSubject],
+		Body:    values[SettingWelcomeEmailBody],
+	}
+}
+
+// Map returns the settings as key/value pairs for storage.
+func (s WelcomeEmailSettings) Map() map[string]string {
+	return map[string]string{
+		SettingWelcomeEmailSubject: s.Subject,
+		SettingWelcomeEmailBody:    s.Body,
+	}
+}
+
+// Validate trims the fields and checks their lengths. The body's template syntax is
+// checked by the settings service, which knows the template engine.
+func (s *WelcomeEmailSettings) Validate() error {
+	if err := trimAndCheckLength("subject", &s.Subject, MaxEmailSubjectLength); err != nil {
+		return err
+	}
+	return trimAndCheckLength("body", &s.Body, MaxEmailBodyLength)
+}
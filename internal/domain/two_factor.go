@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCodeCount is how many recovery codes a user is given at a time.
+const RecoveryCodeCount = 10
+
+// TOTPSecret is a user's authenticator app secret. Secret is the plaintext base32 value;
+// it is encrypted at rest. EnabledAt is nil while enrollment is still pending.
+type TOTPSecret struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Enabled reports whether the user has confirmed their authenticator.
+func (t *TOTPSecret) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// TwoFactorStatus summarizes a user's two-factor setup for the settings page.
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TOTPChallengeError is returned by sign-in methods when the user must still enter a
+// two-factor code. Token identifies the pending sign-in for the verification step.
+// It matches ErrTOTPRequired with errors.Is.
+type TOTPChallengeError struct {
+	Token string
+}
+
+func (e *TOTPChallengeError) Error() string { return ErrTOTPRequired.Error() }
+
+func (e *TOTPChallengeError) Unwrap() error { return ErrTOTPRequired }
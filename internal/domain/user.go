@@ -3,6 +3,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,11 +23,13 @@ type User struct {
 	ID                         uuid.UUID  `json:"id"`
 	Email                      string     `json:"email"`
 	Name                       string     `json:"name"`
+	Username                   *string    `json:"username,omitempty"`
 	PasswordHash               string     `json:"-"` // Never expose in JSON
 	Role                       Role       `json:"role"`
 	ProfileMediaID             *uuid.UUID `json:"profile_media_id,omitempty"`
 	ProfileMedia               *Media     `json:"-"` // Loaded on demand
 	EmailVerified              bool       `json:"email_verified"`
+	ActivityDigestEnabled      bool       `json:"activity_digest_enabled"`
 	VerificationToken          *string    `json:"-"`
 	VerificationTokenExpiresAt *time.Time `json:"-"`
 	Status                     UserStatus `json:"status"`
@@ -34,12 +37,20 @@ type User struct {
 	UpdatedAt                  time.Time  `json:"updated_at"`
 }
 
+// UserDetails aggregates a user with their linked OAuth accounts and last login, for admin views.
+// OAuth links never carry access or refresh tokens.
+type UserDetails struct {
+	User       *User
+	OAuthLinks []*UserOAuth
+	LastLogin  *ActivityLog // nil if the user has never signed in
+}
+
 // NewUser creates a new User with a generated UUID and timestamps.
 func NewUser(email, name, passwordHash string, role Role) *User {
 	now := time.Now()
 	return &User{
 		ID:           uuid.New(),
-		Email:        email,
+		Email:        NormalizeEmail(email),
 		Name:         name,
 		PasswordHash: passwordHash,
 		Role:         role,
@@ -49,17 +60,47 @@ func NewUser(email, name, passwordHash string, role Role) *User {
 	}
 }
 
+// NormalizeEmail trims surrounding whitespace and lowercases an email address,
+// so "  User@Example.com " and "user@example.com" refer to the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // Validate checks if the user data is valid.
 func (u *User) Validate() error {
 	if u.Email == "" {
 		return ErrValidation{Field: "email", Message: "email is required"}
 	}
+	if err := trimAndCheckLength("name", &u.Name, MaxNameLength); err != nil {
+		return err
+	}
 	if u.Name == "" {
 		return ErrValidation{Field: "name", Message: "name is required"}
 	}
 	if !u.Role.IsValid() {
 		return ErrValidation{Field: "role", Message: "invalid role"}
 	}
+	if u.Username != nil {
+		if err := ValidateUsername(*u.Username); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateUsername checks that a username is 3-50 characters of letters, digits, '.', '_' or '-'.
+// '@' is never allowed so a sign-in identifier can be told apart from an email address.
+func ValidateUsername(username string) error {
+	if len(username) < 3 || len(username) > 50 {
+		return ErrValidation{Field: "username", Message: "username must be between 3 and 50 characters"}
+	}
+	for _, c := range username {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return ErrValidation{Field: "username", Message: "username may only contain letters, digits, '.', '_' and '-'"}
+		}
+	}
 	return nil
 }
 
@@ -86,20 +127,22 @@ type RegisterInput struct {
 	ConfirmPassword string `json:"confirm_password"`
 }
 
-// Validate checks if the registration input is valid.
+// Validate checks if the registration input is valid. The password's strength is
+// checked separately against the PasswordPolicy.
 func (i *RegisterInput) Validate() error {
+	i.Email = NormalizeEmail(i.Email)
 	if i.Email == "" {
 		return ErrValidation{Field: "email", Message: "email is required"}
 	}
+	if err := trimAndCheckLength("name", &i.Name, MaxNameLength); err != nil {
+		return err
+	}
 	if i.Name == "" {
 		return ErrValidation{Field: "name", Message: "name is required"}
 	}
 	if i.Password == "" {
 		return ErrValidation{Field: "password", Message: "password is required"}
 	}
-	if len(i.Password) < 8 {
-		return ErrValidation{Field: "password", Message: "password must be at least 8 characters"}
-	}
 	if i.Password != i.ConfirmPassword {
 		return ErrValidation{Field: "confirm_password", Message: "passwords do not match"}
 	}
@@ -107,15 +150,20 @@ func (i *RegisterInput) Validate() error {
 }
 
 // LoginInput represents the input for user login.
+// Identifier is either an email address or a username; it is treated as an email if it contains '@'.
 type LoginInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Identifier string `json:"identifier"`
+	Password   string `json:"password"`
 }
 
 // Validate checks if the login input is valid.
 func (i *LoginInput) Validate() error {
-	if i.Email == "" {
-		return ErrValidation{Field: "email", Message: "email is required"}
+	i.Identifier = strings.TrimSpace(i.Identifier)
+	if strings.Contains(i.Identifier, "@") {
+		i.Identifier = NormalizeEmail(i.Identifier)
+	}
+	if i.Identifier == "" {
+		return ErrValidation{Field: "identifier", Message: "email or username is required"}
 	}
 	if i.Password == "" {
 		return ErrValidation{Field: "password", Message: "password is required"}
@@ -131,20 +179,22 @@ type CreateUserInput struct {
 	Role     Role   `json:"role"`
 }
 
-// Validate checks if the create user input is valid.
+// Validate checks if the create user input is valid. The password's strength is
+// checked separately against the PasswordPolicy.
 func (i *CreateUserInput) Validate() error {
+	i.Email = NormalizeEmail(i.Email)
 	if i.Email == "" {
 		return ErrValidation{Field: "email", Message: "email is required"}
 	}
+	if err := trimAndCheckLength("name", &i.Name, MaxNameLength); err != nil {
+		return err
+	}
 	if i.Name == "" {
 		return ErrValidation{Field: "name", Message: "name is required"}
 	}
 	if i.Password == "" {
 		return ErrValidation{Field: "password", Message: "password is required"}
 	}
-	if len(i.Password) < 8 {
-		return ErrValidation{Field: "password", Message: "password must be at least 8 characters"}
-	}
 	if !i.Role.IsValid() {
 		return ErrValidation{Field: "role", Message: "invalid role"}
 	}
@@ -155,6 +205,8 @@ func (i *CreateUserInput) Validate() error {
 type UpdateUserInput struct {
 	Email          *string    `json:"email,omitempty"`
 	Name           *string    `json:"name,omitempty"`
+	Username       *string    `json:"username,omitempty"` // empty string clears the username
+	ActivityDigest *bool      `json:"activity_digest,omitempty"`
 	Role           *Role      `json:"role,omitempty"`
 	ProfileMediaID *uuid.UUID `json:"profile_media_id,omitempty"`
 }
@@ -201,14 +253,12 @@ type UpdatePasswordInput struct {
 	ConfirmPassword string `json:"confirm_password"`
 }
 
-// ValidateNewPassword checks if the new password fields are valid.
+// ValidateNewPassword checks if the new password fields are present and match. The
+// password's strength is checked separately against the PasswordPolicy.
 func (i *UpdatePasswordInput) ValidateNewPassword() error {
 	if i.NewPassword == "" {
 		return ErrValidation{Field: "new_password", Message: "new password is required"}
 	}
-	if len(i.NewPassword) < 8 {
-		return ErrValidation{Field: "new_password", Message: "password must be at least 8 characters"}
-	}
 	if i.NewPassword != i.ConfirmPassword {
 		return ErrValidation{Field: "confirm_password", Message: "passwords do not match"}
 	}
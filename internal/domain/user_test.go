@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// validationField returns the field of a validation error, or "" for nil.
+func validationField(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var validationErr ErrValidation
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error %v is not an ErrValidation", err)
+	}
+	return validationErr.Field
+}
+
+func TestRegisterInput_Validate(t *testing.T) {
+	valid := RegisterInput{Email: "user@example.com", Name: "User", Password: "password1", ConfirmPassword: "password1"}
+
+	tests := []struct {
+		name      string
+		modify    func(*RegisterInput)
+		wantField string // "" for valid input
+	}{
+		{name: "valid", modify: func(*RegisterInput) {}},
+		{name: "missing email", modify: func(i *RegisterInput) { i.Email = "  " }, wantField: "email"},
+		{name: "missing name", modify: func(i *RegisterInput) { i.Name = " " }, wantField: "name"},
+		{name: "name too long", modify: func(i *RegisterInput) { i.Name = strings.Repeat("a", MaxNameLength+1) }, wantField: "name"},
+		{name: "missing password", modify: func(i *RegisterInput) { i.Password = ""; i.ConfirmPassword = "" }, wantField: "password"},
+		{name: "passwords differ", modify: func(i *RegisterInput) { i.ConfirmPassword = "password2" }, wantField: "confirm_password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid
+			tt.modify(&input)
+			if got := validationField(t, input.Validate()); got != tt.wantField {
+				t.Errorf("Validate() failed on %q, want %q", got, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestRegisterInput_ValidateNormalizes(t *testing.T) {
+	input := RegisterInput{Email: " User@Example.COM ", Name: "  Ada  ", Password: "password1", ConfirmPassword: "password1"}
+	if err := input.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if input.Email != "user@example.com" {
+		t.Errorf("Email = %q, want user@example.com", input.Email)
+	}
+	if input.Name != "Ada" {
+		t.Errorf("Name = %q, want Ada", input.Name)
+	}
+}
+
+func TestCreateUserInput_Validate(t *testing.T) {
+	valid := CreateUserInput{Email: "user@example.com", Name: "User", Password: "password1", Role: RoleUser}
+
+	tests := []struct {
+		name      string
+		modify    func(*CreateUserInput)
+		wantField string
+	}{
+		{name: "valid", modify: func(*CreateUserInput) {}},
+		{name: "valid admin", modify: func(i *CreateUserInput) { i.Role = RoleAdmin }},
+		{name: "missing email", modify: func(i *CreateUserInput) { i.Email = "" }, wantField: "email"},
+		{name: "missing name", modify: func(i *CreateUserInput) { i.Name = "" }, wantField: "name"},
+		{name: "missing password", modify: func(i *CreateUserInput) { i.Password = "" }, wantField: "password"},
+		{name: "invalid role", modify: func(i *CreateUserInput) { i.Role = Role("owner") }, wantField: "role"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid
+			tt.modify(&input)
+			if got := validationField(t, input.Validate()); got != tt.wantField {
+				t.Errorf("Validate() failed on %q, want %q", got, tt.wantField)
+			}
+		})
+	}
+}
+
+// Input validation only checks a password is present; its strength is checked against
+// the policy, as the services do after Validate.
+func TestWeakPasswordPassesInputValidationButFailsPolicy(t *testing.T) {
+	input := RegisterInput{Email: "user@example.com", Name: "User", Password: "abc", ConfirmPassword: "abc"}
+	if err := input.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+
+	err := DefaultPasswordPolicy().Check("password", input.Password)
+	if validationField(t, err) != "password" {
+		t.Errorf("Check() = %v, want a validation error on password", err)
+	}
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Maximum lengths, in characters, for free-text fields. Names, titles and filenames
+// match their VARCHAR(255) columns; the TEXT columns are capped at sizes well above
+// normal use so a single request can't store megabytes of text.
+const (
+	MaxNameLength            = 255
+	MaxBlogTitleLength       = 255
+	MaxBlogExcerptLength     = 1000
+	MaxBlogContentLength     = 500000
+	MaxMetaTitleLength       = 255
+	MaxMetaDescriptionLength = 500
+	MaxMetaKeywordsLength    = 500
+	MaxFilenameLength        = 255
+	MaxAltTextLength         = 500
+)
+
+// trimAndCheckLength trims surrounding whitespace from *s in place and returns a
+// validation error for field if the result is longer than max characters.
+func trimAndCheckLength(field string, s *string, max int) error {
+	*s = strings.TrimSpace(*s)
+	if utf8.RuneCountInString(*s) > max {
+		label := strings.ReplaceAll(field, "_", " ")
+		return ErrValidation{Field: field, Message: fmt.Sprintf("%s must be at most %d characters", label, max)}
+	}
+	return nil
+}
+
+// trimAndCheckOptionalLength is trimAndCheckLength for optional (pointer) fields.
+func trimAndCheckOptionalLength(field string, s *string, max int) error {
+	if s == nil {
+		return nil
+	}
+	return trimAndCheckLength(field, s, max)
+}
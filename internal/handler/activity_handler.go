@@ -33,8 +33,10 @@ func (h *ActivityHandler) UserActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user activities (limit to last 50)
-	activities, err := h.activityService.GetUserActivities(r.Context(), user.ID, 50)
+	limit := h.pageSize(r, 20)
+	page, offset := pageParams(r, limit)
+
+	activities, total, err := h.activityService.GetUserActivities(r.Context(), user.ID, limit, offset)
 	if err != nil {
 		h.Error(w, r, http.StatusInternalServerError, "Failed to load activity logs")
 		return
@@ -58,7 +60,7 @@ func (h *ActivityHandler) UserActivity(w http.ResponseWriter, r *http.Request) {
 
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
-	h.RenderTempl(w, r, profile.UserActivity("Activity Log", formattedActivities, user, theme, themeEnabled, oauthEnabled))
+	h.RenderTempl(w, r, profile.UserActivity("Activity Log", formattedActivities, newPagination(r, page, limit, total), user, theme, themeEnabled, oauthEnabled))
 }
 
 // formatTimeAgo formats a time as a relative time string.
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
@@ -15,15 +19,22 @@ type AdminOAuthHandler struct {
 	*Handler
 	oauthRepo    repository.OAuthRepository
 	auditService service.AuditService
+	userService  service.UserService
+	emailService service.EmailService
 	appURL       string
+	// allowLocalhostURLs accepts http://localhost provider endpoints (development only)
+	allowLocalhostURLs bool
 }
 
-func NewAdminOAuthHandler(base *Handler, oauthRepo repository.OAuthRepository, auditService service.AuditService, appURL string) *AdminOAuthHandler {
+func NewAdminOAuthHandler(base *Handler, oauthRepo repository.OAuthRepository, auditService service.AuditService, userService service.UserService, emailService service.EmailService, appURL string, allowLocalhostURLs bool) *AdminOAuthHandler {
 	return &AdminOAuthHandler{
-		Handler:      base,
-		oauthRepo:    oauthRepo,
-		auditService: auditService,
-		appURL:       appURL,
+		Handler:            base,
+		oauthRepo:          oauthRepo,
+		auditService:       auditService,
+		userService:        userService,
+		emailService:       emailService,
+		appURL:             appURL,
+		allowLocalhostURLs: allowLocalhostURLs,
 	}
 }
 
@@ -61,8 +72,26 @@ func (h *AdminOAuthHandler) Update(w http.ResponseWriter, r *http.Request) {
 	authURL := r.FormValue("auth_url")
 	tokenURL := r.FormValue("token_url")
 	userInfoURL := r.FormValue("user_info_url")
+	endSessionURL := strings.TrimSpace(r.FormValue("end_session_url"))
 	enabled := r.FormValue("enabled") == "on"
 
+	// Blank endpoints and scopes fall back to the provider's standard defaults, so a known
+	// provider can be enabled with just its client credentials.
+	if preset, ok := domain.OAuthProviderPresets[domain.OAuthProviderType(providerName)]; ok {
+		if strings.TrimSpace(authURL) == "" {
+			authURL = preset.AuthURL
+		}
+		if strings.TrimSpace(tokenURL) == "" {
+			tokenURL = preset.TokenURL
+		}
+		if strings.TrimSpace(userInfoURL) == "" {
+			userInfoURL = preset.UserInfoURL
+		}
+		if strings.TrimSpace(scopesStr) == "" {
+			scopesStr = strings.Join(preset.Scopes, ",")
+		}
+	}
+
 	// Prevent disabling the last active OAuth provider if OAuth is the only auth method enabled
 	if !enabled {
 		// 1. Check if OAuth feature is enabled
@@ -106,15 +135,42 @@ func (h *AdminOAuthHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate endpoints before touching the stored config; on failure re-render the card unchanged
+	candidate := &domain.OAuthProvider{
+		Enabled:       enabled,
+		AuthURL:       authURL,
+		TokenURL:      tokenURL,
+		UserInfoURL:   userInfoURL,
+		EndSessionURL: endSessionURL,
+	}
+	err = candidate.ValidateEndpoints(h.allowLocalhostURLs)
+	if err == nil && enabled {
+		err = existing.ValidateCallbackURL(h.appURL)
+	}
+	if err != nil {
+		msg := err.Error()
+		if vErr, ok := err.(domain.ErrValidation); ok {
+			msg = vErr.Message
+		}
+		trigger, _ := json.Marshal(map[string]string{"error-toast": msg})
+		w.Header().Set("HX-Trigger", string(trigger))
+		h.RenderTempl(w, r, adminPage.OAuthProviderCard(existing, h.appURL))
+		return
+	}
+
+	before := *existing
+	secretChanged := clientSecret != "" && clientSecret != before.ClientSecret
+
 	// Update fields
 	existing.ClientID = clientID
-	if clientSecret != "" {
-		existing.ClientSecret = clientSecret
-	}
+	// A blank secret leaves the stored one untouched (UpdateProvider skips it), rather than
+	// round-tripping the decrypted value back through encryption.
+	existing.ClientSecret = clientSecret
 	existing.Enabled = enabled
 	existing.AuthURL = authURL
 	existing.TokenURL = tokenURL
 	existing.UserInfoURL = userInfoURL
+	existing.EndSessionURL = endSessionURL
 
 	// Split scopes
 	var scopes []string
@@ -132,14 +188,79 @@ func (h *AdminOAuthHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log audit
+	oldValues, newValues, sensitive := oauthProviderChanges(&before, existing, secretChanged)
 	if admin := middleware.GetUserFromContext(r.Context()); admin != nil {
 		ip := getIPAddress(r)
-		_ = h.auditService.LogAudit(r.Context(), admin.ID, "update_oauth_provider", "oauth_provider", nil, nil, map[string]interface{}{
-			"provider": providerName,
-			"enabled":  enabled,
-		}, &ip)
+		_ = h.auditService.LogAudit(r.Context(), admin.ID, domain.AuditOAuthProviderUpdate, "oauth_provider", nil, oldValues, newValues, &ip)
+		if len(sensitive) > 0 {
+			h.alertSuperAdmins(providerName, admin.Name, sensitive)
+		}
 	}
 
 	// Render the updated card
 	h.RenderTempl(w, r, adminPage.OAuthProviderCard(existing, h.appURL))
 }
+
+// oauthProviderChanges diffs a provider's settings for the audit log, recording only the
+// fields that changed, plus the provider name. The client secret is never recorded, only
+// whether it changed. It also returns labels for the changed credentials and endpoints,
+// the changes worth alerting super admins about.
+func oauthProviderChanges(before, after *domain.OAuthProvider, secretChanged bool) (oldValues, newValues map[string]interface{}, sensitive []string) {
+	oldValues = map[string]interface{}{"provider": string(before.Provider)}
+	newValues = map[string]interface{}{"provider": string(after.Provider)}
+
+	fields := []struct {
+		key, label  string
+		old, new    string
+		isSensitive bool
+	}{
+		{"client_id", "Client ID", before.ClientID, after.ClientID, true},
+		{"scopes", "Scopes", strings.Join(before.Scopes, ","), strings.Join(after.Scopes, ","), false},
+		{"auth_url", "Authorization URL", before.AuthURL, after.AuthURL, true},
+		{"token_url", "Token URL", before.TokenURL, after.TokenURL, true},
+		{"user_info_url", "User info URL", before.UserInfoURL, after.UserInfoURL, true},
+		{"end_session_url", "End session URL", before.EndSessionURL, after.EndSessionURL, false},
+	}
+	for _, f := range fields {
+		if f.old == f.new {
+			continue
+		}
+		oldValues[f.key] = f.old
+		newValues[f.key] = f.new
+		if f.isSensitive {
+			sensitive = append(sensitive, f.label)
+		}
+	}
+
+	if before.Enabled != after.Enabled {
+		oldValues["enabled"] = before.Enabled
+		newValues["enabled"] = after.Enabled
+	}
+
+	newValues["client_secret"] = "unchanged"
+	if secretChanged {
+		newValues["client_secret"] = "changed"
+		sensitive = append(sensitive, "Client secret")
+	}
+	return oldValues, newValues, sensitive
+}
+
+// alertSuperAdmins emails every super admin about a change to a provider's credentials or
+// endpoints, in the background so the admin's request isn't held up.
+func (h *AdminOAuthHandler) alertSuperAdmins(provider, changedBy string, fields []string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		admins, err := h.userService.ListActiveByRole(ctx, domain.RoleSuperAdmin)
+		if err != nil {
+			log.Printf("Failed to list super admins for OAuth change alert: %v", err)
+			return
+		}
+		for _, admin := range admins {
+			if err := h.emailService.SendOAuthChangeAlert(ctx, admin.Email, admin.Name, provider, changedBy, fields); err != nil {
+				log.Printf("Failed to send OAuth change alert to %s: %v", admin.Email, err)
+			}
+		}
+	}()
+}
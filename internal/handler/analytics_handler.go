@@ -8,20 +8,23 @@ import (
 
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
 	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
 	"github.com/noruj-official/full-stack-go-template/web/templ/pages/admin"
 )
 
 // AnalyticsHandler handles analytics-related HTTP requests.
 type AnalyticsHandler struct {
 	*Handler
-	db *postgres.DB
+	db           *postgres.DB
+	auditService service.AuditService
 }
 
 // NewAnalyticsHandler creates a new analytics handler.
-func NewAnalyticsHandler(base *Handler, db *postgres.DB) *AnalyticsHandler {
+func NewAnalyticsHandler(base *Handler, db *postgres.DB, auditService service.AuditService) *AnalyticsHandler {
 	return &AnalyticsHandler{
-		Handler: base,
-		db:      db,
+		Handler:      base,
+		db:           db,
+		auditService: auditService,
 	}
 }
 
@@ -64,23 +67,37 @@ func (h *AnalyticsHandler) AdminAnalytics(w http.ResponseWriter, r *http.Request
 	}
 
 	// Get user growth data (last 7 days)
-	growthData := make([]admin.GrowthMetric, 7)
-	for i := 6; i >= 0; i-- {
-		date := time.Now().AddDate(0, 0, -i)
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := startOfDay.Add(24 * time.Hour)
+	userCreated := make([]time.Time, 0, len(allUsers))
+	for _, user := range allUsers {
+		userCreated = append(userCreated, user.CreatedAt)
+	}
+	growthData := dailyGrowth(userCreated, 7)
 
-		count := 0
-		for _, user := range allUsers {
-			if user.CreatedAt.After(startOfDay) && user.CreatedAt.Before(endOfDay) {
-				count++
-			}
-		}
+	// Get blog statistics
+	blogRepo := postgres.NewBlogRepository(h.db)
 
-		growthData[6-i] = admin.GrowthMetric{
-			Date:  startOfDay.Format("Mon"),
-			Count: count,
-		}
+	publishedPosts, err := blogRepo.CountPublished(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load blog statistics")
+		return
+	}
+
+	draftPosts, err := blogRepo.CountDrafts(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load blog statistics")
+		return
+	}
+
+	postsCreated, err := blogRepo.ListCreatedSince(r.Context(), time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load blog statistics")
+		return
+	}
+
+	mostViewed, err := blogRepo.ListMostViewed(r.Context(), 5)
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load blog statistics")
+		return
 	}
 
 	theme, themeEnabled := h.GetTheme(r)
@@ -92,14 +109,45 @@ func (h *AnalyticsHandler) AdminAnalytics(w http.ResponseWriter, r *http.Request
 		RecentUsers:   recentCount,
 		UserRoleStats: roleStats,
 		GrowthData:    growthData,
-		Theme:         theme,
-		ThemeEnabled:  themeEnabled,
-		OAuthEnabled:  oauthEnabled,
+
+		TotalPosts:     publishedPosts + draftPosts,
+		PublishedPosts: publishedPosts,
+		DraftPosts:     draftPosts,
+		PostGrowthData: dailyGrowth(postsCreated, 7),
+		MostViewed:     mostViewed,
+
+		Theme:        theme,
+		ThemeEnabled: themeEnabled,
+		OAuthEnabled: oauthEnabled,
 	}
 
 	admin.AdminAnalytics(props).Render(r.Context(), w)
 }
 
+// dailyGrowth buckets creation times into one GrowthMetric per day for the last
+// days days, oldest first, using local day boundaries.
+func dailyGrowth(created []time.Time, days int) []admin.GrowthMetric {
+	growth := make([]admin.GrowthMetric, days)
+	for i := days - 1; i >= 0; i-- {
+		date := time.Now().AddDate(0, 0, -i)
+		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+		endOfDay := startOfDay.Add(24 * time.Hour)
+
+		count := 0
+		for _, t := range created {
+			if t.After(startOfDay) && t.Before(endOfDay) {
+				count++
+			}
+		}
+
+		growth[days-1-i] = admin.GrowthMetric{
+			Date:  startOfDay.Format("Mon"),
+			Count: count,
+		}
+	}
+	return growth
+}
+
 // SystemActivity renders the system-wide activity feed.
 func (h *AnalyticsHandler) SystemActivity(w http.ResponseWriter, r *http.Request) {
 	// Parse offset parameter for pagination
@@ -113,6 +161,8 @@ func (h *AnalyticsHandler) SystemActivity(w http.ResponseWriter, r *http.Request
 
 	const limit = 10 // TODO: Change back to 50 for production
 
+	logView(r, h.auditService, "activity_log", nil, map[string]interface{}{"offset": offset})
+
 	// Get activities from all users with pagination
 	query := `
 		SELECT a.id, a.user_id, u.name as user_name, a.activity_type, a.description, 
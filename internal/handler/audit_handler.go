@@ -3,17 +3,21 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"runtime"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
 
+	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/config"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
 	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
 	"github.com/noruj-official/full-stack-go-template/internal/service"
@@ -27,22 +31,67 @@ type SystemStats struct {
 	RAMTotal       float64
 	RAMUsedPercent float64
 	mu             sync.RWMutex
+
+	// Alerts holds the most recent threshold breaches, newest first.
+	Alerts []admin.SystemAlert
+	// goroutineAlerting and ramAlerting track an ongoing breach so it is recorded once, not every tick.
+	goroutineAlerting bool
+	ramAlerting       bool
+}
+
+// maxSystemAlerts caps how many alerts are kept in memory.
+const maxSystemAlerts = 20
+
+// recordAlert adds an alert, dropping the oldest beyond maxSystemAlerts. The caller must hold mu.
+func (s *SystemStats) recordAlert(kind, message string) {
+	log.Printf("System alert (%s): %s", kind, message)
+	s.Alerts = append([]admin.SystemAlert{{Kind: kind, Message: message, Time: time.Now()}}, s.Alerts...)
+	if len(s.Alerts) > maxSystemAlerts {
+		s.Alerts = s.Alerts[:maxSystemAlerts]
+	}
+}
+
+// checkThresholds records an alert when goroutines or RAM usage first rise above their limits.
+// The caller must hold mu.
+func (s *SystemStats) checkThresholds(goroutines, maxGoroutines int, ramPercent, maxRAMPercent float64) {
+	if maxGoroutines > 0 {
+		over := goroutines > maxGoroutines
+		if over && !s.goroutineAlerting {
+			s.recordAlert("goroutines", fmt.Sprintf("Goroutine count %d exceeds threshold %d (possible leak)", goroutines, maxGoroutines))
+		}
+		s.goroutineAlerting = over
+	}
+
+	if maxRAMPercent > 0 {
+		over := ramPercent > maxRAMPercent
+		if over && !s.ramAlerting {
+			s.recordAlert("memory", fmt.Sprintf("RAM usage %.1f%% exceeds threshold %.1f%%", ramPercent, maxRAMPercent))
+		}
+		s.ramAlerting = over
+	}
 }
 
 // AuditHandler handles audit log HTTP requests.
 type AuditHandler struct {
 	*Handler
 	auditService service.AuditService
+	emailService service.EmailService
 	db           *postgres.DB
 	cfg          *config.Config
 	stats        *SystemStats
+
+	// pgVersion caches the server's version once a query for it succeeds; it cannot
+	// change while the process runs. It is empty until then.
+	pgVersionMu sync.Mutex
+	pgVersion   string
 }
 
 // NewAuditHandler creates a new audit handler.
-func NewAuditHandler(base *Handler, auditService service.AuditService, db *postgres.DB, cfg *config.Config) *AuditHandler {
+func NewAuditHandler(base *Handler, auditService service.AuditService, emailService service.EmailService, db *postgres.DB, cfg *config.Config) *AuditHandler {
 	return &AuditHandler{
 		Handler:      base,
 		auditService: auditService,
+		emailService: emailService,
 		db:           db,
 		cfg:          cfg,
 		stats:        &SystemStats{},
@@ -76,21 +125,30 @@ func (h *AuditHandler) StartMonitoring(ctx context.Context) {
 					h.stats.RAMTotal = float64(v.Total) / 1024 / 1024 / 1024
 					h.stats.RAMUsedPercent = v.UsedPercent
 				}
+				h.stats.checkThresholds(runtime.NumGoroutine(), h.cfg.Server.GoroutineAlertThreshold, h.stats.RAMUsedPercent, h.cfg.Server.RAMAlertPercent)
 				h.stats.mu.Unlock()
 			}
 		}
 	}()
 }
 
-// AuditLogs renders the audit logs page.
-func (h *AuditHandler) AuditLogs(w http.ResponseWriter, r *http.Request) {
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+// logView records in the audit log that the signed-in admin viewed a sensitive resource,
+// so there is a trail of who looked at what. Failures are logged, not shown.
+func logView(r *http.Request, auditService service.AuditService, resourceType string, resourceID *uuid.UUID, details map[string]interface{}) {
+	viewer := middleware.GetUserFromContext(r.Context())
+	if viewer == nil {
+		return
 	}
+	ip := getIPAddress(r)
+	if err := auditService.LogAudit(r.Context(), viewer.ID, domain.AuditView, resourceType, resourceID, nil, details, &ip); err != nil {
+		log.Printf("Failed to record %s view by %s: %v", resourceType, viewer.ID, err)
+	}
+}
 
-	limit := 20
-	offset := (page - 1) * limit
+// AuditLogs renders the audit logs page.
+func (h *AuditHandler) AuditLogs(w http.ResponseWriter, r *http.Request) {
+	limit := h.pageSize(r, 20)
+	page, offset := pageParams(r, limit)
 
 	logs, total, err := h.auditService.GetAuditLogs(r.Context(), limit, offset)
 	if err != nil {
@@ -98,6 +156,8 @@ func (h *AuditHandler) AuditLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	logView(r, h.auditService, "audit_log", nil, map[string]interface{}{"page": page})
+
 	// Format logs for display
 	formattedLogs := make([]admin.AuditLogItem, 0, len(logs))
 	for _, log := range logs {
@@ -116,17 +176,13 @@ func (h *AuditHandler) AuditLogs(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	totalPages := (total + limit - 1) / limit
-
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
 
 	props := admin.AuditLogsProps{
-		User:        middleware.GetUserFromContext(r.Context()),
-		Logs:        formattedLogs,
-		Total:       int64(total),
-		CurrentPage: page,
-		TotalPages:  totalPages,
+		User:       middleware.GetUserFromContext(r.Context()),
+		Logs:       formattedLogs,
+		Pagination: newPagination(r, page, limit, total),
 
 		Theme:        theme,
 		ThemeEnabled: themeEnabled,
@@ -136,6 +192,37 @@ func (h *AuditHandler) AuditLogs(w http.ResponseWriter, r *http.Request) {
 	admin.AuditLogs(props).Render(r.Context(), w)
 }
 
+// AuditLogJSON returns one audit log entry as JSON, with its full old and new values and
+// the admin's details, for investigations. The path is /s/audit/{entry} where entry is
+// "<id>.json", since a mux wildcard can't share a segment with a suffix.
+func (h *AuditHandler) AuditLogJSON(w http.ResponseWriter, r *http.Request) {
+	idStr, ok := strings.CutSuffix(r.PathValue("entry"), ".json")
+	if !ok {
+		h.Error(w, r, http.StatusNotFound, "Not found")
+		return
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.Error(w, r, http.StatusNotFound, "Audit log entry not found")
+		return
+	}
+
+	entry, err := h.auditService.GetAuditLog(r.Context(), id)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			h.Error(w, r, http.StatusNotFound, "Audit log entry not found")
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load audit log entry")
+		return
+	}
+
+	logView(r, h.auditService, "audit_log", &entry.ID, nil)
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.json"`, entry.ID))
+	h.JSON(w, http.StatusOK, entry)
+}
+
 // SystemMetricsJSON returns system metrics as JSON for client-side polling
 func (h *AuditHandler) SystemMetricsJSON(w http.ResponseWriter, r *http.Request) {
 	h.stats.mu.RLock()
@@ -164,10 +251,84 @@ func (h *AuditHandler) SystemHealth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Chart refreshes re-request the page; only the full page counts as a view
+	if !isHTMXRequest(r) {
+		logView(r, h.auditService, "system_health", nil, nil)
+	}
+
+	props := h.collectSystemHealth(r.Context())
+	props.User = middleware.GetUserFromContext(r.Context())
+	props.Theme, props.ThemeEnabled = h.GetTheme(r)
+	props.OAuthEnabled = h.GetOAuthEnabled(r)
+
+	if isHTMXRequest(r) {
+		if r.Header.Get("HX-Target") == "system-charts-container" {
+			admin.SystemResources(props).Render(r.Context(), w)
+			return
+		}
+	}
+
+	admin.SystemHealth(props).Render(r.Context(), w)
+}
+
+// SystemInfoJSON returns the system health data shown on the system page as JSON, for monitoring tools.
+func (h *AuditHandler) SystemInfoJSON(w http.ResponseWriter, r *http.Request) {
+	h.JSON(w, http.StatusOK, h.collectSystemHealth(r.Context()))
+}
+
+// SendTestEmail sends a test email to the signed-in super admin's own address and reports
+// the result as a toast, so email delivery can be checked without waiting for a real one.
+func (h *AuditHandler) SendTestEmail(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	toast := map[string]string{"success-toast": "Test email sent to " + user.Email}
+	if h.cfg.Email.ResendAPIKey == "" {
+		toast = map[string]string{"error-toast": "RESEND_API_KEY is not set, so emails are only printed to the server log"}
+	} else if err := h.emailService.SendTestEmail(r.Context(), user.Email, user.Name); err != nil {
+		log.Printf("Failed to send test email to %s: %v", user.Email, err)
+		toast = map[string]string{"error-toast": "Test email failed: " + err.Error()}
+	}
+
+	trigger, _ := json.Marshal(toast)
+	w.Header().Set("HX-Trigger", string(trigger))
+	w.WriteHeader(http.StatusOK)
+}
+
+// pgVersionTimeout bounds the version query, which runs detached from the request.
+const pgVersionTimeout = 5 * time.Second
+
+// postgresVersion returns the server's version string. It is queried until a query
+// succeeds and cached from then on, so a database that was down at first use is still
+// reported once it is back. The query doesn't use the request's context, whose
+// cancellation would otherwise fail it. The lock only guards the cache, so a slow or
+// unreachable database doesn't queue every health page behind one query; concurrent
+// first requests may each query, which is harmless.
+func (h *AuditHandler) postgresVersion() string {
+	h.pgVersionMu.Lock()
+	version := h.pgVersion
+	h.pgVersionMu.Unlock()
+	if version != "" {
+		return version
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pgVersionTimeout)
+	defer cancel()
+	if err := h.db.Pool.QueryRow(ctx, "SELECT version()").Scan(&version); err != nil || version == "" {
+		return "Unknown"
+	}
+
+	h.pgVersionMu.Lock()
+	h.pgVersion = version
+	h.pgVersionMu.Unlock()
+	return version
+}
+
+// collectSystemHealth gathers database, runtime and server details for the system health views.
+func (h *AuditHandler) collectSystemHealth(ctx context.Context) admin.SystemHealthProps {
 	// Check database health
 	dbStatus := "Connected"
 	dbError := ""
-	if err := h.db.Pool.Ping(r.Context()); err != nil {
+	if err := h.db.Pool.Ping(ctx); err != nil {
 		dbStatus = "Error"
 		dbError = err.Error()
 	}
@@ -175,12 +336,7 @@ func (h *AuditHandler) SystemHealth(w http.ResponseWriter, r *http.Request) {
 	// Get database pool stats
 	poolStats := h.db.Pool.Stat()
 
-	// Get real Postgres version
-	var pgVersion string
-	err := h.db.Pool.QueryRow(r.Context(), "SELECT version()").Scan(&pgVersion)
-	if err != nil {
-		pgVersion = "Unknown"
-	}
+	pgVersion := h.postgresVersion()
 
 	// Get system usage stats from cache or fallback
 	h.stats.mu.RLock()
@@ -188,6 +344,7 @@ func (h *AuditHandler) SystemHealth(w http.ResponseWriter, r *http.Request) {
 	ramUsage := h.stats.RAMUsage
 	ramTotal := h.stats.RAMTotal
 	ramPercent := h.stats.RAMUsedPercent
+	alerts := append([]admin.SystemAlert(nil), h.stats.Alerts...)
 	h.stats.mu.RUnlock()
 
 	// If stats are empty (start up), fetch once synchronously (calls might block but better than showing 0)
@@ -204,11 +361,7 @@ func (h *AuditHandler) SystemHealth(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	theme, themeEnabled := h.GetTheme(r)
-	oauthEnabled := h.GetOAuthEnabled(r)
-
-	props := admin.SystemHealthProps{
-		User: middleware.GetUserFromContext(r.Context()),
+	return admin.SystemHealthProps{
 		Database: admin.DatabaseHealth{
 			Status:         dbStatus,
 			Error:          dbError,
@@ -235,18 +388,7 @@ func (h *AuditHandler) SystemHealth(w http.ResponseWriter, r *http.Request) {
 			WriteTimeout: h.cfg.Server.WriteTimeout,
 			IdleTimeout:  h.cfg.Server.IdleTimeout,
 		},
-
-		Theme:        theme,
-		ThemeEnabled: themeEnabled,
-		OAuthEnabled: oauthEnabled,
-	}
-
-	if isHTMXRequest(r) {
-		if r.Header.Get("HX-Target") == "system-charts-container" {
-			admin.SystemResources(props).Render(r.Context(), w)
-			return
-		}
+		Alerts: alerts,
+		Routes: middleware.RouteMetricsSnapshot(),
 	}
-
-	admin.SystemHealth(props).Render(r.Context(), w)
 }
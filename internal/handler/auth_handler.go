@@ -3,15 +3,24 @@ package handler
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/captcha"
 	"github.com/noruj-official/full-stack-go-template/internal/service"
 	"github.com/noruj-official/full-stack-go-template/web/templ/pages/auth"
+	"github.com/noruj-official/full-stack-go-template/web/templ/pages/profile"
 )
 
 // AuthHandler handles authentication-related HTTP requests.
@@ -20,15 +29,30 @@ type AuthHandler struct {
 	authService     service.AuthService
 	userService     service.UserService
 	activityService service.ActivityService
+	// resetLockout counts invalid password reset tokens per IP and locks the IP out once its bucket is empty
+	resetLockout *middleware.IPRateLimiter
+	// emailLimiter caps sign-in link and password reset emails per recipient address
+	emailLimiter *middleware.KeyedRateLimiter
+	// verificationLimiter caps resent verification emails per recipient address
+	verificationLimiter *middleware.KeyedRateLimiter
+	// loginFriction counts failed sign-ins per IP and slows that IP down once it has too many
+	loginFriction *middleware.LoginFriction
+	// captcha, if configured, is required instead of a delay while an IP has sign-in friction
+	captcha *captcha.Verifier
 }
 
 // NewAuthHandler creates a new auth handler.
-func NewAuthHandler(base *Handler, authService service.AuthService, userService service.UserService, activityService service.ActivityService) *AuthHandler {
+func NewAuthHandler(base *Handler, authService service.AuthService, userService service.UserService, activityService service.ActivityService, resetLockout *middleware.IPRateLimiter, emailLimiter, verificationLimiter *middleware.KeyedRateLimiter, loginFriction *middleware.LoginFriction, captchaVerifier *captcha.Verifier) *AuthHandler {
 	return &AuthHandler{
-		Handler:         base,
-		authService:     authService,
-		userService:     userService,
-		activityService: activityService,
+		Handler:             base,
+		authService:         authService,
+		userService:         userService,
+		activityService:     activityService,
+		resetLockout:        resetLockout,
+		emailLimiter:        emailLimiter,
+		verificationLimiter: verificationLimiter,
+		loginFriction:       loginFriction,
+		captcha:             captchaVerifier,
 	}
 }
 
@@ -60,9 +84,27 @@ func (h *AuthHandler) SignInPage(w http.ResponseWriter, r *http.Request) {
 	msgType := ""
 	msg := ""
 
+	resendVerification := false
 	if r.URL.Query().Get("error") == "unverified" {
 		msgType = "info"
 		msg = "Email not verified. A new verification link has been sent to " + email
+		// The sign-in identifier may be a username, which the resend endpoint doesn't take
+		resendVerification = strings.Contains(email, "@")
+	}
+
+	if r.URL.Query().Get("success") == "verification_resent" {
+		msgType = "success"
+		msg = "If that account still needs verifying, a new link is on its way to " + email
+	}
+
+	if r.URL.Query().Get("error") == "oauth_state" {
+		msgType = "error"
+		msg = "Your sign-in link expired or was not started from this browser. Please try again."
+	}
+
+	if r.URL.Query().Get("error") == "two_factor_expired" {
+		msgType = "error"
+		msg = "Your sign-in timed out before the authentication code was entered. Please sign in again."
 	}
 
 	if r.URL.Query().Get("error") == "account_suspended" {
@@ -106,6 +148,8 @@ func (h *AuthHandler) SignInPage(w http.ResponseWriter, r *http.Request) {
 		EmailAuthEnabled:         emailAuthEnabled,
 		EmailPasswordAuthEnabled: emailPasswordAuthEnabled,
 		OAuthEnabled:             oauthEnabled,
+		ResendVerification:       resendVerification,
+		Captcha:                  h.signInCaptcha(r),
 	}
 	auth.SigninPage(props).Render(r.Context(), w)
 }
@@ -125,19 +169,23 @@ func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	input := &domain.LoginInput{
-		Email:    r.FormValue("email"),
-		Password: r.FormValue("password"),
+		Identifier: r.FormValue("identifier"),
+		Password:   r.FormValue("password"),
 	}
 
 	ip := getIPAddress(r)
 	ua := r.UserAgent()
 
+	if !h.passLoginFriction(w, r, ip, input.Identifier) {
+		return
+	}
+
 	user, session, err := h.authService.Login(r.Context(), input, ip, ua)
 	// Check for email verification error
 	if err == domain.ErrEmailNotVerified {
 		// Redirect back to sign in with error and email
 		// We use a query param 'error=unverified' to trigger the specific message
-		redirectURL := "/signin?error=unverified&email=" + input.Email
+		redirectURL := "/signin?error=unverified&email=" + url.QueryEscape(input.Identifier)
 
 		if isHTMXRequest(r) {
 			w.Header().Set("HX-Redirect", redirectURL)
@@ -149,30 +197,27 @@ func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.redirectToTwoFactor(w, r, err) {
+		return
+	}
+
 	if err != nil {
 		errMsg := "An error occurred"
 		if domain.IsValidationError(err) {
 			errMsg = err.Error()
 		} else if domain.IsInvalidCredentialsError(err) {
-			errMsg = "Invalid email or password"
+			errMsg = "Invalid email, username or password"
+			h.loginFriction.RecordFailure(r.Context(), ip)
 		} else {
 			// Log the actual error for debugging
-			log.Printf("Login error for user %s: %v", input.Email, err)
+			log.Printf("Login error for user %s: %v", input.Identifier, err)
 		}
-		h.renderSignInError(w, r, input.Email, errMsg)
+		h.renderSignInError(w, r, input.Identifier, errMsg)
 		return
 	}
 
 	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     middleware.SessionCookieName,
-		Value:    session.ID,
-		Path:     "/",
-		Expires:  session.ExpiresAt,
-		HttpOnly: true,
-		Secure:   r.TLS != nil,
-		SameSite: http.SameSiteLaxMode,
-	})
+	middleware.SetSessionCookie(w, r, session)
 
 	// Log login activity
 	// ip and ua already captured above
@@ -190,6 +235,49 @@ func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
+// passLoginFriction holds back sign-in attempts from an IP with too many recent failures.
+// With a CAPTCHA configured the attempt must carry a solved one; otherwise, or if the
+// provider can't be reached, the response is delayed. It reports whether to go on with
+// the attempt, having written a response if not.
+func (h *AuthHandler) passLoginFriction(w http.ResponseWriter, r *http.Request, ip, identifier string) bool {
+	required, delay := h.loginFriction.Check(r.Context(), ip)
+	if !required {
+		return true
+	}
+
+	if h.captcha != nil {
+		ok, err := h.captcha.Verify(r.Context(), r, ip)
+		if err == nil {
+			if !ok {
+				h.renderSignInError(w, r, identifier, "Please complete the security check to sign in")
+			}
+			return ok
+		}
+		log.Printf("CAPTCHA unavailable, delaying sign-in instead: %v", err)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// signInCaptcha returns the CAPTCHA to show on the sign-in form, or nil when the
+// requesting IP doesn't need one.
+func (h *AuthHandler) signInCaptcha(r *http.Request) *captcha.Verifier {
+	if h.captcha == nil {
+		return nil
+	}
+	if required, _ := h.loginFriction.Check(r.Context(), getIPAddress(r)); !required {
+		return nil
+	}
+	return h.captcha
+}
+
 func (h *AuthHandler) renderSignInError(w http.ResponseWriter, r *http.Request, email, errMsg string) {
 	theme, themeEnabled := h.GetTheme(r)
 
@@ -212,6 +300,7 @@ func (h *AuthHandler) renderSignInError(w http.ResponseWriter, r *http.Request,
 		EmailAuthEnabled:         emailAuthEnabled,
 		EmailPasswordAuthEnabled: emailPasswordAuthEnabled,
 		OAuthEnabled:             nil,
+		Captcha:                  h.signInCaptcha(r),
 	}
 
 	// Fetch oauth providers even in error for consistent UI
@@ -251,6 +340,7 @@ func (h *AuthHandler) SignupPage(w http.ResponseWriter, r *http.Request) {
 	props := auth.SignupPageProps{
 		Form:                     nil,
 		Error:                    "",
+		PasswordPolicy:           h.authService.PasswordPolicy(r.Context()),
 		Theme:                    theme,
 		ThemeEnabled:             themeEnabled,
 		EmailAuthEnabled:         emailAuthEnabled,
@@ -275,7 +365,7 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := r.ParseForm(); err != nil {
-		h.renderSignupError(w, r, nil, "Invalid form data")
+		h.renderSignupError(w, r, nil, "Invalid form data", nil)
 		return
 	}
 
@@ -298,7 +388,7 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		} else if domain.IsConflictError(err) {
 			errMsg = "An account with this email already exists"
 		}
-		h.renderSignupError(w, r, input, errMsg)
+		h.renderSignupError(w, r, input, errMsg, err)
 		return
 	}
 
@@ -320,12 +410,16 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
-func (h *AuthHandler) renderSignupError(w http.ResponseWriter, r *http.Request, input *domain.RegisterInput, errMsg string) {
+// renderSignupError re-renders the signup form with an error. If err is a password
+// policy error, the rules the password failed are highlighted.
+func (h *AuthHandler) renderSignupError(w http.ResponseWriter, r *http.Request, input *domain.RegisterInput, errMsg string, err error) {
 	theme, themeEnabled := h.GetTheme(r)
 	props := auth.SignupPageProps{
-		Form:  input,
-		Error: errMsg,
-		Theme: theme,
+		Form:                input,
+		Error:               errMsg,
+		PasswordPolicy:      h.authService.PasswordPolicy(r.Context()),
+		FailedPasswordRules: failedPasswordRules(err),
+		Theme:               theme,
 
 		ThemeEnabled:     themeEnabled,
 		EmailAuthEnabled: true,
@@ -343,24 +437,41 @@ func (h *AuthHandler) renderSignupError(w http.ResponseWriter, r *http.Request,
 	auth.SignupPage(props).Render(r.Context(), w)
 }
 
+// failedPasswordRules returns the IDs of the password rules err reports as failed,
+// or nil if err isn't a password policy error.
+func failedPasswordRules(err error) []string {
+	var policyErr *domain.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.ViolationIDs()
+	}
+	return nil
+}
+
 // Logout handles user logout.
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// Sessions from providers that support RP-initiated logout go through the provider's
+	// end session endpoint, which sends the user back to the sign-in page.
+	redirectURL := "/signin"
+	if sessionID := middleware.SessionCookieValue(r); sessionID != "" {
+		endSessionURL, err := h.authService.Logout(r.Context(), sessionID)
+		if err != nil {
+			log.Printf("Failed to delete session on logout: %v", err)
+		}
+		if endSessionURL != "" {
+			redirectURL = endSessionURL
+		}
+	}
+
 	// Clear session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     middleware.SessionCookieName,
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
+	middleware.ClearSessionCookie(w, r)
 
 	if isHTMXRequest(r) {
-		w.Header().Set("HX-Redirect", "/signin")
+		w.Header().Set("HX-Redirect", redirectURL)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	http.Redirect(w, r, "/signin", http.StatusSeeOther)
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
 // SignOutAllDevices handles invalidating all user sessions.
@@ -382,13 +493,7 @@ func (h *AuthHandler) SignOutAllDevices(w http.ResponseWriter, r *http.Request)
 	_ = h.activityService.LogActivity(r.Context(), user.ID, domain.ActivityLogout, "User signed out all devices", &ip, &ua)
 
 	// Clear session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     middleware.SessionCookieName,
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
+	middleware.ClearSessionCookie(w, r)
 
 	if isHTMXRequest(r) {
 		w.Header().Set("HX-Redirect", "/signin?success=signed_out_all")
@@ -399,6 +504,131 @@ func (h *AuthHandler) SignOutAllDevices(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/signin?success=signed_out_all", http.StatusSeeOther)
 }
 
+// Sessions renders the list of the current user's signed-in sessions.
+func (h *AuthHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/signin", http.StatusSeeOther)
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load sessions")
+		return
+	}
+
+	currentID := middleware.GetSessionIDFromContext(r.Context())
+	views := make([]profile.SessionViewModel, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, profile.SessionViewModel{
+			ID:         session.PublicID(),
+			Device:     describeUserAgent(session.UserAgent),
+			IPAddress:  session.IPAddress,
+			Provider:   string(session.AuthProvider),
+			LastActive: formatTimeAgo(session.LastActivityAt),
+			SignedIn:   session.CreatedAt.Format("Jan 02, 2006 at 3:04 PM"),
+			Current:    session.ID == currentID,
+		})
+	}
+
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, profile.UserSessions("Active Sessions", views, user, theme, themeEnabled, oauthEnabled))
+}
+
+// RevokeSession handles POST /u/sessions/{id}/revoke, signing out one of the current user's
+// sessions. Revoking the session making the request signs the user out here too.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/signin", http.StatusSeeOther)
+		return
+	}
+
+	publicID := r.PathValue("id")
+	if err := h.authService.RevokeSession(r.Context(), user.ID, publicID); err != nil {
+		if domain.IsNotFoundError(err) {
+			h.Error(w, r, http.StatusNotFound, "Session not found")
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+
+	current := (&domain.Session{ID: middleware.GetSessionIDFromContext(r.Context())}).PublicID() == publicID
+
+	ip := getIPAddress(r)
+	ua := r.UserAgent()
+	desc := "User signed out a session on another device"
+	if current {
+		desc = "User logged out"
+	}
+	_ = h.activityService.LogActivity(r.Context(), user.ID, domain.ActivityLogout, desc, &ip, &ua)
+
+	if current {
+		middleware.ClearSessionCookie(w, r)
+		if isHTMXRequest(r) {
+			w.Header().Set("HX-Redirect", "/signin")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/signin", http.StatusSeeOther)
+		return
+	}
+
+	if isHTMXRequest(r) {
+		// An empty body removes the session's row from the list
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, "/u/sessions", http.StatusSeeOther)
+}
+
+// describeUserAgent summarises a User-Agent header as "Browser on OS" for the sessions list.
+func describeUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			platform = o.name
+			break
+		}
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
+
 // ForgotPasswordPage renders the forgot password page.
 func (h *AuthHandler) ForgotPasswordPage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -414,7 +644,10 @@ func (h *AuthHandler) ForgotPasswordPage(w http.ResponseWriter, r *http.Request)
 func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	emailStr := r.FormValue("email")
 
-	if err := h.authService.RequestPasswordReset(r.Context(), emailStr); err != nil {
+	// A throttled address gets the same response, so the limit reveals nothing about the account
+	if !h.emailLimiter.Allow(r) {
+		log.Printf("Password reset request throttled for %s", domain.NormalizeEmail(emailStr))
+	} else if err := h.authService.RequestPasswordReset(r.Context(), emailStr); err != nil {
 		// Log error but don't reveal failure to user (security best practice)
 		log.Printf("Password reset request failed: %v", err)
 	}
@@ -428,6 +661,27 @@ func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	h.RenderTempl(w, r, auth.ForgotPasswordSuccess(theme, themeEnabled))
 }
 
+// ResendVerification sends a new verification link to an unverified account. Every
+// request gets the same response, whether or not the address has an account, is already
+// verified or is being throttled, so it can't be used to probe for accounts.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	email := domain.NormalizeEmail(r.FormValue("email"))
+
+	if email != "" {
+		if !h.verificationLimiter.Allow(r) {
+			log.Printf("Verification email resend throttled for %s", email)
+		} else if err := h.authService.ResendVerificationEmail(r.Context(), email); err != nil {
+			log.Printf("Verification email resend failed: %v", err)
+		}
+	}
+
+	if isHTMXRequest(r) {
+		h.RenderTempl(w, r, auth.ResendVerificationSent())
+		return
+	}
+	http.Redirect(w, r, "/signin?success=verification_resent&email="+url.QueryEscape(email), http.StatusSeeOther)
+}
+
 // ResetPasswordPage renders the reset password page.
 func (h *AuthHandler) ResetPasswordPage(w http.ResponseWriter, r *http.Request) {
 	token := r.URL.Query().Get("token")
@@ -437,7 +691,31 @@ func (h *AuthHandler) ResetPasswordPage(w http.ResponseWriter, r *http.Request)
 	}
 
 	theme, themeEnabled := h.GetTheme(r)
-	h.RenderTempl(w, r, auth.ResetPassword(token, theme, themeEnabled))
+	h.RenderTempl(w, r, auth.ResetPassword(auth.ResetPasswordProps{
+		Token:          token,
+		PasswordPolicy: h.authService.PasswordPolicy(r.Context()),
+		Theme:          theme,
+		ThemeEnabled:   themeEnabled,
+	}))
+}
+
+// renderResetPasswordError re-renders the reset form with an error, highlighting the
+// password rules the submitted password failed.
+func (h *AuthHandler) renderResetPasswordError(w http.ResponseWriter, r *http.Request, token, errMsg string, err error) {
+	theme, themeEnabled := h.GetTheme(r)
+	props := auth.ResetPasswordProps{
+		Token:               token,
+		Error:               errMsg,
+		PasswordPolicy:      h.authService.PasswordPolicy(r.Context()),
+		FailedPasswordRules: failedPasswordRules(err),
+		Theme:               theme,
+		ThemeEnabled:        themeEnabled,
+	}
+	if isHTMXRequest(r) {
+		h.RenderTempl(w, r, auth.ResetPasswordForm(props))
+		return
+	}
+	h.RenderTempl(w, r, auth.ResetPassword(props))
 }
 
 // ResetPassword handles the password reset.
@@ -447,12 +725,29 @@ func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	confirmPassword := r.FormValue("confirm_password")
 
 	if password != confirmPassword {
-		// In a real app, render the form again with error
-		http.Error(w, "Passwords do not match", http.StatusBadRequest)
+		h.renderResetPasswordError(w, r, token, "Passwords do not match", nil)
+		return
+	}
+
+	// Key on the host alone so opening a new connection doesn't reset the count.
+	ip := getIPAddress(r)
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if h.resetLockout.Blocked(r.Context(), ip) {
+		http.Error(w, "Too many invalid reset attempts. Please try again later.", http.StatusTooManyRequests)
 		return
 	}
 
 	if err := h.authService.ResetPassword(r.Context(), token, password); err != nil {
+		var policyErr *domain.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			h.renderResetPasswordError(w, r, token, "Please choose a password that meets every requirement", err)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidToken) {
+			h.resetLockout.Allow(r.Context(), ip)
+		}
 		http.Error(w, "Failed to reset password: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -507,6 +802,46 @@ func (h *AuthHandler) VerifyEmailPage(w http.ResponseWriter, r *http.Request) {
 	auth.VerifyEmailPage(props).Render(r.Context(), w)
 }
 
+// VerifyEmailChange applies an email change from the confirmation link sent to the new address.
+func (h *AuthHandler) VerifyEmailChange(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	var props auth.VerifyEmailPageProps
+	theme, themeEnabled := h.GetTheme(r)
+	props.Theme = theme
+	props.ThemeEnabled = themeEnabled
+
+	if token == "" {
+		props.Message = "No confirmation token provided. Please use the link from your email."
+		auth.VerifyEmailPage(props).Render(r.Context(), w)
+		return
+	}
+
+	user, err := h.userService.ConfirmEmailChange(r.Context(), token)
+	if err != nil {
+		if err == domain.ErrInvalidToken {
+			props.Message = "This confirmation link is invalid or has already been used."
+		} else if err == domain.ErrTokenExpired {
+			props.Message = "This confirmation link has expired. Please change your email again from your profile."
+		} else if domain.IsConflictError(err) {
+			props.Message = "This email address is now used by another account."
+		} else {
+			log.Printf("Failed to confirm email change: %v", err)
+			props.Message = "An error occurred during verification. Please try again later."
+		}
+		auth.VerifyEmailPage(props).Render(r.Context(), w)
+		return
+	}
+
+	ip := getIPAddress(r)
+	ua := r.UserAgent()
+	_ = h.activityService.LogActivity(r.Context(), user.ID, domain.ActivityEmailChange, "Email changed to "+user.Email, &ip, &ua)
+
+	props.Success = true
+	props.Message = "Your email address is now " + user.Email + ". Use it the next time you sign in."
+	auth.VerifyEmailPage(props).Render(r.Context(), w)
+}
+
 // HandleOAuthLogin initiates the OAuth login flow.
 func (h *AuthHandler) HandleOAuthLogin(w http.ResponseWriter, r *http.Request) {
 	provider := r.URL.Query().Get("provider")
@@ -519,18 +854,23 @@ func (h *AuthHandler) HandleOAuthLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate state (random string) to prevent CSRF
-	// In a real app, store this in session or cookie
-	state := "random_state_string" // TODO: Implement proper state handling
+	// A random state, echoed back by the provider and checked against this cookie in the
+	// callback, ties the callback to a sign-in this browser started (login CSRF protection).
+	state, err := generateOAuthState()
+	if err != nil {
+		log.Printf("Failed to generate oauth state: %v", err)
+		http.Redirect(w, r, "/signin?error=oauth_failed", http.StatusSeeOther)
+		return
+	}
 
 	url, err := h.authService.GetOAuthLoginURL(r.Context(), domain.OAuthProviderType(provider), state)
 	if err != nil {
-		fmt.Printf("DEBUG: HandleOAuthLogin failed: %v\n", err)
 		log.Printf("Failed to get oauth login url: %v", err)
 		http.Redirect(w, r, "/signin?error=oauth_failed", http.StatusSeeOther)
 		return
 	}
 
+	middleware.SetOAuthStateCookie(w, r, state)
 	http.Redirect(w, r, url, http.StatusSeeOther)
 }
 
@@ -549,17 +889,26 @@ func (h *AuthHandler) HandleOAuthCallback(w http.ResponseWriter, r *http.Request
 	code := r.URL.Query().Get("code")
 	state := r.URL.Query().Get("state")
 
+	// The state is single use, whatever the outcome
+	expectedState := middleware.OAuthStateCookieValue(r)
+	middleware.ClearOAuthStateCookie(w, r)
+	if expectedState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expectedState)) != 1 {
+		http.Redirect(w, r, "/signin?error=oauth_state", http.StatusSeeOther)
+		return
+	}
+
 	if code == "" {
 		http.Redirect(w, r, "/signin?error=oauth_failed", http.StatusSeeOther)
 		return
 	}
 
-	// Verify state...
-
 	ip := getIPAddress(r)
 	ua := r.UserAgent()
 
 	user, session, err := h.authService.LoginWithOAuth(r.Context(), domain.OAuthProviderType(provider), code, state, ip, ua)
+	if h.redirectToTwoFactor(w, r, err) {
+		return
+	}
 	if err != nil {
 		log.Printf("OAuth login failed for %s: %v", provider, err)
 		http.Redirect(w, r, "/signin?error=oauth_failed", http.StatusSeeOther)
@@ -567,15 +916,7 @@ func (h *AuthHandler) HandleOAuthCallback(w http.ResponseWriter, r *http.Request
 	}
 
 	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     middleware.SessionCookieName,
-		Value:    session.ID,
-		Path:     "/",
-		Expires:  session.ExpiresAt,
-		HttpOnly: true,
-		Secure:   r.TLS != nil,
-		SameSite: http.SameSiteLaxMode,
-	})
+	middleware.SetSessionCookie(w, r, session)
 
 	_ = h.activityService.LogActivity(r.Context(), user.ID, domain.ActivityLogin, fmt.Sprintf("User signed in with %s", provider), &ip, &ua)
 
@@ -583,6 +924,15 @@ func (h *AuthHandler) HandleOAuthCallback(w http.ResponseWriter, r *http.Request
 	http.Redirect(w, r, getDashboardURLForRole(user), http.StatusSeeOther)
 }
 
+// generateOAuthState returns a random, URL-safe OAuth state value.
+func generateOAuthState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // HandleEmailAuthRequest handles the request to sign in/up with email.
 func (h *AuthHandler) HandleEmailAuthRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -597,30 +947,36 @@ func (h *AuthHandler) HandleEmailAuthRequest(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	email := r.FormValue("email")
+	email := domain.NormalizeEmail(r.FormValue("email"))
 	if email == "" {
 		// Render error on signin page
 		h.renderSignInError(w, r, "", "Email is required")
 		return
 	}
 
-	// 1. Generate Token
-	token, err := h.authService.GenerateEmailAuthToken(email, domain.TokenPurposeEmailAuth)
-	if err != nil {
-		log.Printf("Failed to generate email auth token: %v", err)
-		h.renderSignInError(w, r, email, "An error occurred")
-		return
-	}
-
-	// 2. Send Email
-	// Use goroutine to not block response
-	go func() {
-		sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := h.authService.SendEmailAuthLink(sendCtx, email, token); err != nil {
-			log.Printf("Failed to send email auth link to %s: %v", email, err)
+	// Too many links to this address: skip the email but respond as if it was sent,
+	// so the limit can't be used to spam an inbox or to probe for accounts.
+	if h.emailLimiter.Allow(r) {
+		// 1. Generate Token
+		token, err := h.authService.GenerateEmailAuthToken(email, domain.TokenPurposeEmailAuth)
+		if err != nil {
+			log.Printf("Failed to generate email auth token: %v", err)
+			h.renderSignInError(w, r, email, "An error occurred")
+			return
 		}
-	}()
+
+		// 2. Send Email
+		// Use goroutine to not block response
+		go func() {
+			sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := h.authService.SendEmailAuthLink(sendCtx, email, token); err != nil {
+				log.Printf("Failed to send email auth link to %s: %v", email, err)
+			}
+		}()
+	} else {
+		log.Printf("Email sign-in link throttled for %s", email)
+	}
 
 	// 3. Render Success Page or Message
 	// We should probably redirect to a "Check your email" page or show a success message on the signin page.
@@ -656,6 +1012,9 @@ func (h *AuthHandler) HandleEmailAuthVerify(w http.ResponseWriter, r *http.Reque
 
 	// Verify and Login
 	user, session, err := h.authService.LoginWithEmailToken(r.Context(), token, ip, ua)
+	if h.redirectToTwoFactor(w, r, err) {
+		return
+	}
 	if err != nil {
 		log.Printf("Email auth login failed: %v", err)
 		if err == domain.ErrInvalidToken || err == domain.ErrTokenExpired {
@@ -667,15 +1026,7 @@ func (h *AuthHandler) HandleEmailAuthVerify(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     middleware.SessionCookieName,
-		Value:    session.ID,
-		Path:     "/",
-		Expires:  session.ExpiresAt,
-		HttpOnly: true,
-		Secure:   r.TLS != nil,
-		SameSite: http.SameSiteLaxMode,
-	})
+	middleware.SetSessionCookie(w, r, session)
 
 	_ = h.activityService.LogActivity(r.Context(), user.ID, domain.ActivityLogin, "User signed in via email", &ip, &ua)
 
@@ -702,6 +1053,87 @@ func (h *AuthHandler) HandleEmailAuthVerify(w http.ResponseWriter, r *http.Reque
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
+// redirectToTwoFactor sends the browser to the two-factor step when err says the sign-in
+// needs a code, keeping the pending sign-in in a cookie. It reports whether it did.
+func (h *AuthHandler) redirectToTwoFactor(w http.ResponseWriter, r *http.Request, err error) bool {
+	var challenge *domain.TOTPChallengeError
+	if !errors.As(err, &challenge) {
+		return false
+	}
+
+	middleware.SetTwoFactorCookie(w, r, challenge.Token)
+	if isHTMXRequest(r) {
+		w.Header().Set("HX-Redirect", "/signin/2fa")
+		w.WriteHeader(http.StatusOK)
+		return true
+	}
+	http.Redirect(w, r, "/signin/2fa", http.StatusSeeOther)
+	return true
+}
+
+// TwoFactorPage renders the two-factor code step of signing in.
+func (h *AuthHandler) TwoFactorPage(w http.ResponseWriter, r *http.Request) {
+	if middleware.TwoFactorCookieValue(r) == "" {
+		http.Redirect(w, r, "/signin", http.StatusSeeOther)
+		return
+	}
+
+	theme, themeEnabled := h.GetTheme(r)
+	h.RenderTempl(w, r, auth.TwoFactor(auth.TwoFactorProps{Theme: theme, ThemeEnabled: themeEnabled}))
+}
+
+// TwoFactor checks the submitted code and, if it is right, creates the session the
+// first sign-in step held back.
+func (h *AuthHandler) TwoFactor(w http.ResponseWriter, r *http.Request) {
+	ip := getIPAddress(r)
+	ua := r.UserAgent()
+
+	user, session, err := h.authService.CompleteTwoFactorLogin(r.Context(), middleware.TwoFactorCookieValue(r), r.FormValue("code"), ip, ua)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTOTPCode) {
+			theme, themeEnabled := h.GetTheme(r)
+			props := auth.TwoFactorProps{Error: "Invalid authentication code", Theme: theme, ThemeEnabled: themeEnabled}
+			if isHTMXRequest(r) {
+				h.RenderTempl(w, r, auth.TwoFactorForm(props))
+				return
+			}
+			h.RenderTempl(w, r, auth.TwoFactor(props))
+			return
+		}
+
+		redirectURL := "/signin?error=two_factor_expired"
+		if !errors.Is(err, domain.ErrInvalidToken) {
+			log.Printf("Two-factor sign-in failed: %v", err)
+			redirectURL = "/signin?error=server_error"
+		}
+		middleware.ClearTwoFactorCookie(w, r)
+		if isHTMXRequest(r) {
+			w.Header().Set("HX-Redirect", redirectURL)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	middleware.ClearTwoFactorCookie(w, r)
+	middleware.SetSessionCookie(w, r, session)
+
+	_ = h.activityService.LogActivity(r.Context(), user.ID, domain.ActivityLogin, "User signed in with two-factor authentication", &ip, &ua)
+
+	// Accounts created by a magic link may still need a name
+	redirectURL := getDashboardURLForRole(user)
+	if user.Name == "" {
+		redirectURL = "/auth/complete-profile"
+	}
+	if isHTMXRequest(r) {
+		w.Header().Set("HX-Redirect", redirectURL)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
 // CompleteProfilePage renders the profile completion page.
 func (h *AuthHandler) CompleteProfilePage(w http.ResponseWriter, r *http.Request) {
 	theme, themeEnabled := h.GetTheme(r)
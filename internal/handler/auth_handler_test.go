@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+)
+
+func TestAuthHandler_HandleOAuthCallbackRejectsBadState(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string // "" sends no oauth_state cookie
+		state  string
+	}{
+		{name: "missing cookie", state: "expected-state"},
+		{name: "mismatched state", cookie: "expected-state", state: "attacker-state"},
+		{name: "missing state param", cookie: "expected-state"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No auth service: reaching the code exchange would panic
+			h := &AuthHandler{}
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?code=abc&state="+tt.state, nil)
+			req.SetPathValue("provider", "google")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: middleware.OAuthStateCookieName, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+
+			h.HandleOAuthCallback(rec, req)
+
+			if rec.Code != http.StatusSeeOther {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusSeeOther)
+			}
+			if got := rec.Header().Get("Location"); got != "/signin?error=oauth_state" {
+				t.Errorf("Location = %q, want /signin?error=oauth_state", got)
+			}
+			assertOAuthStateCleared(t, rec)
+		})
+	}
+}
+
+// assertOAuthStateCleared checks the response expires the oauth_state cookie.
+func assertOAuthStateCleared(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	for _, c := range rec.Result().Cookies() {
+		if c.Name != middleware.OAuthStateCookieName {
+			continue
+		}
+		if c.Value != "" || c.MaxAge >= 0 {
+			t.Errorf("oauth_state cookie not cleared: value %q, max age %d", c.Value, c.MaxAge)
+		}
+		if c.Path != "/auth/" {
+			t.Errorf("oauth_state cookie path = %q, want /auth/", c.Path)
+		}
+		return
+	}
+	t.Error("response does not clear the oauth_state cookie")
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -17,15 +19,17 @@ import (
 
 type BlogHandler struct {
 	*Handler
-	blogService  *service.BlogService
-	mediaService *service.MediaService
+	blogService     *service.BlogService
+	mediaService    *service.MediaService
+	activityService service.ActivityService
 }
 
-func NewBlogHandler(base *Handler, blogService *service.BlogService, mediaService *service.MediaService) *BlogHandler {
+func NewBlogHandler(base *Handler, blogService *service.BlogService, mediaService *service.MediaService, activityService service.ActivityService) *BlogHandler {
 	return &BlogHandler{
-		Handler:      base,
-		blogService:  blogService,
-		mediaService: mediaService,
+		Handler:         base,
+		blogService:     blogService,
+		mediaService:    mediaService,
+		activityService: activityService,
 	}
 }
 
@@ -40,18 +44,15 @@ func (h *BlogHandler) NotFound(w http.ResponseWriter, r *http.Request) {
 // Public Routes
 
 func (h *BlogHandler) List(w http.ResponseWriter, r *http.Request) {
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-	limit := 10
-	offset := (page - 1) * limit
+	limit := h.pageSize(r, 10)
+	page, offset := pageParams(r, limit)
 
 	isPublished := true
 	filter := domain.BlogFilter{
-		IsPublished: &isPublished,
-		Limit:       limit,
-		Offset:      offset,
+		IsPublished:    &isPublished,
+		Limit:          limit,
+		Offset:         offset,
+		WithCoverMedia: true,
 	}
 
 	blogs, total, err := h.blogService.List(r.Context(), filter)
@@ -64,7 +65,21 @@ func (h *BlogHandler) List(w http.ResponseWriter, r *http.Request) {
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
 
-	h.RenderTempl(w, r, blog.List("Blog", blogs, total, page, limit, user, theme, themeEnabled, oauthEnabled))
+	// Signed-in visitors get a personalised header, so only anonymous pages are revalidated.
+	w.Header().Add("Vary", "Cookie")
+	if user != nil {
+		privatePage(w)
+	} else {
+		parts := []string{"blogs", strconv.Itoa(page), strconv.Itoa(limit), strconv.Itoa(total), theme, strconv.FormatBool(themeEnabled), strconv.FormatBool(oauthEnabled)}
+		for _, b := range blogs {
+			parts = append(parts, b.ID.String(), b.UpdatedAt.UTC().Format(time.RFC3339Nano), b.Author.Name)
+		}
+		if notModified(w, r, weakETag(parts...), time.Time{}) {
+			return
+		}
+	}
+
+	h.RenderTempl(w, r, blog.List("Blog", blogs, newPagination(r, page, limit, total), user, theme, themeEnabled, oauthEnabled))
 }
 
 func (h *BlogHandler) View(w http.ResponseWriter, r *http.Request) {
@@ -75,7 +90,11 @@ func (h *BlogHandler) View(w http.ResponseWriter, r *http.Request) {
 
 	b, err := h.blogService.GetBySlug(r.Context(), slug)
 	if err != nil {
-		h.NotFound(w, r)
+		if domain.IsNotFoundError(err) {
+			h.NotFound(w, r)
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load blog")
 		return
 	}
 
@@ -89,9 +108,34 @@ func (h *BlogHandler) View(w http.ResponseWriter, r *http.Request) {
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
 
+	w.Header().Add("Vary", "Cookie")
+	if user != nil {
+		privatePage(w)
+	} else {
+		etag := weakETag("blog", b.ID.String(), b.UpdatedAt.UTC().Format(time.RFC3339Nano), b.Author.Name, theme, strconv.FormatBool(themeEnabled), strconv.FormatBool(oauthEnabled))
+		if notModified(w, r, etag, b.UpdatedAt) {
+			return
+		}
+	}
+
 	h.RenderTempl(w, r, blog.View(b.Title, b, user, theme, themeEnabled, oauthEnabled))
 }
 
+// CountViews records a view of the post at /blogs/{slug} before handing the request on.
+// It runs in front of the page cache so that views served from the cache are counted too.
+// Only GET counts: HEAD requests, such as link checkers and uptime probes, aren't readers.
+func (h *BlogHandler) CountViews(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			slug := r.PathValue("slug")
+			if err := h.blogService.RecordViewBySlug(r.Context(), slug); err != nil {
+				log.Printf("Failed to record view for blog %s: %v", slug, err)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // GetCoverImage serves the cover image for a blog post (public)
 func (h *BlogHandler) GetCoverImage(w http.ResponseWriter, r *http.Request) {
 	slug := r.PathValue("slug")
@@ -100,7 +144,15 @@ func (h *BlogHandler) GetCoverImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	b, err := h.blogService.GetBySlug(r.Context(), slug)
-	if err != nil || b.CoverMediaID == nil {
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Failed to load blog", http.StatusInternalServerError)
+		return
+	}
+	if b.CoverMediaID == nil {
 		http.NotFound(w, r)
 		return
 	}
@@ -116,19 +168,150 @@ func (h *BlogHandler) GetCoverImage(w http.ResponseWriter, r *http.Request) {
 	w.Write(media.Data)
 }
 
+// Public API Routes
+
+// blogAPIPost is the public JSON shape of a published post. It leaves out the author's
+// email and anything only editors see.
+type blogAPIPost struct {
+	ID                 uuid.UUID      `json:"id"`
+	Title              string         `json:"title"`
+	Slug               string         `json:"slug"`
+	Excerpt            string         `json:"excerpt"`
+	Content            string         `json:"content,omitempty"` // Only in single-post responses
+	Author             *blogAPIAuthor `json:"author,omitempty"`
+	PublishedAt        *time.Time     `json:"published_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	CoverImage         *blogAPICover  `json:"cover_image,omitempty"`
+	Tags               []string       `json:"tags"`
+	ReadingTimeMinutes int            `json:"reading_time_minutes"`
+	SEO                blogAPISEO     `json:"seo"`
+}
+
+type blogAPIAuthor struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type blogAPICover struct {
+	URL    string `json:"url"`
+	Alt    string `json:"alt"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+type blogAPISEO struct {
+	MetaTitle       string `json:"meta_title,omitempty"`
+	MetaDescription string `json:"meta_description,omitempty"`
+	MetaKeywords    string `json:"meta_keywords,omitempty"`
+}
+
+func newBlogAPIPost(b *domain.Blog, withContent bool) blogAPIPost {
+	post := blogAPIPost{
+		ID:                 b.ID,
+		Title:              b.Title,
+		Slug:               b.Slug,
+		Excerpt:            b.Excerpt,
+		PublishedAt:        b.PublishedAt,
+		UpdatedAt:          b.UpdatedAt,
+		Tags:               b.Tags(),
+		ReadingTimeMinutes: b.ReadingTime(),
+		SEO: blogAPISEO{
+			MetaTitle:       b.MetaTitle,
+			MetaDescription: b.MetaDescription,
+			MetaKeywords:    b.MetaKeywords,
+		},
+	}
+	if withContent {
+		post.Content = b.Content
+	}
+	if b.Author != nil {
+		post.Author = &blogAPIAuthor{ID: b.Author.ID, Name: b.Author.Name}
+	}
+	if b.CoverMediaID != nil {
+		post.CoverImage = &blogAPICover{URL: b.CoverURL(), Alt: b.CoverAlt()}
+		if b.CoverMedia != nil {
+			post.CoverImage.Width = b.CoverMedia.Width
+			post.CoverImage.Height = b.CoverMedia.Height
+		}
+	}
+	return post
+}
+
+// APIList handles GET /api/blogs, returning a page of published posts without their content.
+func (h *BlogHandler) APIList(w http.ResponseWriter, r *http.Request) {
+	limit := h.pageSize(r, 10)
+	page, offset := pageParams(r, limit)
+
+	isPublished := true
+	blogs, total, err := h.blogService.List(r.Context(), domain.BlogFilter{
+		IsPublished:    &isPublished,
+		Limit:          limit,
+		Offset:         offset,
+		WithCoverMedia: true,
+	})
+	if err != nil {
+		h.JSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load blogs"})
+		return
+	}
+
+	posts := make([]blogAPIPost, 0, len(blogs))
+	for _, b := range blogs {
+		posts = append(posts, newBlogAPIPost(b, false))
+	}
+
+	h.JSON(w, http.StatusOK, map[string]interface{}{
+		"data":  posts,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+// APIView handles GET /api/blogs/{slug}, returning a published post with its content.
+// Drafts are reported as not found.
+func (h *BlogHandler) APIView(w http.ResponseWriter, r *http.Request) {
+	b, err := h.blogService.GetBySlug(r.Context(), r.PathValue("slug"))
+	if err == nil && !b.IsPublished {
+		err = domain.ErrNotFound
+	}
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			h.JSON(w, http.StatusNotFound, map[string]string{"error": "Blog not found"})
+			return
+		}
+		h.JSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load blog"})
+		return
+	}
+
+	if b.CoverMediaID != nil && b.CoverMedia == nil {
+		if media, err := h.mediaService.GetMetadata(r.Context(), *b.CoverMediaID); err == nil {
+			b.CoverMedia = media
+		}
+	}
+
+	h.JSON(w, http.StatusOK, newBlogAPIPost(b, true))
+}
+
 // Admin Routes
 
 func (h *BlogHandler) AdminList(w http.ResponseWriter, r *http.Request) {
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-	limit := 20
-	offset := (page - 1) * limit
+	limit := h.pageSize(r, 20)
+	page, offset := pageParams(r, limit)
+
+	user := middleware.GetUserFromContext(r.Context())
 
 	filter := domain.BlogFilter{
-		Limit:  limit,
-		Offset: offset,
+		Limit:          limit,
+		Offset:         offset,
+		WithCoverMedia: true,
+	}
+
+	// ?author=me narrows the list to the current user's own posts.
+	author := r.URL.Query().Get("author")
+	if author == "me" {
+		filter.AuthorID = &user.ID
+	} else {
+		author = ""
 	}
 
 	blogs, total, err := h.blogService.List(r.Context(), filter)
@@ -137,11 +320,10 @@ func (h *BlogHandler) AdminList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := middleware.GetUserFromContext(r.Context())
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
 
-	h.RenderTempl(w, r, admin.BlogList("Manage Blogs", blogs, total, page, limit, user, theme, themeEnabled, oauthEnabled))
+	h.RenderTempl(w, r, admin.BlogList("Manage Blogs", blogs, newPagination(r, page, limit, total), author, user, theme, themeEnabled, oauthEnabled))
 }
 
 func (h *BlogHandler) CreatePage(w http.ResponseWriter, r *http.Request) {
@@ -184,7 +366,7 @@ func (h *BlogHandler) Create(w http.ResponseWriter, r *http.Request) {
 	blog, err := h.blogService.Create(r.Context(), input, user.ID)
 	if err != nil {
 		// In a real app we'd re-render the form with errors
-		h.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to create blog: %v", err))
+		h.blogError(w, r, err, "Failed to create blog")
 		return
 	}
 
@@ -203,8 +385,10 @@ func (h *BlogHandler) Create(w http.ResponseWriter, r *http.Request) {
 				CoverImage: imageData,
 			}
 
-			if _, err := h.blogService.Update(r.Context(), blog.ID, updateInput); err != nil {
+			if updated, err := h.blogService.Update(r.Context(), blog.ID, updateInput); err != nil {
 				fmt.Printf("Failed to save cover image: %v\n", err)
+			} else if updated.CoverMedia != nil {
+				logMediaUpload(r, h.activityService, user.ID, updated.CoverMedia)
 			}
 		}
 	}
@@ -220,13 +404,20 @@ func (h *BlogHandler) EditPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blog, err := h.blogService.GetByID(r.Context(), id)
+	user := middleware.GetUserFromContext(r.Context())
+	blog, err := h.blogService.GetForManagement(r.Context(), id, user)
 	if err != nil {
-		h.NotFound(w, r)
+		if domain.IsNotFoundError(err) {
+			h.NotFound(w, r)
+			return
+		}
+		if h.AuthError(w, r, err) {
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load blog")
 		return
 	}
 
-	user := middleware.GetUserFromContext(r.Context())
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
 
@@ -241,6 +432,10 @@ func (h *BlogHandler) Edit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.loadManagedBlog(w, r, id); !ok {
+		return
+	}
+
 	err = r.ParseMultipartForm(10 << 20) // 10 MB max
 	if err != nil {
 		h.Error(w, r, http.StatusBadRequest, "Invalid form data")
@@ -295,12 +490,17 @@ func (h *BlogHandler) Edit(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("No cover_image file found in request: %v\n", err)
 	}
 
-	_, err = h.blogService.Update(r.Context(), id, input)
+	updated, err := h.blogService.Update(r.Context(), id, input)
 	if err != nil {
-		h.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to update blog: %v", err))
+		h.blogError(w, r, err, "Failed to update blog")
 		return
 	}
 
+	if len(input.CoverImage) > 0 && updated.CoverMedia != nil {
+		user := middleware.GetUserFromContext(r.Context())
+		logMediaUpload(r, h.activityService, user.ID, updated.CoverMedia)
+	}
+
 	http.Redirect(w, r, fmt.Sprintf("/a/blogs/%s/edit", id), http.StatusSeeOther)
 }
 
@@ -313,9 +513,16 @@ func (h *BlogHandler) GetBlogJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blog, err := h.blogService.GetByID(r.Context(), id)
-	if err != nil || blog == nil {
-		http.NotFound(w, r)
+	blog, err := h.blogService.GetForManagement(r.Context(), id, middleware.GetUserFromContext(r.Context()))
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			http.NotFound(w, r)
+			return
+		}
+		if h.AuthError(w, r, err) {
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load blog")
 		return
 	}
 
@@ -339,8 +546,12 @@ func (h *BlogHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.loadManagedBlog(w, r, id); !ok {
+		return
+	}
+
 	if err := h.blogService.Delete(r.Context(), id); err != nil {
-		h.Error(w, r, http.StatusInternalServerError, "Failed to delete blog")
+		h.blogError(w, r, err, "Failed to delete blog")
 		return
 	}
 
@@ -352,3 +563,34 @@ func (h *BlogHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, "/a/blogs", http.StatusSeeOther)
 }
+
+// loadManagedBlog fetches a post and checks that the current user may change it.
+// It writes a 401, 403 or 404 response and returns false when they may not.
+func (h *BlogHandler) loadManagedBlog(w http.ResponseWriter, r *http.Request, id uuid.UUID) (*domain.Blog, bool) {
+	b, err := h.blogService.GetForManagement(r.Context(), id, middleware.GetUserFromContext(r.Context()))
+	if err != nil {
+		h.blogError(w, r, err, "Failed to load blog")
+		return nil, false
+	}
+
+	return b, true
+}
+
+// blogError maps domain errors from the blog service to HTTP status codes.
+// Anything unrecognised is reported as a 500 with the given message.
+func (h *BlogHandler) blogError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if h.AuthError(w, r, err) {
+		return
+	}
+
+	switch {
+	case domain.IsNotFoundError(err):
+		h.Error(w, r, http.StatusNotFound, "Blog not found")
+	case domain.IsConflictError(err):
+		h.Error(w, r, http.StatusConflict, "A blog with this slug already exists")
+	case domain.IsValidationError(err):
+		h.Error(w, r, http.StatusBadRequest, err.Error())
+	default:
+		h.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("%s: %v", message, err))
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+)
+
+// fakeBlogRepo counts recorded views. Calling any other method panics.
+type fakeBlogRepo struct {
+	service.BlogRepository
+	views map[string]int
+}
+
+func (r *fakeBlogRepo) IncrementViewCountBySlug(_ context.Context, slug string) error {
+	r.views[slug]++
+	return nil
+}
+
+func TestBlogHandler_CountViews(t *testing.T) {
+	tests := []struct {
+		method string
+		want   int
+	}{
+		{method: http.MethodGet, want: 1},
+		{method: http.MethodHead, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			repo := &fakeBlogRepo{views: map[string]int{}}
+			h := &BlogHandler{blogService: service.NewBlogService(repo, nil)}
+
+			mux := http.NewServeMux()
+			mux.Handle("GET /blogs/{slug}", h.CountViews(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/blogs/hello", nil))
+
+			if got := repo.views["hello"]; got != tt.want {
+				t.Errorf("%s recorded %d views, want %d", tt.method, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,24 +3,31 @@ package handler
 import (
 	"encoding/json"
 	"log"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/a-h/templ"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/middleware"
 	"github.com/noruj-official/full-stack-go-template/internal/service"
+	"github.com/noruj-official/full-stack-go-template/web/templ/pages"
 )
 
 // Handler is the base handler with shared utilities.
 type Handler struct {
 	appName        string
 	appLogo        string
+	maxPageSize    int
 	featureService service.FeatureService
 }
 
 // NewHandler creates a new base handler.
-func NewHandler(appName, appLogo string, featureService service.FeatureService) *Handler {
+func NewHandler(appName, appLogo string, maxPageSize int, featureService service.FeatureService) *Handler {
 	return &Handler{
 		appName:        appName,
 		appLogo:        appLogo,
+		maxPageSize:    maxPageSize,
 		featureService: featureService,
 	}
 }
@@ -89,11 +96,51 @@ func (h *Handler) Error(w http.ResponseWriter, r *http.Request, status int, mess
 	http.Error(w, message, status)
 }
 
+// AuthError writes the response for domain.ErrUnauthorized (401) and domain.ErrForbidden (403):
+// JSON for API routes, an inline error for HTMX, and the themed access-denied page otherwise.
+// It reports false, writing nothing, for any other error so callers can fall through to their own handling.
+func (h *Handler) AuthError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var status int
+	var title, message string
+	switch {
+	case domain.IsUnauthorizedError(err):
+		status, title, message = http.StatusUnauthorized, "Unauthorized", "You need to sign in to view this page."
+	case domain.IsForbiddenError(err):
+		status, title, message = http.StatusForbidden, "Forbidden", "You don't have permission to access this resource."
+	default:
+		return false
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		h.JSON(w, status, map[string]string{"error": strings.ToLower(title)})
+		return true
+	}
+
+	if isHTMXRequest(r) {
+		h.Error(w, r, status, message)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	user := middleware.GetUserFromContext(r.Context())
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, pages.AccessDenied(title, message, status, user, theme, themeEnabled, oauthEnabled))
+	return true
+}
+
 // isHTMXRequest checks if the request is from HTMX.
 func isHTMXRequest(r *http.Request) bool {
 	return r.Header.Get("HX-Request") == "true"
 }
 
+// isJSONRequest reports whether the request body is JSON.
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
 func isHTMXBoosted(r *http.Request) bool {
 	return r.Header.Get("HX-Boosted") == "true"
 }
@@ -107,20 +154,8 @@ func (h *Handler) RenderTempl(w http.ResponseWriter, r *http.Request, component
 	}
 }
 
-// getIPAddress extracts the client IP address from the request.
+// getIPAddress extracts the client IP address from the request, using the configured
+// X-Forwarded-For strategy.
 func getIPAddress(r *http.Request) string {
-	// Check X-Forwarded-For header first
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return forwarded
-	}
-
-	// Check X-Real-IP header
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
-	}
-
-	// Fallback to RemoteAddr
-	return r.RemoteAddr
+	return middleware.ClientIP(r)
 }
@@ -3,12 +3,16 @@ package handler
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/startup"
 	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
 	"github.com/noruj-official/full-stack-go-template/web/templ/components"
 	"github.com/noruj-official/full-stack-go-template/web/templ/pages"
 	"github.com/noruj-official/full-stack-go-template/web/templ/pages/dashboards"
@@ -17,14 +21,18 @@ import (
 // HomeHandler handles home page requests.
 type HomeHandler struct {
 	*Handler
-	db *postgres.DB
+	db              *postgres.DB
+	settingsService service.SettingsService
+	startup         *startup.Tracker
 }
 
 // NewHomeHandler creates a new home handler.
-func NewHomeHandler(base *Handler, db *postgres.DB) *HomeHandler {
+func NewHomeHandler(base *Handler, db *postgres.DB, settingsService service.SettingsService, startupTracker *startup.Tracker) *HomeHandler {
 	return &HomeHandler{
-		Handler: base,
-		db:      db,
+		Handler:         base,
+		db:              db,
+		settingsService: settingsService,
+		startup:         startupTracker,
 	}
 }
 
@@ -34,7 +42,13 @@ func (h *HomeHandler) Index(w http.ResponseWriter, r *http.Request) {
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
 
-	h.RenderTempl(w, r, pages.Home("Full Stack Go Template", "A professional full-stack Go application", user, theme, themeEnabled, oauthEnabled))
+	// Fall back to the default content rather than failing the landing page
+	content, err := h.settingsService.HomePage(r.Context())
+	if err != nil {
+		log.Printf("Failed to load home page settings: %v", err)
+	}
+
+	h.RenderTempl(w, r, pages.Home("Full Stack Go Template", "A professional full-stack Go application", user, theme, themeEnabled, oauthEnabled, content))
 }
 
 // DashboardRedirect redirects to the appropriate dashboard based on user role.
@@ -168,6 +182,49 @@ func (h *HomeHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HealthCheckHead reports health through the status code only, for probes that send HEAD.
+func (h *HomeHandler) HealthCheckHead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.db.Health(r.Context()); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Readiness reports whether the instance should receive traffic: startup tasks such as
+// migrations and the feature flag sync have finished and the database is reachable.
+// Unlike HealthCheck, it stays unavailable while a startup task is still being retried.
+func (h *HomeHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if pending := h.startup.Pending(); len(pending) > 0 {
+		h.JSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status":  "not ready",
+			"pending": pending,
+		})
+		return
+	}
+	if err := h.db.Health(r.Context()); err != nil {
+		h.JSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "not ready",
+			"error":  "database unavailable",
+		})
+		return
+	}
+	h.JSON(w, http.StatusOK, map[string]string{
+		"status": "ready",
+	})
+}
+
+// ReadinessHead reports readiness through the status code only, for probes that send HEAD.
+func (h *HomeHandler) ReadinessHead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !h.startup.Ready() || h.db.Health(r.Context()) != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 // humanizeDuration returns a short relative time like "2m ago" or "1h ago".
 func humanizeDuration(d time.Duration) string {
 	if d < time.Minute {
@@ -198,6 +255,71 @@ func (h *HomeHandler) NotFound(w http.ResponseWriter, r *http.Request) {
 	h.RenderTempl(w, r, pages.NotFound("Page Not Found", "The page you requested was not found.", user, theme, themeEnabled, oauthEnabled))
 }
 
+// Forbidden renders the 403 response. It is used by the role middleware when a user lacks the required role.
+func (h *HomeHandler) Forbidden(w http.ResponseWriter, r *http.Request) {
+	h.AuthError(w, r, domain.ErrForbidden)
+}
+
+// ServerError renders the 500 page. It is used by the recovery middleware after a panic.
+func (h *HomeHandler) ServerError(w http.ResponseWriter, r *http.Request) {
+	if isHTMXRequest(r) {
+		h.Error(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	user := middleware.GetUserFromContext(r.Context())
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, pages.ServerError("Server Error", "Something went wrong.", user, theme, themeEnabled, oauthEnabled))
+}
+
+// MethodNotAllowed renders the 405 response: JSON for API routes, a themed page otherwise.
+// The Allow header is set by middleware.MethodNotAllowed before this runs.
+func (h *HomeHandler) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		h.JSON(w, http.StatusMethodNotAllowed, map[string]string{
+			"error": "method not allowed",
+			"allow": w.Header().Get("Allow"),
+		})
+		return
+	}
+
+	if isHTMXRequest(r) {
+		h.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	user := middleware.GetUserFromContext(r.Context())
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, pages.MethodNotAllowed("Method Not Allowed", "This page does not support that request method.", user, theme, themeEnabled, oauthEnabled))
+}
+
+// FeatureDisabled renders the response for routes behind a feature flag that is turned off.
+// It answers 404, since to visitors a disabled subsystem simply isn't there.
+func (h *HomeHandler) FeatureDisabled(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		h.JSON(w, http.StatusNotFound, map[string]string{"error": "feature disabled"})
+		return
+	}
+
+	if isHTMXRequest(r) {
+		h.Error(w, r, http.StatusNotFound, "This feature is turned off")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	user := middleware.GetUserFromContext(r.Context())
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, pages.FeatureDisabled("Feature Disabled", "This feature is turned off.", user, theme, themeEnabled, oauthEnabled))
+}
+
 // Sidebar renders the sidebar component independently.
 func (h *HomeHandler) Sidebar(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r.Context())
@@ -1,31 +1,54 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
 	"github.com/noruj-official/full-stack-go-template/internal/service"
+	"github.com/noruj-official/full-stack-go-template/web/templ/pages/admin"
 )
 
 type MediaHandler struct {
 	*Handler
-	mediaService *service.MediaService
+	mediaService    *service.MediaService
+	activityService service.ActivityService
 }
 
-func NewMediaHandler(base *Handler, mediaService *service.MediaService) *MediaHandler {
+func NewMediaHandler(base *Handler, mediaService *service.MediaService, activityService service.ActivityService) *MediaHandler {
 	return &MediaHandler{
-		Handler:      base,
-		mediaService: mediaService,
+		Handler:         base,
+		mediaService:    mediaService,
+		activityService: activityService,
 	}
 }
 
+// logMediaUpload records a media upload in the user's activity feed. It runs in the
+// background so a slow or failing activity insert never delays the upload response.
+func logMediaUpload(r *http.Request, activityService service.ActivityService, userID uuid.UUID, media *domain.Media) {
+	ipAddr := getIPAddress(r)
+	userAgent := r.UserAgent()
+	description := fmt.Sprintf("Uploaded %s (%s)", media.Filename, media.ID)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := activityService.LogActivity(ctx, userID, domain.ActivityMediaUpload, description, &ipAddr, &userAgent); err != nil {
+			log.Printf("Failed to log media upload %s: %v", media.ID, err)
+		}
+	}()
+}
+
 // Upload handles generic media uploads (used by editor, gallery, etc.)
 // Returns JSON: { id, url, filename }
 func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) {
@@ -99,10 +122,16 @@ func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) {
 
 	media, err := h.mediaService.Upload(r.Context(), input)
 	if err != nil {
+		if domain.IsValidationError(err) {
+			h.Error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.Error(w, r, http.StatusInternalServerError, "Failed to upload media")
 		return
 	}
 
+	logMediaUpload(r, h.activityService, user.ID, media)
+
 	// Construct URL: /media/{uuid}.{ext}
 	ext := filepath.Ext(media.Filename)
 	if ext == "" {
@@ -116,11 +145,15 @@ func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	publicURL := fmt.Sprintf("/media/%s%s", media.ID, ext)
 
 	// Return JSON for editor
-	response := map[string]string{
+	response := map[string]interface{}{
 		"id":       media.ID.String(),
 		"url":      publicURL,
 		"filename": media.Filename,
 	}
+	if media.Width > 0 && media.Height > 0 {
+		response["width"] = media.Width
+		response["height"] = media.Height
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -149,7 +182,11 @@ func (h *MediaHandler) Serve(w http.ResponseWriter, r *http.Request) {
 
 	media, err := h.mediaService.GetByID(r.Context(), id)
 	if err != nil {
-		http.NotFound(w, r)
+		if domain.IsNotFoundError(err) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Failed to load media", http.StatusInternalServerError)
 		return
 	}
 
@@ -163,3 +200,133 @@ func (h *MediaHandler) Serve(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "public, max-age=31536000") // 1 year
 	w.Write(media.Data)
 }
+
+// Head returns the headers Serve would send, without loading or writing the file data.
+func (h *MediaHandler) Head(w http.ResponseWriter, r *http.Request) {
+	filename := r.PathValue("filename")
+
+	idStr := filename
+	if idx := strings.LastIndex(filename, "."); idx != -1 {
+		idStr = filename[:idx]
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	media, err := h.mediaService.GetMetadata(r.Context(), id)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if media.StorageProvider == domain.StorageProviderS3 && media.PublicURL != "" {
+		w.Header().Set("Location", media.PublicURL)
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(media.SizeBytes))
+	w.Header().Set("Cache-Control", "public, max-age=31536000") // 1 year
+	w.WriteHeader(http.StatusOK)
+}
+
+// AdminList renders the admin media library with optional owner, type and date filters.
+func (h *MediaHandler) AdminList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := h.pageSize(r, 20)
+	page, offset := pageParams(r, limit)
+
+	filter := domain.MediaFilter{
+		ContentType: strings.TrimSpace(q.Get("type")),
+		Limit:       limit,
+		Offset:      offset,
+	}
+
+	owner := strings.TrimSpace(q.Get("owner"))
+	if owner != "" {
+		ownerID, err := uuid.Parse(owner)
+		if err != nil {
+			h.Error(w, r, http.StatusBadRequest, "Invalid owner ID")
+			return
+		}
+		filter.UserID = &ownerID
+	}
+
+	from := q.Get("from")
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			h.Error(w, r, http.StatusBadRequest, "Invalid from date")
+			return
+		}
+		filter.CreatedAfter = &t
+	}
+
+	to := q.Get("to")
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			h.Error(w, r, http.StatusBadRequest, "Invalid to date")
+			return
+		}
+		// Include the whole "to" day
+		t = t.AddDate(0, 0, 1)
+		filter.CreatedBefore = &t
+	}
+
+	items, total, err := h.mediaService.List(r.Context(), filter)
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load media")
+		return
+	}
+
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+
+	h.RenderTempl(w, r, admin.MediaLibrary(admin.MediaLibraryProps{
+		User:         middleware.GetUserFromContext(r.Context()),
+		Items:        items,
+		Pagination:   newPagination(r, page, limit, total),
+		Owner:        owner,
+		ContentType:  filter.ContentType,
+		From:         from,
+		To:           to,
+		Theme:        theme,
+		ThemeEnabled: themeEnabled,
+		OAuthEnabled: oauthEnabled,
+	}))
+}
+
+// AdminDelete handles DELETE /a/media/{id}
+func (h *MediaHandler) AdminDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.Error(w, r, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.mediaService.Delete(r.Context(), id); err != nil {
+		if domain.IsNotFoundError(err) {
+			h.Error(w, r, http.StatusNotFound, "Media not found")
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to delete media")
+		return
+	}
+
+	if isHTMXRequest(r) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, "/a/media", http.StatusSeeOther)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/internal/repository"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+	"github.com/noruj-official/full-stack-go-template/web/templ/pages/admin"
+)
+
+// OnlineHandler shows super admins which users currently have an active session.
+type OnlineHandler struct {
+	*Handler
+	sessionRepo  repository.SessionRepository
+	auditService service.AuditService
+}
+
+// NewOnlineHandler creates a new online users handler.
+func NewOnlineHandler(base *Handler, sessionRepo repository.SessionRepository, auditService service.AuditService) *OnlineHandler {
+	return &OnlineHandler{
+		Handler:      base,
+		sessionRepo:  sessionRepo,
+		auditService: auditService,
+	}
+}
+
+// List renders users with a session used within domain.OnlineWindow.
+// IP addresses are truncated, and each view is recorded in the audit log.
+func (h *OnlineHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	online, err := h.sessionRepo.ListOnline(r.Context(), time.Now().Add(-domain.OnlineWindow))
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load online users")
+		return
+	}
+
+	for _, u := range online {
+		u.IPAddress = maskIP(u.IPAddress)
+	}
+
+	ip := getIPAddress(r)
+	_ = h.auditService.LogAudit(r.Context(), user.ID, domain.AuditViewOnlineUsers, "session", nil, nil, map[string]interface{}{
+		"online_count": len(online),
+	}, &ip)
+
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+
+	props := admin.OnlineUsersProps{
+		User:         user,
+		Online:       online,
+		Window:       domain.OnlineWindow,
+		Theme:        theme,
+		ThemeEnabled: themeEnabled,
+		OAuthEnabled: oauthEnabled,
+	}
+
+	h.RenderTempl(w, r, admin.OnlineUsers(props))
+}
+
+// maskIP hides the host part of an address: the last octet of IPv4, everything after
+// the /48 prefix of IPv6. Enough to tell networks apart without identifying a device.
+func maskIP(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
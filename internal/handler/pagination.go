@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/noruj-official/full-stack-go-template/web/templ/components"
+)
+
+// pageWindow is how many numbered links are shown either side of the current page.
+const pageWindow = 2
+
+// pageSize reads the optional ?limit= query parameter, falling back to defaultSize.
+// The result is capped at the configured maximum so a crafted limit can't force a huge query.
+func (h *Handler) pageSize(r *http.Request, defaultSize int) int {
+	size := defaultSize
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		size = limit
+	}
+	if h.maxPageSize > 0 && size > h.maxPageSize {
+		size = h.maxPageSize
+	}
+	return size
+}
+
+// pageParams reads the 1-based ?page= query parameter and returns it with the row offset for pageSize.
+func pageParams(r *http.Request, pageSize int) (page, offset int) {
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	return page, (page - 1) * pageSize
+}
+
+// newPagination builds the pager view model for a list page. Page links keep the
+// request's other query parameters, so active filters survive paging.
+func newPagination(r *http.Request, page, pageSize, total int) components.Pagination {
+	p := components.Pagination{
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}
+	if pageSize > 0 {
+		p.TotalPages = (total + pageSize - 1) / pageSize
+	}
+
+	pageURL := func(n int) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(n))
+		return r.URL.Path + "?" + q.Encode()
+	}
+
+	if page > 1 {
+		p.PrevURL = pageURL(page - 1)
+	}
+	if page < p.TotalPages {
+		p.NextURL = pageURL(page + 1)
+	}
+
+	// First page, last page and a window around the current one, with gaps between.
+	for n := 1; n <= p.TotalPages; n++ {
+		if n != 1 && n != p.TotalPages && (n < page-pageWindow || n > page+pageWindow) {
+			p.Links = append(p.Links, components.PageLink{Gap: true})
+			if n < page {
+				n = min(page-pageWindow, p.TotalPages) - 1
+			} else {
+				n = p.TotalPages - 1
+			}
+			continue
+		}
+		p.Links = append(p.Links, components.PageLink{Number: n, URL: pageURL(n), Active: n == page})
+	}
+
+	return p
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_PageSize(t *testing.T) {
+	h := &Handler{maxPageSize: 100}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "no limit uses the default", query: "", want: 20},
+		{name: "limit within the maximum", query: "?limit=50", want: 50},
+		{name: "limit at the maximum", query: "?limit=100", want: 100},
+		{name: "absurd limit is clamped", query: "?limit=100000", want: 100},
+		{name: "zero falls back to the default", query: "?limit=0", want: 20},
+		{name: "negative falls back to the default", query: "?limit=-5", want: 20},
+		{name: "non-numeric falls back to the default", query: "?limit=all", want: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/a/users"+tt.query, nil)
+			if got := h.pageSize(req, 20); got != tt.want {
+				t.Errorf("pageSize(%q) = %d, want %d", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_PageSizeClampsDefault(t *testing.T) {
+	// A maximum below a handler's default still wins
+	h := &Handler{maxPageSize: 5}
+	req := httptest.NewRequest(http.MethodGet, "/a/users", nil)
+	if got := h.pageSize(req, 20); got != 5 {
+		t.Errorf("pageSize() = %d, want 5", got)
+	}
+}
+
+func TestPageParams(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantPage   int
+		wantOffset int
+	}{
+		{query: "", wantPage: 1, wantOffset: 0},
+		{query: "?page=3", wantPage: 3, wantOffset: 20},
+		{query: "?page=0", wantPage: 1, wantOffset: 0},
+		{query: "?page=-2", wantPage: 1, wantOffset: 0},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/a/users"+tt.query, nil)
+		page, offset := pageParams(req, 10)
+		if page != tt.wantPage || offset != tt.wantOffset {
+			t.Errorf("pageParams(%q) = %d, %d, want %d, %d", tt.query, page, offset, tt.wantPage, tt.wantOffset)
+		}
+	}
+}
@@ -2,6 +2,8 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 
@@ -42,6 +44,7 @@ func (h *ProfileHandler) ProfilePage(w http.ResponseWriter, r *http.Request) {
 	oauthEnabled := h.GetOAuthEnabled(r)
 	props := profile.UserProfileProps{
 		User:         user,
+		PendingEmail: h.pendingEmail(r, user),
 		Theme:        theme,
 		ThemeEnabled: themeEnabled,
 		OAuthEnabled: oauthEnabled,
@@ -50,36 +53,55 @@ func (h *ProfileHandler) ProfilePage(w http.ResponseWriter, r *http.Request) {
 	profile.UserProfile(props).Render(r.Context(), w)
 }
 
-// UpdateProfile handles profile information updates (name, email).
-func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+// Me returns the signed-in user as JSON, including their role and whether their email is verified.
+// Secrets such as the password hash and verification token are excluded by the User JSON tags.
+func (h *ProfileHandler) Me(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r.Context())
 	if user == nil {
 		h.Error(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	if err := r.ParseForm(); err != nil {
-		h.renderProfileError(w, r, "Invalid form data")
+	h.JSON(w, http.StatusOK, user)
+}
+
+// maxProfileBodySize caps the JSON body accepted by UpdateProfile.
+const maxProfileBodySize = 64 << 10
+
+// UpdateProfile handles profile information updates (name, email). It accepts a form
+// post from the profile page or a JSON UpdateUserInput from API clients, which get the
+// updated user back as JSON.
+func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		h.Error(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	name := r.FormValue("name")
-	email := r.FormValue("email")
+	jsonRequest := isJSONRequest(r)
+	fail := func(status int, errMsg string) {
+		if jsonRequest {
+			h.JSON(w, status, map[string]string{"error": errMsg})
+			return
+		}
+		h.renderProfileError(w, r, errMsg)
+	}
 
-	input := &domain.UpdateUserInput{
-		Email: &email,
-		Name:  &name,
+	input, err := parseProfileUpdate(w, r)
+	if err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
 	}
 
-	_, err := h.userService.UpdateUser(r.Context(), user.ID, input)
+	updated, err := h.userService.UpdateUser(r.Context(), user.ID, input)
 	if err != nil {
-		errMsg := "Failed to update profile"
+		status, errMsg := http.StatusInternalServerError, "Failed to update profile"
 		if domain.IsValidationError(err) {
-			errMsg = err.Error()
+			status, errMsg = http.StatusBadRequest, err.Error()
 		} else if domain.IsConflictError(err) {
-			errMsg = "This email is already in use"
+			status, errMsg = http.StatusConflict, "This email is already in use"
 		}
-		h.renderProfileError(w, r, errMsg)
+		fail(status, errMsg)
 		return
 	}
 
@@ -95,16 +117,66 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		&userAgent,
 	)
 
+	// A changed email is held back until the new address confirms it
+	message := "Profile updated successfully"
+	if input.Email != nil && domain.NormalizeEmail(*input.Email) != updated.Email {
+		newEmail := domain.NormalizeEmail(*input.Email)
+		_ = h.activityService.LogActivity(
+			r.Context(),
+			user.ID,
+			domain.ActivityEmailChange,
+			"Requested email change to "+newEmail,
+			&ipAddr,
+			&userAgent,
+		)
+		message = "Profile updated. We sent a confirmation link to " + newEmail + "; your email changes once you follow it."
+	}
+
 	// Success response
+	if jsonRequest {
+		h.JSON(w, http.StatusOK, updated)
+		return
+	}
+
 	if isHTMXRequest(r) {
 		w.Header().Set("HX-Trigger", "profileUpdated")
-		profile.ProfileSuccess("Profile updated successfully").Render(r.Context(), w)
+		profile.ProfileSuccess(message).Render(r.Context(), w)
 		return
 	}
 
 	http.Redirect(w, r, "/u/profile", http.StatusSeeOther)
 }
 
+// parseProfileUpdate reads a profile update from either a JSON or a form body.
+// Only the fields a user may change about themselves are kept, so a JSON client
+// can't set its own role or point its profile image at someone else's media.
+func parseProfileUpdate(w http.ResponseWriter, r *http.Request) (*domain.UpdateUserInput, error) {
+	var input domain.UpdateUserInput
+
+	if isJSONRequest(r) {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProfileBodySize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&input); err != nil {
+			return nil, errors.New("Invalid JSON body")
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, errors.New("Invalid form data")
+		}
+		name := r.FormValue("name")
+		email := r.FormValue("email")
+		input.Name = &name
+		input.Email = &email
+	}
+
+	return &domain.UpdateUserInput{
+		Email:          input.Email,
+		Name:           input.Name,
+		Username:       input.Username,
+		ActivityDigest: input.ActivityDigest,
+	}, nil
+}
+
 // UploadProfileImage handles profile image uploads.
 func (h *ProfileHandler) UploadProfileImage(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r.Context())
@@ -244,12 +316,22 @@ func (h *ProfileHandler) serveMedia(w http.ResponseWriter, r *http.Request, medi
 	w.Write(media.Data)
 }
 
+// pendingEmail returns the user's unconfirmed new email address, or "" if there is none.
+func (h *ProfileHandler) pendingEmail(r *http.Request, user *domain.User) string {
+	change, err := h.userService.PendingEmailChange(r.Context(), user.ID)
+	if err != nil {
+		return ""
+	}
+	return change.NewEmail
+}
+
 func (h *ProfileHandler) renderProfileError(w http.ResponseWriter, r *http.Request, errMsg string) {
 	user := middleware.GetUserFromContext(r.Context())
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
 	props := profile.UserProfileProps{
 		User:         user,
+		PendingEmail: h.pendingEmail(r, user),
 		Error:        errMsg,
 		Theme:        theme,
 		ThemeEnabled: themeEnabled,
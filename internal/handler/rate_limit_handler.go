@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/web/templ/pages/admin"
+)
+
+// RateLimitHandler exposes the state of the IP rate limiter to super admins.
+type RateLimitHandler struct {
+	*Handler
+	limiter *middleware.IPRateLimiter
+}
+
+// NewRateLimitHandler creates a new rate limit handler.
+func NewRateLimitHandler(base *Handler, limiter *middleware.IPRateLimiter) *RateLimitHandler {
+	return &RateLimitHandler{
+		Handler: base,
+		limiter: limiter,
+	}
+}
+
+// List renders the tracked IPs with their remaining tokens and last-seen time.
+func (h *RateLimitHandler) List(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.limiter.Snapshot(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load rate limiter state")
+		return
+	}
+
+	theme, themeEnabled := h.GetTheme(r)
+	oauthEnabled := h.GetOAuthEnabled(r)
+
+	props := admin.RateLimitsProps{
+		User:         middleware.GetUserFromContext(r.Context()),
+		Entries:      entries,
+		Theme:        theme,
+		ThemeEnabled: themeEnabled,
+		OAuthEnabled: oauthEnabled,
+	}
+
+	h.RenderTempl(w, r, admin.RateLimits(props))
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+)
+
+// maxSitemapURLs is the most URLs the sitemap protocol allows in a single file.
+const maxSitemapURLs = 50000
+
+// SEOHandler serves robots.txt and the sitemap. Outside production, crawlers are
+// turned away and no sitemap is served, so staging sites don't end up indexed.
+type SEOHandler struct {
+	*Handler
+	blogService *service.BlogService
+	publicURL   string
+	production  bool
+}
+
+// NewSEOHandler creates a new SEO handler. publicURL is the base for absolute sitemap URLs.
+func NewSEOHandler(base *Handler, blogService *service.BlogService, publicURL string, production bool) *SEOHandler {
+	return &SEOHandler{
+		Handler:     base,
+		blogService: blogService,
+		publicURL:   strings.TrimRight(publicURL, "/"),
+		production:  production,
+	}
+}
+
+// Robots serves robots.txt: allow everything and point to the sitemap in production,
+// disallow everything elsewhere.
+func (h *SEOHandler) Robots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if !h.production {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /\n")
+		return
+	}
+
+	fmt.Fprintf(w, "User-agent: *\nDisallow: /a/\nDisallow: /s/\nDisallow: /u/\nDisallow: /api/\nAllow: /\n\nSitemap: %s/sitemap.xml\n", h.publicURL)
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap serves sitemap.xml with the public pages and every published blog post.
+// It is only available in production.
+func (h *SEOHandler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	if !h.production {
+		http.NotFound(w, r)
+		return
+	}
+
+	set := sitemapURLSet{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs:  []sitemapURL{{Loc: h.publicURL + "/"}},
+	}
+
+	// Blog URLs are only listed while the blog feature is on; otherwise they would 404.
+	var blogs []*domain.Blog
+	if enabled, err := h.featureService.IsEnabled(r.Context(), domain.FeatureBlog); err == nil && enabled {
+		blogs, err = h.blogService.ListPublishedSlugs(r.Context(), maxSitemapURLs-2)
+		if err != nil {
+			log.Printf("Failed to build sitemap: %v", err)
+			http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+			return
+		}
+		set.URLs = append(set.URLs, sitemapURL{Loc: h.publicURL + "/blogs"})
+	}
+	for _, b := range blogs {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     h.publicURL + "/blogs/" + b.Slug,
+			LastMod: b.UpdatedAt.Format("2006-01-02"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	fmt.Fprint(w, xml.Header)
+	if err := xml.NewEncoder(w).Encode(set); err != nil {
+		log.Printf("Error encoding sitemap: %v", err)
+	}
+}
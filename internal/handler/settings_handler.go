@@ -2,7 +2,10 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/middleware"
@@ -14,14 +17,16 @@ import (
 type SettingsHandler struct {
 	*Handler
 	userService     service.UserService
+	authService     service.AuthService
 	activityService service.ActivityService
 }
 
 // NewSettingsHandler creates a new settings handler.
-func NewSettingsHandler(base *Handler, userService service.UserService, activityService service.ActivityService) *SettingsHandler {
+func NewSettingsHandler(base *Handler, userService service.UserService, authService service.AuthService, activityService service.ActivityService) *SettingsHandler {
 	return &SettingsHandler{
 		Handler:         base,
 		userService:     userService,
+		authService:     authService,
 		activityService: activityService,
 	}
 }
@@ -39,7 +44,7 @@ func (h *SettingsHandler) Settings(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method == http.MethodGet {
 		oauthEnabled := h.GetOAuthEnabled(r)
-		h.RenderTempl(w, r, profile.Settings("Settings", user, theme, themeEnabled, oauthEnabled, "", hasPassword))
+		h.RenderTempl(w, r, profile.Settings("Settings", user, theme, themeEnabled, oauthEnabled, "", hasPassword, h.authService.PasswordPolicy(r.Context()), h.linkedAccounts(r, user)))
 		return
 	}
 
@@ -50,10 +55,14 @@ func (h *SettingsHandler) Settings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	name := r.FormValue("name")
+	username := strings.TrimSpace(r.FormValue("username"))
+	activityDigest := r.FormValue("activity_digest") == "on"
 	// Email is read-only in settings
 
 	input := &domain.UpdateUserInput{
-		Name: &name,
+		Name:           &name,
+		Username:       &username,
+		ActivityDigest: &activityDigest,
 	}
 
 	_, err := h.userService.UpdateUser(r.Context(), user.ID, input)
@@ -62,7 +71,7 @@ func (h *SettingsHandler) Settings(w http.ResponseWriter, r *http.Request) {
 		if domain.IsValidationError(err) {
 			errMsg = err.Error()
 		} else if domain.IsConflictError(err) {
-			errMsg = "This email is already in use"
+			errMsg = "This username is already taken"
 		}
 		h.renderSettingsError(w, r, user, theme, themeEnabled, errMsg)
 		return
@@ -96,7 +105,68 @@ func (h *SettingsHandler) renderSettingsError(w http.ResponseWriter, r *http.Req
 	// For full page loads, this renders the full HTML
 	hasPassword := user.PasswordHash != ""
 	oauthEnabled := h.GetOAuthEnabled(r)
-	h.RenderTempl(w, r, profile.Settings("Settings", user, theme, themeEnabled, oauthEnabled, errMsg, hasPassword))
+	h.RenderTempl(w, r, profile.Settings("Settings", user, theme, themeEnabled, oauthEnabled, errMsg, hasPassword, h.authService.PasswordPolicy(r.Context()), h.linkedAccounts(r, user)))
+}
+
+// linkedAccounts loads the user's linked OAuth accounts for the settings page. A failure
+// is logged and shown as no linked accounts rather than failing the whole page.
+func (h *SettingsHandler) linkedAccounts(r *http.Request, user *domain.User) []*domain.UserOAuth {
+	links, err := h.userService.ListOAuthLinks(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to load linked accounts for user %s: %v", user.ID, err)
+		return nil
+	}
+	return links
+}
+
+// UnlinkOAuth handles POST /u/settings/oauth/{provider}/unlink, removing one of the current
+// user's linked accounts. Unlinking the only way the user can sign in is refused.
+func (h *SettingsHandler) UnlinkOAuth(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/signin", http.StatusSeeOther)
+		return
+	}
+	provider := domain.OAuthProviderType(r.PathValue("provider"))
+
+	if err := h.userService.UnlinkOAuth(r.Context(), user.ID, provider); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrLastSignInMethod):
+			if !isHTMXRequest(r) {
+				h.Error(w, r, http.StatusConflict, "Set a password or link another account before unlinking this one")
+				return
+			}
+			// Leave the row in place and explain why
+			w.Header().Set("HX-Reswap", "none")
+			w.Header().Set("HX-Trigger", `{"error-toast": "Set a password or link another account before unlinking this one"}`)
+			w.WriteHeader(http.StatusOK)
+		case domain.IsNotFoundError(err):
+			h.Error(w, r, http.StatusNotFound, "Linked account not found")
+		default:
+			h.Error(w, r, http.StatusInternalServerError, "Failed to unlink account")
+		}
+		return
+	}
+
+	ipAddr := getIPAddress(r)
+	userAgent := r.UserAgent()
+	_ = h.activityService.LogActivity(
+		r.Context(),
+		user.ID,
+		domain.ActivityOAuthUnlink,
+		"Unlinked "+string(provider)+" account",
+		&ipAddr,
+		&userAgent,
+	)
+
+	if isHTMXRequest(r) {
+		w.Header().Set("HX-Trigger", "oauthUnlinked")
+		// An empty body removes the account's row from the list
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, "/u/settings", http.StatusSeeOther)
 }
 
 // UpdatePassword handles password update requests.
@@ -110,7 +180,7 @@ func (h *SettingsHandler) UpdatePassword(w http.ResponseWriter, r *http.Request)
 	hasPassword := user.PasswordHash != ""
 
 	if err := r.ParseForm(); err != nil {
-		h.RenderTempl(w, r, profile.PasswordUpdateForm("Invalid form data", hasPassword))
+		h.RenderTempl(w, r, profile.PasswordUpdateForm("Invalid form data", hasPassword, h.authService.PasswordPolicy(r.Context()), nil))
 		return
 	}
 
@@ -127,7 +197,7 @@ func (h *SettingsHandler) UpdatePassword(w http.ResponseWriter, r *http.Request)
 		} else if err == domain.ErrInvalidCredentials {
 			errMsg = "Invalid current password"
 		}
-		h.RenderTempl(w, r, profile.PasswordUpdateForm(errMsg, hasPassword))
+		h.RenderTempl(w, r, profile.PasswordUpdateForm(errMsg, hasPassword, h.authService.PasswordPolicy(r.Context()), failedPasswordRules(err)))
 		return
 	}
 
@@ -143,5 +213,26 @@ func (h *SettingsHandler) UpdatePassword(w http.ResponseWriter, r *http.Request)
 		&userAgent,
 	)
 
-	h.RenderTempl(w, r, profile.SettingsSuccess("Password updated successfully"))
+	// Whoever knew the old password may still be signed in elsewhere, so end every session
+	// and keep this browser signed in on a fresh one.
+	if err := h.authService.SignOutAllDevices(r.Context(), user.ID); err != nil {
+		log.Printf("Failed to sign out other devices after password change for user %s: %v", user.ID, err)
+		h.RenderTempl(w, r, profile.SettingsSuccess("Password updated, but other devices could not be signed out. Use Sign Out All Devices to try again."))
+		return
+	}
+	session, err := h.authService.StartSession(r.Context(), user.ID, ipAddr, userAgent)
+	if err != nil {
+		log.Printf("Failed to start a new session after password change for user %s: %v", user.ID, err)
+		middleware.ClearSessionCookie(w, r)
+		if isHTMXRequest(r) {
+			w.Header().Set("HX-Redirect", "/signin")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/signin", http.StatusSeeOther)
+		return
+	}
+	middleware.SetSessionCookie(w, r, session)
+
+	h.RenderTempl(w, r, profile.SettingsSuccess("Password updated successfully. Other devices have been signed out."))
 }
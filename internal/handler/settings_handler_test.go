@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/password"
+	"github.com/noruj-official/full-stack-go-template/internal/repository"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUserRepo holds a single user in memory. Calling any other method panics.
+type fakeUserRepo struct {
+	repository.UserRepository
+	user *domain.User
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	if r.user == nil || r.user.ID != id {
+		return nil, domain.ErrNotFound
+	}
+	u := *r.user
+	return &u, nil
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *domain.User) error {
+	u := *user
+	r.user = &u
+	return nil
+}
+
+// fakeSettings serves the default password policy.
+type fakeSettings struct {
+	service.SettingsService
+}
+
+func (fakeSettings) PasswordPolicy(context.Context) (domain.PasswordPolicy, error) {
+	return domain.DefaultPasswordPolicy(), nil
+}
+
+// fakeAuth records sign-outs and hands out new sessions.
+type fakeAuth struct {
+	service.AuthService
+	signedOut []uuid.UUID
+	started   []*domain.Session
+}
+
+func (a *fakeAuth) PasswordPolicy(context.Context) domain.PasswordPolicy {
+	return domain.DefaultPasswordPolicy()
+}
+
+func (a *fakeAuth) SignOutAllDevices(_ context.Context, userID uuid.UUID) error {
+	a.signedOut = append(a.signedOut, userID)
+	return nil
+}
+
+func (a *fakeAuth) StartSession(_ context.Context, userID uuid.UUID, ip, userAgent string) (*domain.Session, error) {
+	session := domain.NewSession(userID, ip, userAgent)
+	a.started = append(a.started, session)
+	return session, nil
+}
+
+// fakeActivity discards activity logs.
+type fakeActivity struct {
+	service.ActivityService
+}
+
+func (fakeActivity) LogActivity(context.Context, uuid.UUID, domain.ActivityType, string, *string, *string) error {
+	return nil
+}
+
+func TestSettingsHandler_UpdatePassword(t *testing.T) {
+	const current = "old-password1"
+
+	tests := []struct {
+		name        string
+		form        url.Values
+		wantSignOut bool
+		wantBody    string
+	}{
+		{
+			name:        "success signs out every device",
+			form:        url.Values{"current_password": {current}, "new_password": {"new-password1"}, "confirm_password": {"new-password1"}},
+			wantSignOut: true,
+			wantBody:    "Other devices have been signed out",
+		},
+		{
+			name:     "wrong current password",
+			form:     url.Values{"current_password": {"not-my-password"}, "new_password": {"new-password1"}, "confirm_password": {"new-password1"}},
+			wantBody: "Invalid current password",
+		},
+		{
+			name:     "new passwords differ",
+			form:     url.Values{"current_password": {current}, "new_password": {"new-password1"}, "confirm_password": {"new-password2"}},
+			wantBody: "passwords do not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+			hash, err := hasher.Hash(current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			user := domain.NewUser("user@example.com", "User", hash, domain.RoleUser)
+			repo := &fakeUserRepo{user: user}
+			auth := &fakeAuth{}
+			users := service.NewUserService(repo, nil, nil, nil, nil, nil, fakeSettings{}, nil, nil, hasher)
+			h := NewSettingsHandler(&Handler{}, users, auth, fakeActivity{})
+
+			req := httptest.NewRequest(http.MethodPost, "/u/settings/password", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("HX-Request", "true")
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, user))
+			rec := httptest.NewRecorder()
+
+			h.UpdatePassword(rec, req)
+
+			if body := rec.Body.String(); !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body does not contain %q:\n%s", tt.wantBody, body)
+			}
+
+			changed := hasher.Verify(repo.user.PasswordHash, "new-password1")
+			if !tt.wantSignOut {
+				if changed {
+					t.Error("password was changed")
+				}
+				if len(auth.signedOut) != 0 || len(auth.started) != 0 {
+					t.Errorf("sessions touched: signed out %v, started %d", auth.signedOut, len(auth.started))
+				}
+				return
+			}
+
+			if !changed {
+				t.Error("password was not changed")
+			}
+			if len(auth.signedOut) != 1 || auth.signedOut[0] != user.ID {
+				t.Fatalf("SignOutAllDevices called for %v, want [%s]", auth.signedOut, user.ID)
+			}
+			if len(auth.started) != 1 {
+				t.Fatalf("StartSession called %d times, want 1", len(auth.started))
+			}
+			// This browser keeps signed in on the fresh session
+			var cookie *http.Cookie
+			for _, c := range rec.Result().Cookies() {
+				if c.Value == auth.started[0].ID {
+					cookie = c
+				}
+			}
+			if cookie == nil {
+				t.Error("response does not set a cookie for the new session")
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+	"github.com/noruj-official/full-stack-go-template/web/templ/pages/admin"
+)
+
+// SiteSettingsHandler lets super admins edit site content such as the landing page and welcome
+// email, and site-wide rules such as the password policy.
+type SiteSettingsHandler struct {
+	*Handler
+	settingsService service.SettingsService
+	auditService    service.AuditService
+}
+
+// NewSiteSettingsHandler creates a new site settings handler.
+func NewSiteSettingsHandler(base *Handler, settingsService service.SettingsService, auditService service.AuditService) *SiteSettingsHandler {
+	return &SiteSettingsHandler{
+		Handler:         base,
+		settingsService: settingsService,
+		auditService:    auditService,
+	}
+}
+
+// HomePage renders the landing page content editor.
+func (h *SiteSettingsHandler) HomePage(w http.ResponseWriter, r *http.Request) {
+	content, err := h.settingsService.HomePage(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load home page settings")
+		return
+	}
+
+	h.renderHomePage(w, r, admin.HomeSettingsProps{Content: content})
+}
+
+// UpdateHomePage saves the landing page content and records the change in the audit log.
+func (h *SiteSettingsHandler) UpdateHomePage(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.Error(w, r, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	old, err := h.settingsService.HomePage(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load home page settings")
+		return
+	}
+
+	content := domain.HomePageSettings{
+		HeroTitle:     r.FormValue("hero_title"),
+		HeroHighlight: r.FormValue("hero_highlight"),
+		HeroSubtitle:  r.FormValue("hero_subtitle"),
+		CTALabel:      r.FormValue("cta_label"),
+		CTAURL:        r.FormValue("cta_url"),
+	}
+
+	if err := h.settingsService.UpdateHomePage(r.Context(), &content); err != nil {
+		msg := "Failed to save home page settings"
+		if domain.IsValidationError(err) {
+			msg = err.Error()
+		} else {
+			log.Printf("Failed to save home page settings: %v", err)
+		}
+		h.renderHomePage(w, r, admin.HomeSettingsProps{Content: content, Error: msg})
+		return
+	}
+
+	if user := middleware.GetUserFromContext(r.Context()); user != nil {
+		ip := getIPAddress(r)
+		_ = h.auditService.LogAudit(r.Context(), user.ID, domain.AuditSystemConfig, "home_page", nil, stringMap(old.Map()), stringMap(content.Map()), &ip)
+	}
+
+	h.renderHomePage(w, r, admin.HomeSettingsProps{Content: content, Message: "Home page updated"})
+}
+
+// renderHomePage renders the full editor, or only the form for HTMX submissions.
+func (h *SiteSettingsHandler) renderHomePage(w http.ResponseWriter, r *http.Request, props admin.HomeSettingsProps) {
+	if isHTMXRequest(r) && r.Method == http.MethodPost {
+		admin.HomeSettingsForm(props).Render(r.Context(), w)
+		return
+	}
+
+	props.User = middleware.GetUserFromContext(r.Context())
+	props.Theme, props.ThemeEnabled = h.GetTheme(r)
+	props.OAuthEnabled = h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, admin.HomeSettings(props))
+}
+
+// WelcomeEmail renders the welcome email editor.
+func (h *SiteSettingsHandler) WelcomeEmail(w http.ResponseWriter, r *http.Request) {
+	content, err := h.settingsService.WelcomeEmail(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load welcome email settings")
+		return
+	}
+
+	h.renderWelcomeEmail(w, r, admin.WelcomeEmailSettingsProps{Content: content})
+}
+
+// UpdateWelcomeEmail saves the welcome email overrides and records the change in the audit log.
+func (h *SiteSettingsHandler) UpdateWelcomeEmail(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.Error(w, r, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	old, err := h.settingsService.WelcomeEmail(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load welcome email settings")
+		return
+	}
+
+	content := domain.WelcomeEmailSettings{
+		Subject: r.FormValue("subject"),
+		Body:    r.FormValue("body"),
+	}
+
+	if err := h.settingsService.UpdateWelcomeEmail(r.Context(), &content); err != nil {
+		msg := "Failed to save welcome email settings"
+		if domain.IsValidationError(err) {
+			msg = err.Error()
+		} else {
+			log.Printf("Failed to save welcome email settings: %v", err)
+		}
+		h.renderWelcomeEmail(w, r, admin.WelcomeEmailSettingsProps{Content: content, Error: msg})
+		return
+	}
+
+	if user := middleware.GetUserFromContext(r.Context()); user != nil {
+		ip := getIPAddress(r)
+		_ = h.auditService.LogAudit(r.Context(), user.ID, domain.AuditSystemConfig, "welcome_email", nil, stringMap(old.Map()), stringMap(content.Map()), &ip)
+	}
+
+	h.renderWelcomeEmail(w, r, admin.WelcomeEmailSettingsProps{Content: content, Message: "Welcome email updated"})
+}
+
+// renderWelcomeEmail renders the full editor, or only the form for HTMX submissions.
+func (h *SiteSettingsHandler) renderWelcomeEmail(w http.ResponseWriter, r *http.Request, props admin.WelcomeEmailSettingsProps) {
+	if isHTMXRequest(r) && r.Method == http.MethodPost {
+		admin.WelcomeEmailSettingsForm(props).Render(r.Context(), w)
+		return
+	}
+
+	props.User = middleware.GetUserFromContext(r.Context())
+	props.Theme, props.ThemeEnabled = h.GetTheme(r)
+	props.OAuthEnabled = h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, admin.WelcomeEmailSettings(props))
+}
+
+// PasswordPolicy renders the password policy editor.
+func (h *SiteSettingsHandler) PasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.settingsService.PasswordPolicy(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load the password policy")
+		return
+	}
+
+	h.renderPasswordPolicy(w, r, admin.PasswordPolicyProps{Policy: policy})
+}
+
+// UpdatePasswordPolicy saves the password policy and records the change in the audit log.
+func (h *SiteSettingsHandler) UpdatePasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.Error(w, r, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	old, err := h.settingsService.PasswordPolicy(r.Context())
+	if err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load the password policy")
+		return
+	}
+
+	policy := domain.PasswordPolicy{
+		RequireUpper:  r.FormValue("require_upper") == "true",
+		RequireLower:  r.FormValue("require_lower") == "true",
+		RequireDigit:  r.FormValue("require_digit") == "true",
+		RequireSymbol: r.FormValue("require_symbol") == "true",
+	}
+	policy.MinLength, _ = strconv.Atoi(r.FormValue("min_length"))
+
+	if err := h.settingsService.UpdatePasswordPolicy(r.Context(), &policy); err != nil {
+		msg := "Failed to save the password policy"
+		if domain.IsValidationError(err) {
+			msg = err.Error()
+		} else {
+			log.Printf("Failed to save the password policy: %v", err)
+		}
+		h.renderPasswordPolicy(w, r, admin.PasswordPolicyProps{Policy: policy, Error: msg})
+		return
+	}
+
+	if user := middleware.GetUserFromContext(r.Context()); user != nil {
+		ip := getIPAddress(r)
+		_ = h.auditService.LogAudit(r.Context(), user.ID, domain.AuditSystemConfig, "password_policy", nil, stringMap(old.Map()), stringMap(policy.Map()), &ip)
+	}
+
+	h.renderPasswordPolicy(w, r, admin.PasswordPolicyProps{Policy: policy, Message: "Password policy updated"})
+}
+
+// renderPasswordPolicy renders the full editor, or only the form for HTMX submissions.
+func (h *SiteSettingsHandler) renderPasswordPolicy(w http.ResponseWriter, r *http.Request, props admin.PasswordPolicyProps) {
+	if isHTMXRequest(r) && r.Method == http.MethodPost {
+		admin.PasswordPolicyForm(props).Render(r.Context(), w)
+		return
+	}
+
+	props.User = middleware.GetUserFromContext(r.Context())
+	props.Theme, props.ThemeEnabled = h.GetTheme(r)
+	props.OAuthEnabled = h.GetOAuthEnabled(r)
+	h.RenderTempl(w, r, admin.PasswordPolicySettings(props))
+}
+
+// stringMap converts settings to the map type taken by the audit log.
+func stringMap(values map[string]string) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/middleware"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+	"github.com/noruj-official/full-stack-go-template/web/templ/pages/profile"
+)
+
+// TwoFactorHandler handles setting up and managing TOTP two-factor authentication.
+type TwoFactorHandler struct {
+	*Handler
+	twoFactorService service.TwoFactorService
+	activityService  service.ActivityService
+}
+
+// NewTwoFactorHandler creates a new two-factor handler.
+func NewTwoFactorHandler(base *Handler, twoFactorService service.TwoFactorService, activityService service.ActivityService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		Handler:          base,
+		twoFactorService: twoFactorService,
+		activityService:  activityService,
+	}
+}
+
+// Status renders the two-factor card on the settings page.
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	h.renderCard(w, r, user, "", nil)
+}
+
+// EnablePage shows the secret for the user to add to their authenticator app. Reloading
+// the page shows the same pending secret rather than one the app doesn't have.
+func (h *TwoFactorHandler) EnablePage(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	secret, uri, err := h.twoFactorService.PendingEnrollment(r.Context(), user)
+	if domain.IsNotFoundError(err) {
+		secret, uri, err = h.twoFactorService.BeginEnrollment(r.Context(), user)
+	}
+	if err != nil {
+		if domain.IsConflictError(err) {
+			http.Redirect(w, r, "/u/settings", http.StatusSeeOther)
+			return
+		}
+		log.Printf("Failed to start two-factor setup for user %s: %v", user.ID, err)
+		h.Error(w, r, http.StatusInternalServerError, "Failed to start two-factor setup")
+		return
+	}
+
+	h.renderSetup(w, r, user, profile.TwoFactorSetupProps{Secret: secret, URI: uri})
+}
+
+// Enable checks the first code from the user's app, turns two-factor authentication on
+// and shows the recovery codes.
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	codes, err := h.twoFactorService.ConfirmEnrollment(r.Context(), user.ID, r.FormValue("code"))
+	if err != nil {
+		if domain.IsConflictError(err) {
+			// Already turned on, e.g. from another tab
+			if isHTMXRequest(r) {
+				w.Header().Set("HX-Redirect", "/u/settings")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			http.Redirect(w, r, "/u/settings", http.StatusSeeOther)
+			return
+		}
+		if !errors.Is(err, domain.ErrInvalidTOTPCode) {
+			log.Printf("Failed to enable two-factor authentication for user %s: %v", user.ID, err)
+			h.Error(w, r, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+			return
+		}
+
+		secret, uri, err := h.twoFactorService.PendingEnrollment(r.Context(), user)
+		if err != nil {
+			http.Redirect(w, r, "/u/settings/2fa/enable", http.StatusSeeOther)
+			return
+		}
+		h.renderSetup(w, r, user, profile.TwoFactorSetupProps{Secret: secret, URI: uri, Error: "That code didn't match. Check your device's clock and try the newest code."})
+		return
+	}
+
+	h.logActivity(r, user, "Two-factor authentication turned on")
+	h.renderSetup(w, r, user, profile.TwoFactorSetupProps{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a code.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(r.Context(), user.ID, r.FormValue("code"))
+	if err != nil {
+		h.renderCard(w, r, user, codeErrorMessage(err, user), nil)
+		return
+	}
+
+	h.logActivity(r, user, "Two-factor recovery codes regenerated")
+	h.renderCard(w, r, user, "", codes)
+}
+
+// Disable turns two-factor authentication off after checking a code.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	if err := h.twoFactorService.Disable(r.Context(), user.ID, r.FormValue("code")); err != nil {
+		h.renderCard(w, r, user, codeErrorMessage(err, user), nil)
+		return
+	}
+
+	h.logActivity(r, user, "Two-factor authentication turned off")
+	h.renderCard(w, r, user, "", nil)
+}
+
+func (h *TwoFactorHandler) renderCard(w http.ResponseWriter, r *http.Request, user *domain.User, errMsg string, codes []string) {
+	status, err := h.twoFactorService.Status(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to load two-factor status for user %s: %v", user.ID, err)
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load two-factor settings")
+		return
+	}
+	h.RenderTempl(w, r, profile.TwoFactorCard(status, errMsg, codes))
+}
+
+func (h *TwoFactorHandler) renderSetup(w http.ResponseWriter, r *http.Request, user *domain.User, props profile.TwoFactorSetupProps) {
+	if isHTMXRequest(r) && !isHTMXBoosted(r) {
+		h.RenderTempl(w, r, profile.TwoFactorSetup(props))
+		return
+	}
+	theme, themeEnabled := h.GetTheme(r)
+	h.RenderTempl(w, r, profile.TwoFactorSetupPage(user, theme, themeEnabled, h.GetOAuthEnabled(r), props))
+}
+
+func (h *TwoFactorHandler) logActivity(r *http.Request, user *domain.User, description string) {
+	ip := getIPAddress(r)
+	ua := r.UserAgent()
+	_ = h.activityService.LogActivity(r.Context(), user.ID, domain.ActivityTwoFactorChange, description, &ip, &ua)
+}
+
+// codeErrorMessage turns a failed code check into a message for the settings card.
+func codeErrorMessage(err error, user *domain.User) string {
+	if errors.Is(err, domain.ErrInvalidTOTPCode) {
+		return "That code didn't match. Enter a current code from your app or an unused recovery code."
+	}
+	log.Printf("Two-factor settings change failed for user %s: %v", user.ID, err)
+	return "Something went wrong. Please try again."
+}
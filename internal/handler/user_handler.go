@@ -2,8 +2,8 @@
 package handler
 
 import (
+	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
@@ -16,26 +16,26 @@ import (
 type UserHandler struct {
 	*Handler
 	userService  service.UserService
+	authService  service.AuthService
 	auditService service.AuditService
 }
 
 // NewUserHandler creates a new user handler.
-func NewUserHandler(base *Handler, userService service.UserService, auditService service.AuditService) *UserHandler {
+func NewUserHandler(base *Handler, userService service.UserService, authService service.AuthService, auditService service.AuditService) *UserHandler {
 	return &UserHandler{
 		Handler:      base,
 		userService:  userService,
+		authService:  authService,
 		auditService: auditService,
 	}
 }
 
 // List renders the users list page.
 func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
+	pageSize := h.pageSize(r, 10)
+	page, _ := pageParams(r, pageSize)
 
-	users, total, err := h.userService.ListUsers(r.Context(), page, 10)
+	users, total, err := h.userService.ListUsers(r.Context(), page, pageSize)
 	if err != nil {
 		h.Error(w, r, http.StatusInternalServerError, "Failed to load users")
 		return
@@ -47,7 +47,7 @@ func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	oauthEnabled := h.GetOAuthEnabled(r)
 
-	h.RenderTempl(w, r, usersPage.List("Users", "Manage your application users", user, showSidebar, theme, themeEnabled, oauthEnabled, users, total, page, int((total+9)/10)))
+	h.RenderTempl(w, r, usersPage.List("Users", "Manage your application users", user, showSidebar, theme, themeEnabled, oauthEnabled, users, newPagination(r, page, pageSize, int(total))))
 }
 
 // Create handles user creation form display and submission.
@@ -127,6 +127,27 @@ func (h *UserHandler) Edit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Method == http.MethodGet {
+		details, err := h.userService.GetUserDetails(r.Context(), id)
+		if err != nil {
+			if domain.IsNotFoundError(err) {
+				h.Error(w, r, http.StatusNotFound, "User not found")
+				return
+			}
+			h.Error(w, r, http.StatusInternalServerError, "Failed to load user")
+			return
+		}
+
+		// The page shows the user's linked accounts and last sign-in IP
+		logView(r, h.auditService, "user", &id, nil)
+
+		currentUser := middleware.GetUserFromContext(r.Context())
+		theme, themeEnabled := h.GetTheme(r)
+		oauthEnabled := h.GetOAuthEnabled(r)
+		h.RenderTempl(w, r, usersPage.Edit("Edit User", "Update user information", currentUser, true, theme, themeEnabled, oauthEnabled, details.User, details, ""))
+		return
+	}
+
 	user, err := h.userService.GetUser(r.Context(), id)
 	if err != nil {
 		if domain.IsNotFoundError(err) {
@@ -137,14 +158,6 @@ func (h *UserHandler) Edit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.Method == http.MethodGet {
-		currentUser := middleware.GetUserFromContext(r.Context())
-		theme, themeEnabled := h.GetTheme(r)
-		oauthEnabled := h.GetOAuthEnabled(r)
-		h.RenderTempl(w, r, usersPage.Edit("Edit User", "Update user information", currentUser, true, theme, themeEnabled, oauthEnabled, user, ""))
-		return
-	}
-
 	// Handle POST/PUT
 	if err := r.ParseForm(); err != nil {
 		h.Error(w, r, http.StatusBadRequest, "Invalid form data")
@@ -196,16 +209,23 @@ func (h *UserHandler) Edit(w http.ResponseWriter, r *http.Request) {
 
 func (h *UserHandler) renderEditForm(w http.ResponseWriter, r *http.Request, targetUser *domain.User, errMsg string) {
 	if isHTMXRequest(r) {
-		// Render just the form content (UserForm)
+		// Render just the form content (UserForm); the details panel around it stays on the page
 		// Note: We need to pass targetUser here essentially as the 'user' for UserForm
 		h.RenderTempl(w, r, usersPage.UserForm(targetUser, nil, errMsg))
 		return
 	}
 
+	// The full page must keep the linked accounts and last sign-in the GET showed
+	details, err := h.userService.GetUserDetails(r.Context(), targetUser.ID)
+	if err != nil {
+		log.Printf("Failed to load details for user %s: %v", targetUser.ID, err)
+		details = nil
+	}
+
 	currentUser := middleware.GetUserFromContext(r.Context())
 	theme, themeEnabled := h.GetTheme(r)
 	oauthEnabled := h.GetOAuthEnabled(r)
-	h.RenderTempl(w, r, usersPage.Edit("Edit User", "Update user information", currentUser, true, theme, themeEnabled, oauthEnabled, targetUser, errMsg))
+	h.RenderTempl(w, r, usersPage.Edit("Edit User", "Update user information", currentUser, true, theme, themeEnabled, oauthEnabled, targetUser, details, errMsg))
 }
 
 // Delete handles user deletion.
@@ -286,3 +306,102 @@ func (h *UserHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, "/a/users", http.StatusSeeOther)
 }
+
+// Secure revokes all of a user's sessions and outstanding tokens for incident response,
+// optionally emailing them a fresh password reset link.
+func (h *UserHandler) Secure(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.userService.GetUser(r.Context(), id)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			h.Error(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+
+	if err := h.userService.SecureAccount(r.Context(), id); err != nil {
+		h.Error(w, r, http.StatusInternalServerError, "Failed to secure account")
+		return
+	}
+
+	// Issued after the revocation so the new reset token is not swept up with the old ones
+	sendReset := r.FormValue("send_reset") == "on"
+	resetSent := false
+	if sendReset {
+		if err := h.authService.RequestPasswordReset(r.Context(), user.Email); err == nil {
+			resetSent = true
+		}
+	}
+
+	if admin := middleware.GetUserFromContext(r.Context()); admin != nil {
+		ip := getIPAddress(r)
+		_ = h.auditService.LogAudit(r.Context(), admin.ID, domain.AuditUserSecure, "user", &id, nil, map[string]interface{}{
+			"sessions_revoked":    true,
+			"tokens_revoked":      true,
+			"password_reset_sent": resetSent,
+		}, &ip)
+	}
+
+	if isHTMXRequest(r) {
+		if sendReset && !resetSent {
+			w.Header().Set("HX-Trigger", `{"error-toast": "Account secured, but the password reset email could not be sent"}`)
+		} else {
+			w.Header().Set("HX-Trigger", "userSecured")
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Redirect(w, r, "/a/users/"+id.String()+"/edit", http.StatusSeeOther)
+}
+
+// RevokeOAuth unlinks one of a user's OAuth accounts and revokes its token with the
+// provider where supported, e.g. after the provider has been compromised.
+func (h *UserHandler) RevokeOAuth(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	provider := domain.OAuthProviderType(r.PathValue("provider"))
+
+	providerRevoked, err := h.userService.RevokeOAuthLink(r.Context(), id, provider)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			h.Error(w, r, http.StatusNotFound, "Linked account not found")
+			return
+		}
+		h.Error(w, r, http.StatusInternalServerError, "Failed to revoke linked account")
+		return
+	}
+
+	if admin := middleware.GetUserFromContext(r.Context()); admin != nil {
+		ip := getIPAddress(r)
+		_ = h.auditService.LogAudit(r.Context(), admin.ID, domain.AuditOAuthRevoke, "user", &id, map[string]interface{}{
+			"provider": provider,
+		}, map[string]interface{}{
+			"unlinked":         true,
+			"provider_revoked": providerRevoked,
+		}, &ip)
+	}
+
+	if isHTMXRequest(r) {
+		if providerRevoked {
+			w.Header().Set("HX-Trigger", "oauthRevoked")
+		} else {
+			w.Header().Set("HX-Trigger", `{"error-toast": "Account unlinked, but the provider did not confirm the token was revoked"}`)
+		}
+		// An empty body removes the account's row from the list
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, "/a/users/"+id.String()+"/edit", http.StatusSeeOther)
+}
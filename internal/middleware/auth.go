@@ -20,8 +20,17 @@ const (
 	SessionIDContextKey contextKey = "sessionID"
 )
 
-// SessionCookieName is the name of the session cookie.
-const SessionCookieName = "session_id"
+// DefaultSessionCookieName is the session cookie name used unless SetSessionCookieName overrides it.
+const DefaultSessionCookieName = "session_id"
+
+// forbiddenHandler renders the 403 response for RequireRole. When nil a plain response is written.
+var forbiddenHandler http.Handler
+
+// SetForbiddenHandler sets the handler RequireRole uses to render the 403 response,
+// so it can match the rest of the site's error pages.
+func SetForbiddenHandler(h http.Handler) {
+	forbiddenHandler = h
+}
 
 // Auth is middleware that validates the session and loads the user into context.
 // It does not block access - use RequireAuth for protected routes.
@@ -38,30 +47,27 @@ func NewAuth(authService service.AuthService) *Auth {
 func (a *Auth) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get session cookie
-		cookie, err := r.Cookie(SessionCookieName)
-		if err != nil || cookie.Value == "" {
+		sessionID := SessionCookieValue(r)
+		if sessionID == "" {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Validate session and get user
-		user, err := a.authService.ValidateSession(r.Context(), cookie.Value)
+		// Validate session and get user, sliding the expiry forward for active sessions
+		user, renewedUntil, err := a.authService.ExtendSession(r.Context(), sessionID)
 		if err != nil {
 			// Clear invalid cookie
-			http.SetCookie(w, &http.Cookie{
-				Name:     SessionCookieName,
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			})
+			ClearSessionCookie(w, r)
 			next.ServeHTTP(w, r)
 			return
 		}
+		if !renewedUntil.IsZero() {
+			renewSessionCookie(w, r, sessionID, renewedUntil)
+		}
 
 		// Add user and session ID to context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
-		ctx = context.WithValue(ctx, SessionIDContextKey, cookie.Value)
+		ctx = context.WithValue(ctx, SessionIDContextKey, sessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -84,13 +90,7 @@ func RequireAuth(next http.Handler) http.Handler {
 		// Check user status
 		if user.Status != domain.UserStatusActive {
 			// Clear any session if present
-			http.SetCookie(w, &http.Cookie{
-				Name:     SessionCookieName,
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			})
+			ClearSessionCookie(w, r)
 
 			if r.Header.Get("HX-Request") == "true" {
 				w.Header().Set("HX-Redirect", "/signin")
@@ -130,6 +130,10 @@ func RequireRole(roles ...domain.Role) func(http.Handler) http.Handler {
 			}
 
 			if !hasRole {
+				if forbiddenHandler != nil {
+					forbiddenHandler.ServeHTTP(w, r)
+					return
+				}
 				if r.Header.Get("HX-Request") == "true" {
 					w.WriteHeader(http.StatusForbidden)
 					w.Write([]byte(`<div class="text-red-500">Access denied</div>`))
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Client IP strategies, selecting how far the X-Forwarded-For header is trusted.
+const (
+	// XFFLeftmost takes the first X-Forwarded-For entry, for deployments behind a single
+	// proxy that replaces the header. Anywhere else clients can spoof their address.
+	XFFLeftmost = "leftmost"
+	// XFFRightmostTrusted walks X-Forwarded-For from the right, skipping trusted proxies,
+	// and only when the request itself came from a trusted proxy.
+	XFFRightmostTrusted = "rightmost-trusted"
+	// XFFDirect ignores forwarding headers and uses the connection's peer address.
+	XFFDirect = "direct"
+)
+
+// defaultTrustedProxies are the loopback and private ranges a reverse proxy usually sits in.
+var defaultTrustedProxies = []string{
+	"127.0.0.0/8", "::1/128",
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7",
+}
+
+var (
+	clientIPStrategy = XFFRightmostTrusted
+	trustedProxies   = mustParsePrefixes(defaultTrustedProxies)
+)
+
+// SetClientIPStrategy selects how ClientIP resolves the client address: XFFLeftmost,
+// XFFRightmostTrusted or XFFDirect. proxies lists the IPs/CIDRs of trusted reverse
+// proxies for XFFRightmostTrusted, and whose X-Forwarded-Proto IsSecureCookie believes
+// under any strategy but XFFDirect; when empty, loopback and private ranges are trusted.
+func SetClientIPStrategy(strategy string, proxies []string) error {
+	switch strategy {
+	case XFFLeftmost, XFFRightmostTrusted, XFFDirect:
+	default:
+		return fmt.Errorf("%q is not one of %s, %s or %s", strategy, XFFLeftmost, XFFRightmostTrusted, XFFDirect)
+	}
+
+	if len(proxies) == 0 {
+		proxies = defaultTrustedProxies
+	}
+	prefixes, err := parsePrefixes(proxies)
+	if err != nil {
+		return err
+	}
+
+	clientIPStrategy = strategy
+	trustedProxies = prefixes
+	return nil
+}
+
+// ClientIP returns the client's IP address, without a port, using the configured strategy.
+// It is the single place request IPs come from, for audit and activity logs, sessions
+// and rate limiting alike.
+func ClientIP(r *http.Request) string {
+	peer := remoteIP(r.RemoteAddr)
+
+	switch clientIPStrategy {
+	case XFFDirect:
+		return peer
+
+	case XFFLeftmost:
+		if entries := forwardedFor(r); len(entries) > 0 {
+			return entries[0]
+		}
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+		return peer
+
+	default:
+		if !isTrustedProxy(peer) {
+			return peer
+		}
+		entries := forwardedFor(r)
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !isTrustedProxy(entries[i]) {
+				return entries[i]
+			}
+		}
+		// Every hop was a trusted proxy, so the leftmost is the closest we have to the client
+		if len(entries) > 0 {
+			return entries[0]
+		}
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+		return peer
+	}
+}
+
+// forwardedFor returns the non-empty entries of every X-Forwarded-For header, in order.
+func forwardedFor(r *http.Request) []string {
+	var entries []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// remoteIP strips the port from a RemoteAddr, returning it unchanged if it has none.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// fromTrustedProxy reports whether the request's forwarding headers can be believed: it
+// arrived directly from a trusted proxy and the strategy doesn't ignore those headers.
+func fromTrustedProxy(r *http.Request) bool {
+	return clientIPStrategy != XFFDirect && isTrustedProxy(remoteIP(r.RemoteAddr))
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, ok := parseClientIP(ip)
+	if !ok {
+		return false
+	}
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefixes parses IPs and CIDR ranges, treating a bare IP as a single-address range.
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid entry %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func mustParsePrefixes(entries []string) []netip.Prefix {
+	prefixes, err := parsePrefixes(entries)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
@@ -0,0 +1,336 @@
+// Package middleware provides HTTP middleware functions.
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+)
+
+// Cookie Secure flag modes.
+const (
+	CookieSecureAuto   = "auto"
+	CookieSecureAlways = "always"
+	CookieSecureNever  = "never"
+)
+
+// cookieSecureMode is the configured Secure flag mode for cookies set by the app.
+var cookieSecureMode = CookieSecureAuto
+
+// sessionSameSite is the SameSite mode applied to session cookies.
+var sessionSameSite = http.SameSiteLaxMode
+
+// sessionHostPrefix enables the __Host- prefixed session cookie on HTTPS requests.
+var sessionHostPrefix bool
+
+// sessionCookieBaseName is the configured session cookie name, without any __Host- prefix.
+var sessionCookieBaseName = DefaultSessionCookieName
+
+// sessionCookieDomain is the Domain attribute for session cookies on HTTPS requests; empty means host-only.
+var sessionCookieDomain string
+
+// hostCookiePrefix pins a cookie to the exact host. Browsers only accept it with
+// Secure, Path=/ and no Domain.
+const hostCookiePrefix = "__Host-"
+
+// SetCookieSecureMode configures how the Secure flag is decided: "auto" (default),
+// "always" or "never". Unknown values fall back to auto.
+func SetCookieSecureMode(mode string) {
+	switch strings.ToLower(mode) {
+	case CookieSecureAlways, CookieSecureNever:
+		cookieSecureMode = strings.ToLower(mode)
+	default:
+		cookieSecureMode = CookieSecureAuto
+	}
+}
+
+// IsSecureCookie reports whether the request counts as HTTPS for cookie purposes. It decides
+// the Secure flag on every cookie the app sets, and for session cookies also whether the
+// __Host- name (sessionCookieName) and the configured Domain (sessionDomain) apply. In auto
+// mode the request counts as HTTPS if it arrived over TLS, or if it came from a trusted proxy
+// (see SetClientIPStrategy) that set X-Forwarded-Proto: https. Any other client could send
+// the header to pick the cookie name and domain, so it is ignored from them.
+func IsSecureCookie(r *http.Request) bool {
+	switch cookieSecureMode {
+	case CookieSecureAlways:
+		return true
+	case CookieSecureNever:
+		return false
+	}
+
+	if r.TLS != nil {
+		return true
+	}
+	return fromTrustedProxy(r) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// SetSessionSameSite configures the SameSite mode for session cookies: "lax" (default) or "strict".
+// Strict suits password-only deployments; cookies that must survive a cross-site redirect,
+// such as OAuth state, should keep using Lax.
+func SetSessionSameSite(mode string) {
+	if strings.EqualFold(mode, "strict") {
+		sessionSameSite = http.SameSiteStrictMode
+		return
+	}
+	sessionSameSite = http.SameSiteLaxMode
+}
+
+// SetSessionCookieName sets the session cookie name so several deployments sharing a parent
+// domain don't overwrite each other's sessions. Empty or invalid names fall back to session_id;
+// a __Host- prefix is stripped since SetSessionHostPrefix controls it.
+func SetSessionCookieName(name string) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), hostCookiePrefix)
+	if name == "" || (&http.Cookie{Name: name, Value: "x"}).Valid() != nil {
+		sessionCookieBaseName = DefaultSessionCookieName
+		return
+	}
+	sessionCookieBaseName = name
+}
+
+// SessionCookieName returns the configured session cookie name, without any __Host- prefix.
+func SessionCookieName() string {
+	return sessionCookieBaseName
+}
+
+// hostSessionCookieName returns the __Host- prefixed session cookie name used when the prefix is enabled.
+func hostSessionCookieName() string {
+	return hostCookiePrefix + sessionCookieBaseName
+}
+
+// SetSessionCookieDomain scopes the session cookie to domain and its subdomains, so one sign-in
+// works across e.g. app.example.com and admin.example.com. An empty domain keeps host-only cookies.
+// It returns an error for anything that isn't a plain DNS name such as "example.com".
+func SetSessionCookieDomain(domain string) error {
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+	if domain == "" {
+		sessionCookieDomain = ""
+		return nil
+	}
+	if !isValidCookieDomain(domain) {
+		return fmt.Errorf("%q is not a DNS name such as example.com", domain)
+	}
+	sessionCookieDomain = domain
+	return nil
+}
+
+// isValidCookieDomain reports whether domain is a DNS name with at least two labels.
+// IP addresses, ports and single-label names like "localhost" are rejected.
+func isValidCookieDomain(domain string) bool {
+	if net.ParseIP(domain) != nil || len(domain) > 253 {
+		return false
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sessionDomain returns the Domain attribute for this request's session cookie. It is only
+// set over HTTPS, and only when the request host is within the configured domain, since
+// browsers drop cookies whose Domain doesn't match the host.
+func sessionDomain(r *http.Request) string {
+	if sessionCookieDomain == "" || !IsSecureCookie(r) {
+		return ""
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if host != sessionCookieDomain && !strings.HasSuffix(host, "."+sessionCookieDomain) {
+		return ""
+	}
+	return sessionCookieDomain
+}
+
+// SetSessionHostPrefix enables naming the session cookie __Host-<name> on HTTPS requests.
+// Plain HTTP requests (e.g. local development) keep the legacy name since browsers reject
+// __Host- cookies without Secure.
+func SetSessionHostPrefix(enabled bool) {
+	sessionHostPrefix = enabled
+}
+
+// sessionCookieName returns the session cookie name to use for this request.
+// A domain-scoped cookie never gets the __Host- prefix, which forbids a Domain attribute.
+func sessionCookieName(r *http.Request) string {
+	if sessionHostPrefix && IsSecureCookie(r) && sessionDomain(r) == "" {
+		return hostSessionCookieName()
+	}
+	return sessionCookieBaseName
+}
+
+// SessionCookieValue returns the session ID from the request, preferring the __Host- cookie
+// and falling back to the legacy name so existing sessions survive enabling the prefix.
+func SessionCookieValue(r *http.Request) string {
+	for _, name := range []string{hostSessionCookieName(), sessionCookieBaseName} {
+		if c, err := r.Cookie(name); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// SetSessionCookie writes the session cookie for a newly started session and rotates the
+// CSRF token. Renewing an existing session uses renewSessionCookie instead.
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, session *domain.Session) {
+	http.SetCookie(w, newSessionCookie(r, session.ID, session.ExpiresAt))
+	RotateCSRFToken(w, r)
+}
+
+// renewSessionCookie rewrites the session cookie with a later expiry, keeping the CSRF token.
+func renewSessionCookie(w http.ResponseWriter, r *http.Request, sessionID string, expires time.Time) {
+	http.SetCookie(w, newSessionCookie(r, sessionID, expires))
+}
+
+// ClearSessionCookie removes the session cookie under every name it may have been set with
+// and rotates the CSRF token.
+func ClearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	for _, c := range expiredSessionCookies(r) {
+		http.SetCookie(w, c)
+	}
+	RotateCSRFToken(w, r)
+}
+
+// newSessionCookie builds the session cookie with the configured security policy.
+func newSessionCookie(r *http.Request, value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookieName(r),
+		Value:    value,
+		Path:     "/",
+		Domain:   sessionDomain(r),
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   IsSecureCookie(r),
+		SameSite: sessionSameSite,
+	}
+}
+
+// expiredSessionCookies builds cookies that clear the session under both the legacy
+// and (when applicable) the __Host- prefixed name, plus the domain-scoped cookie if one is configured.
+func expiredSessionCookies(r *http.Request) []*http.Cookie {
+	secure := IsSecureCookie(r)
+
+	names := []string{sessionCookieBaseName}
+	if secure {
+		names = append(names, hostSessionCookieName())
+	}
+
+	expired := func(name, domain string) *http.Cookie {
+		return &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			Domain:   domain,
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: sessionSameSite,
+		}
+	}
+
+	cookies := make([]*http.Cookie, 0, len(names)+1)
+	for _, name := range names {
+		cookies = append(cookies, expired(name, ""))
+	}
+	if domain := sessionDomain(r); domain != "" {
+		cookies = append(cookies, expired(sessionCookieBaseName, domain))
+	}
+	return cookies
+}
+
+// OAuthStateCookieName holds the state parameter of an OAuth sign-in in progress.
+const OAuthStateCookieName = "oauth_state"
+
+// oauthStateTTL is how long a user has to finish signing in at the provider.
+const oauthStateTTL = 10 * time.Minute
+
+// SetOAuthStateCookie stores the OAuth state for the callback to check. It uses SameSite=Lax
+// whatever the session setting, since the callback is a cross-site redirect from the provider.
+func SetOAuthStateCookie(w http.ResponseWriter, r *http.Request, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OAuthStateCookieName,
+		Value:    state,
+		Path:     "/auth/",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   IsSecureCookie(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OAuthStateCookieValue returns the stored OAuth state, or "" if there is none.
+func OAuthStateCookieValue(r *http.Request) string {
+	if c, err := r.Cookie(OAuthStateCookieName); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ClearOAuthStateCookie removes the OAuth state cookie so a state can only be used once.
+func ClearOAuthStateCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OAuthStateCookieName,
+		Value:    "",
+		Path:     "/auth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   IsSecureCookie(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// TwoFactorCookieName holds the token of a sign-in waiting on a two-factor code.
+const TwoFactorCookieName = "two_factor"
+
+// twoFactorCookieTTL matches how long the pending sign-in token is valid.
+const twoFactorCookieTTL = 5 * time.Minute
+
+// SetTwoFactorCookie stores a pending sign-in for the /signin/2fa step. Like the OAuth state
+// cookie it uses SameSite=Lax, since an OAuth sign-in reaches this step from a cross-site redirect.
+func SetTwoFactorCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     TwoFactorCookieName,
+		Value:    token,
+		Path:     "/signin/2fa",
+		MaxAge:   int(twoFactorCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   IsSecureCookie(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// TwoFactorCookieValue returns the pending sign-in token, or "" if there is none.
+func TwoFactorCookieValue(r *http.Request) string {
+	if c, err := r.Cookie(TwoFactorCookieName); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ClearTwoFactorCookie removes the pending sign-in once it has been completed.
+func ClearTwoFactorCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     TwoFactorCookieName,
+		Value:    "",
+		Path:     "/signin/2fa",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   IsSecureCookie(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
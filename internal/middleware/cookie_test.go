@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsSecureCookie_ForwardedProto(t *testing.T) {
+	if err := SetClientIPStrategy(XFFRightmostTrusted, []string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetClientIPStrategy(XFFRightmostTrusted, nil) })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		proto      string
+		tls        bool
+		want       bool
+	}{
+		{name: "plain HTTP", remoteAddr: "203.0.113.7:5000", want: false},
+		{name: "TLS", remoteAddr: "203.0.113.7:5000", tls: true, want: true},
+		{name: "trusted proxy says https", remoteAddr: "10.0.0.2:5000", proto: "https", want: true},
+		{name: "trusted proxy says http", remoteAddr: "10.0.0.2:5000", proto: "http", want: false},
+		{name: "untrusted client says https", remoteAddr: "203.0.113.7:5000", proto: "https", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if got := IsSecureCookie(req); got != tt.want {
+				t.Errorf("IsSecureCookie() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSecureCookie_DirectIgnoresForwardedProto(t *testing.T) {
+	if err := SetClientIPStrategy(XFFDirect, []string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetClientIPStrategy(XFFRightmostTrusted, nil) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if IsSecureCookie(req) {
+		t.Error("IsSecureCookie() = true for X-Forwarded-Proto with the direct strategy")
+	}
+}
+
+// A spoofed X-Forwarded-Proto must not switch an untrusted client onto the __Host- cookie.
+func TestSessionCookieName_IgnoresUntrustedForwardedProto(t *testing.T) {
+	sessionHostPrefix = true
+	t.Cleanup(func() { sessionHostPrefix = false })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if got := sessionCookieName(req); got != sessionCookieBaseName {
+		t.Errorf("sessionCookieName() = %q, want %q", got, sessionCookieBaseName)
+	}
+}
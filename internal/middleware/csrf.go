@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path"
+)
+
+// CSRF token names: the cookie holding the visitor's token, the hidden form field and
+// the header HTMX and fetch requests send it in.
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFFieldName  = "_csrf"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// CSRFTokenContextKey is the context key for the current request's CSRF token.
+const CSRFTokenContextKey contextKey = "csrfToken"
+
+// csrfMultipartMemory matches the limit handlers parse multipart uploads with, so checking
+// the token doesn't change how much of an upload is held in memory.
+const csrfMultipartMemory = 10 << 20
+
+// csrfMaxMultipartBody caps how much of a multipart body is read looking for the form
+// field: the largest upload handlers accept plus room for the other fields. Anything
+// bigger must send the token in the header, so unauthenticated requests can't make the
+// server spool arbitrarily large uploads to disk.
+const csrfMaxMultipartBody = csrfMultipartMemory + 1<<20
+
+// CSRF protects state-changing requests with a double-submit token. Each visitor gets a
+// random token in a cookie, replaced whenever a session starts or ends; unsafe requests
+// must echo it in the _csrf form field or the X-CSRF-Token header, which a cross-site
+// page can't read and so can't forge. Rotating on sign-in means a token planted or read
+// before the session existed, for example through a sibling subdomain, is useless after.
+//
+// Handlers get the token with CSRFToken(ctx); templ forms include it with
+// components.CSRFField(). The layouts also add the header to every HTMX request and the
+// field to any POST form missing one, so most pages need nothing extra.
+type CSRF struct {
+	exempt []string
+}
+
+// NewCSRF creates the CSRF middleware. exempt lists path patterns, in path.Match syntax
+// such as "/auth/*/callback", whose requests are never checked.
+func NewCSRF(exempt ...string) *CSRF {
+	return &CSRF{exempt: exempt}
+}
+
+// Handler issues the token cookie when missing, puts the token in the request context and
+// rejects unsafe requests whose token doesn't match with 403 Forbidden.
+func (c *CSRF) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := csrfCookieValue(r)
+		if token == "" {
+			var err error
+			if token, err = newCSRFToken(); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			http.SetCookie(w, newCSRFCookie(r, token))
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), CSRFTokenContextKey, &csrfTokenHolder{token: token}))
+
+		if !isSafeMethod(r.Method) && !c.isExempt(r.URL.Path) && !validCSRFToken(w, r, token) {
+			csrfFailure(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfTokenHolder is what the middleware stores in the request context. It's a pointer so
+// RotateCSRFToken can swap the token for the rest of the request.
+type csrfTokenHolder struct {
+	token string
+}
+
+// CSRFToken returns the CSRF token for the current request, or "" outside the middleware.
+func CSRFToken(ctx context.Context) string {
+	if h, ok := ctx.Value(CSRFTokenContextKey).(*csrfTokenHolder); ok {
+		return h.token
+	}
+	return ""
+}
+
+// RotateCSRFToken replaces the visitor's CSRF token with a fresh one, both in the cookie
+// and for anything still rendered in this request. SetSessionCookie and
+// ClearSessionCookie call it; the layout script reads the cookie on every request, so
+// pages already open keep working.
+func RotateCSRFToken(w http.ResponseWriter, r *http.Request) {
+	token, err := newCSRFToken()
+	if err != nil {
+		// Keeping the old token is safer than leaving the visitor without one
+		return
+	}
+	http.SetCookie(w, newCSRFCookie(r, token))
+	if h, ok := r.Context().Value(CSRFTokenContextKey).(*csrfTokenHolder); ok && h.token != "" {
+		h.token = token
+	}
+}
+
+// withoutCSRFToken hides the token from what's rendered with ctx, for pages shared
+// between visitors.
+func withoutCSRFToken(ctx context.Context) context.Context {
+	return context.WithValue(ctx, CSRFTokenContextKey, &csrfTokenHolder{})
+}
+
+func (c *CSRF) isExempt(p string) bool {
+	for _, pattern := range c.exempt {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// validCSRFToken checks the header first, then the form field for form posts. Other
+// bodies, such as JSON, are left unread and need the header. Multipart bodies are read
+// only up to csrfMaxMultipartBody.
+func validCSRFToken(w http.ResponseWriter, r *http.Request, token string) bool {
+	sent := r.Header.Get(CSRFHeaderName)
+	if sent == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			sent = r.PostFormValue(CSRFFieldName)
+		case "multipart/form-data":
+			r.Body = http.MaxBytesReader(w, r.Body, csrfMaxMultipartBody)
+			if err := r.ParseMultipartForm(csrfMultipartMemory); err == nil {
+				sent = r.PostFormValue(CSRFFieldName)
+			}
+		}
+	}
+	return sent != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1
+}
+
+// csrfFailure writes the 403 response. HTMX requests also get an error-toast trigger,
+// since HTMX doesn't swap error responses into the page by default.
+func csrfFailure(w http.ResponseWriter, r *http.Request) {
+	const msg = "Your session has expired. Please reload the page and try again."
+	if r.Header.Get("HX-Request") == "true" {
+		trigger, _ := json.Marshal(map[string]string{"error-toast": msg})
+		w.Header().Set("HX-Trigger", string(trigger))
+	}
+	http.Error(w, msg, http.StatusForbidden)
+}
+
+func newCSRFCookie(r *http.Request, token string) *http.Cookie {
+	return &http.Cookie{
+		Name:  CSRFCookieName,
+		Value: token,
+		Path:  "/",
+		// Readable by the layout script, which copies it into HTMX headers and forms
+		HttpOnly: false,
+		Secure:   IsSecureCookie(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func csrfCookieValue(r *http.Request) string {
+	if c, err := r.Cookie(CSRFCookieName); err == nil && len(c.Value) == base64.RawURLEncoding.EncodedLen(32) {
+		return c.Value
+	}
+	return ""
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+)
+
+const testCSRFToken = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+// countingReader records how many bytes the server read from a request body.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func multipartUpload(t *testing.T, fileSize int64, token string) (io.Reader, string) {
+	t.Helper()
+	var head bytes.Buffer
+	mw := multipart.NewWriter(&head)
+	if _, err := mw.CreateFormFile("file", "upload.bin"); err != nil {
+		t.Fatal(err)
+	}
+	var tail bytes.Buffer
+	tail.WriteString("\r\n--" + mw.Boundary() + "\r\n")
+	tail.WriteString(`Content-Disposition: form-data; name="` + CSRFFieldName + `"` + "\r\n\r\n")
+	tail.WriteString(token + "\r\n--" + mw.Boundary() + "--\r\n")
+	body := io.MultiReader(&head, io.LimitReader(zeroReader{}, fileSize), &tail)
+	return body, mw.FormDataContentType()
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestCSRF_MultipartField(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileSize int64
+		want     int
+	}{
+		{name: "upload within the cap", fileSize: 1 << 10, want: http.StatusOK},
+		{name: "upload over the cap", fileSize: csrfMaxMultipartBody, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartUpload(t, tt.fileSize, testCSRFToken)
+			counted := &countingReader{r: body}
+			req := httptest.NewRequest(http.MethodPost, "/media", counted)
+			req.Header.Set("Content-Type", contentType)
+			req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: testCSRFToken})
+			rec := httptest.NewRecorder()
+
+			NewCSRF().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if limit := int64(csrfMaxMultipartBody + 64<<10); counted.n > limit {
+				t.Errorf("read %d bytes of the body, want at most %d", counted.n, limit)
+			}
+		})
+	}
+}
+
+func TestCSRF_HeaderSkipsBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/media", strings.NewReader("not a form"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	req.Header.Set(CSRFHeaderName, testCSRFToken)
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: testCSRFToken})
+	rec := httptest.NewRecorder()
+
+	NewCSRF().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestCSRF_RotatesWithSession(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(w http.ResponseWriter, r *http.Request)
+	}{
+		{name: "session started", change: func(w http.ResponseWriter, r *http.Request) {
+			SetSessionCookie(w, r, &domain.Session{ID: "session", ExpiresAt: time.Now().Add(time.Hour)})
+		}},
+		{name: "session ended", change: ClearSessionCookie},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/signin", nil)
+			req.Header.Set(CSRFHeaderName, testCSRFToken)
+			req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: testCSRFToken})
+			rec := httptest.NewRecorder()
+
+			var rendered string
+			NewCSRF().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.change(w, r)
+				rendered = CSRFToken(r.Context())
+			})).ServeHTTP(rec, req)
+
+			var issued string
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == CSRFCookieName {
+					issued = c.Value
+				}
+			}
+			if issued == "" || issued == testCSRFToken {
+				t.Fatalf("CSRF cookie = %q, want a fresh token", issued)
+			}
+			if rendered != issued {
+				t.Errorf("CSRFToken() = %q after rotation, want the new cookie value %q", rendered, issued)
+			}
+		})
+	}
+}
+
+func TestRenewSessionCookie_KeepsCSRFToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	renewSessionCookie(rec, req, "session", time.Now().Add(time.Hour))
+
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CSRFCookieName {
+			t.Errorf("renewing the session set the CSRF cookie to %q", c.Value)
+		}
+	}
+}
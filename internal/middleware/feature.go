@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+)
+
+// FeatureGate blocks routes whose feature flag is turned off, so operators can
+// disable a whole subsystem from the admin UI.
+type FeatureGate struct {
+	featureService service.FeatureService
+	disabled       http.Handler
+}
+
+// NewFeatureGate creates a gate that checks flags with featureService and serves
+// disabled when a required feature is off.
+func NewFeatureGate(featureService service.FeatureService, disabled http.Handler) *FeatureGate {
+	return &FeatureGate{featureService: featureService, disabled: disabled}
+}
+
+// FeatureGateContextKey is the key for storing the feature gate in context.
+const FeatureGateContextKey contextKey = "featureGate"
+
+// Handler makes the gate available to FeatureEnabled for the rest of the request,
+// so templates can hide links to disabled features.
+func (g *FeatureGate) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), FeatureGateContextKey, g)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FeatureEnabled reports whether the named feature is on, using the gate stored in ctx.
+// It reports false when no gate is present or the lookup fails.
+func FeatureEnabled(ctx context.Context, name string) bool {
+	g, ok := ctx.Value(FeatureGateContextKey).(*FeatureGate)
+	if !ok {
+		return false
+	}
+	enabled, err := g.featureService.IsEnabled(ctx, name)
+	return err == nil && enabled
+}
+
+// RequireFeature returns middleware that only calls the next handler while the named
+// feature is enabled. A failed flag lookup is treated as disabled.
+func (g *FeatureGate) RequireFeature(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enabled, err := g.featureService.IsEnabled(r.Context(), name)
+			if err != nil {
+				log.Printf("Feature flag check failed for %s: %v", name, err)
+			}
+			if !enabled {
+				g.disabled.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
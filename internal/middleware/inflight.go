@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// inFlight counts requests currently being served.
+var inFlight atomic.Int64
+
+// InFlight returns the number of requests currently being served.
+func InFlight() int64 {
+	return inFlight.Load()
+}
+
+// TrackInFlight counts requests for the lifetime of the handler call, so shutdown
+// can report how many were still running when its timeout expired.
+func TrackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
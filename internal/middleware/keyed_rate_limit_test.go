@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Email addresses limited by a keyed limiter must never appear in the IP list of an
+// IP limiter sharing its store.
+func TestKeyedRateLimiter_KeysStayOutOfIPSnapshot(t *testing.T) {
+	store := NewMemoryRateLimitStore(DefaultRateLimitIdleTTL)
+	ips := NewIPRateLimiterWithStore(rate.Every(2*time.Second), 5, store)
+	ips.SetKeyPrefix("auth:")
+	emails := NewKeyedRateLimiter(rate.Every(20*time.Minute), 3, store, "email:", EmailKey("email"))
+
+	ips.Allow(context.Background(), "203.0.113.7")
+	form := url.Values{"email": {"User@Example.com"}}
+	req := httptest.NewRequest(http.MethodPost, "/forgot-password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if !emails.Allow(req) {
+		t.Fatal("first request for the address was limited")
+	}
+
+	entries, err := ips.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshot() = %v", err)
+	}
+	if len(entries) != 1 || entries[0].IP != "203.0.113.7" {
+		t.Errorf("Snapshot() = %+v, want only 203.0.113.7", entries)
+	}
+}
+
+func TestNewKeyedRateLimiter_RequiresPrefix(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewKeyedRateLimiter with an empty prefix did not panic")
+		}
+	}()
+	NewKeyedRateLimiter(1, 1, NewMemoryRateLimitStore(0), "", EmailKey("email"))
+}
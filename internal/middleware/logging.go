@@ -4,21 +4,60 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
-// responseWriter wraps http.ResponseWriter to capture the status code.
+// responseWriter wraps http.ResponseWriter to capture the status code and response size.
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	bytes       int
+	wroteHeader bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Logging logs HTTP requests with timing information.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController (flushing, deadlines).
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// quietPaths are high-frequency endpoints (probes, polling, assets) whose successful
+// requests are not logged to avoid drowning out everything else. Errors are always logged.
+var quietPaths = []string{
+	"/health",
+	"/ready",
+	"/assets/",
+	"/s/system/metrics",
+}
+
+func isQuietPath(path string) bool {
+	for _, p := range quietPaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Logging logs HTTP requests with status, response size, duration and client IP.
+// If an upstream proxy supplied an X-Request-ID header it is included for correlation.
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -30,12 +69,24 @@ func Logging(next http.Handler) http.Handler {
 
 		next.ServeHTTP(wrapped, r)
 
+		if wrapped.statusCode < http.StatusBadRequest && isQuietPath(r.URL.Path) {
+			return
+		}
+
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = "-"
+		}
+
 		log.Printf(
-			"%s %s %d %s",
+			"%s %s %d %dB %s ip=%s request_id=%s",
 			r.Method,
 			r.URL.Path,
 			wrapped.statusCode,
+			wrapped.bytes,
 			time.Since(start),
+			ClientIP(r),
+			requestID,
 		)
 	})
 }
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math/bits"
+	"net/netip"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// loginFrictionBaseDelay is the delay after the first failure past the threshold; each
+// further failure doubles it, up to the configured maximum.
+const loginFrictionBaseDelay = time.Second
+
+// LoginFriction slows password guessing from one IP without locking it out, since many
+// legitimate users can share an address behind NAT. Failed sign-ins are counted in a
+// token bucket in the rate limit store. Once an IP has failed `after` times within the
+// window, each attempt must first pass a CAPTCHA, when one is configured, or wait a delay
+// that doubles with every further failure. The count drains over the window, so friction
+// fades on its own.
+type LoginFriction struct {
+	store    RateLimitStore
+	r        rate.Limit
+	b        int
+	after    int
+	maxDelay time.Duration
+
+	mu        sync.RWMutex
+	allowlist []netip.Prefix
+}
+
+// NewLoginFriction creates a tracker that adds friction after `after` failures from an
+// IP within window, with delays capped at maxDelay. It returns nil, which adds no
+// friction, when after is not positive.
+func NewLoginFriction(store RateLimitStore, after int, window, maxDelay time.Duration) *LoginFriction {
+	if after <= 0 || window <= 0 {
+		return nil
+	}
+	maxDelay = max(maxDelay, loginFrictionBaseDelay)
+
+	// Room in the bucket for the failures before friction, plus one per doubling of the
+	// delay, so the bucket is empty once the delay reaches its cap.
+	steps := bits.Len64(uint64(maxDelay/loginFrictionBaseDelay)) + 1
+	return &LoginFriction{
+		store:    store,
+		r:        rate.Limit(float64(after) / window.Seconds()),
+		b:        after + steps,
+		after:    after,
+		maxDelay: maxDelay,
+	}
+}
+
+// SetAllowlist configures IPs or CIDR ranges that never get friction, like the rate limiters' allowlist.
+func (f *LoginFriction) SetAllowlist(entries []string) error {
+	if f == nil {
+		return nil
+	}
+	prefixes, err := parsePrefixes(entries)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.allowlist = prefixes
+	f.mu.Unlock()
+	return nil
+}
+
+// Check reports whether the next sign-in from ip needs friction and, if so, how long to
+// delay it when no CAPTCHA is configured. Store errors fail open.
+func (f *LoginFriction) Check(ctx context.Context, ip string) (bool, time.Duration) {
+	if f == nil || f.isAllowlisted(ip) {
+		return false, 0
+	}
+
+	tokens, err := f.store.Tokens(ctx, f.key(ip), f.r, f.b)
+	if err != nil {
+		log.Printf("Rate limit store error for %s: %v", ip, err)
+		return false, 0
+	}
+
+	failures := f.b - int(tokens)
+	if failures < f.after {
+		return false, 0
+	}
+	excess := min(failures-f.after, 30)
+	return true, min(loginFrictionBaseDelay<<excess, f.maxDelay)
+}
+
+// RecordFailure counts a failed sign-in from ip.
+func (f *LoginFriction) RecordFailure(ctx context.Context, ip string) {
+	if f == nil || f.isAllowlisted(ip) {
+		return
+	}
+	if _, _, err := f.store.Take(ctx, f.key(ip), f.r, f.b); err != nil {
+		log.Printf("Rate limit store error for %s: %v", ip, err)
+	}
+}
+
+func (f *LoginFriction) key(ip string) string {
+	return "login-failed:" + ip
+}
+
+func (f *LoginFriction) isAllowlisted(ip string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if len(f.allowlist) == 0 {
+		return false
+	}
+	addr, ok := parseClientIP(ip)
+	if !ok {
+		return false
+	}
+	for _, prefix := range f.allowlist {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
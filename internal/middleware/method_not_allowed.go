@@ -0,0 +1,46 @@
+// Package middleware provides HTTP middleware functions.
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// probeMethods are the methods checked when working out which ones a path supports.
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// AllowedMethods returns the methods that some route other than the catch-all "/" accepts for r's path.
+func AllowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	var allowed []string
+	for _, method := range probeMethods {
+		probe := *r
+		probe.Method = method
+		if _, pattern := mux.Handler(&probe); pattern != "" && pattern != "/" {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// MethodNotAllowed wraps the catch-all handler. Because "/" matches every method, the mux
+// never produces its own 405; instead, when another route serves this path with a different
+// method, the Allow header is set and notAllowed renders the response.
+func MethodNotAllowed(mux *http.ServeMux, notAllowed http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if allowed := AllowedMethods(mux, r); len(allowed) > 0 {
+				w.Header().Set("Allow", strings.Join(allowed, ", "))
+				notAllowed.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PageCache keeps rendered pages for anonymous visitors for a short time, so popular
+// public pages aren't re-rendered on every hit. Requests with a signed-in user in context
+// always go straight to the handler, and only complete 200 responses that set no cookies
+// and aren't marked private are stored.
+type PageCache struct {
+	maxEntries int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+}
+
+type cachedPage struct {
+	key     string
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// NewPageCache creates a cache holding at most maxEntries pages for ttl each.
+// A cache with maxEntries or ttl of zero stores nothing.
+func NewPageCache(maxEntries int, ttl time.Duration) *PageCache {
+	return &PageCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Purge drops every cached page, e.g. after a blog post is published or edited.
+func (c *PageCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Middleware serves anonymous GET requests from the cache, rendering and storing the
+// page on a miss.
+func (c *PageCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.maxEntries <= 0 || c.ttl <= 0 || r.Method != http.MethodGet || GetUserFromContext(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := pageCacheKey(r)
+		if page, ok := c.get(key); ok {
+			page.serve(w, r)
+			return
+		}
+
+		// Render without the visitor's CSRF token, so a cached page never hands one
+		// visitor's token to another. The layout script adds it to forms from the cookie.
+		rec := &pageRecorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(withoutCSRFToken(r.Context())))
+
+		page := &cachedPage{key: key, header: rec.header, body: rec.body.Bytes(), expires: time.Now().Add(c.ttl)}
+		if rec.status == http.StatusOK && cacheable(rec.header) {
+			c.put(page)
+		}
+
+		copyHeader(w.Header(), rec.header)
+		w.WriteHeader(rec.status)
+		w.Write(page.body)
+	})
+}
+
+// pageCacheKey identifies a rendering of a page: its URL plus the inputs the handlers
+// use to pick a theme, and whether HTMX asked for it.
+func pageCacheKey(r *http.Request) string {
+	theme := r.Header.Get("Sec-CH-Prefers-Color-Scheme")
+	if c, err := r.Cookie("theme"); err == nil && c.Value != "" {
+		theme = "cookie:" + c.Value
+	}
+	return strings.Join([]string{r.URL.RequestURI(), theme, r.Header.Get("HX-Request")}, "\x00")
+}
+
+// cacheable reports whether a response may be shared between anonymous visitors.
+func cacheable(h http.Header) bool {
+	if len(h.Values("Set-Cookie")) > 0 {
+		return false
+	}
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return !strings.Contains(cc, "private") && !strings.Contains(cc, "no-store")
+}
+
+func (c *PageCache) get(key string) (*cachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	page := el.Value.(*cachedPage)
+	if time.Now().After(page.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return page, true
+}
+
+func (c *PageCache) put(page *cachedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[page.key]; ok {
+		el.Value = page
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[page.key] = c.lru.PushFront(page)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedPage).key)
+	}
+}
+
+// serve writes the cached page, or a 304 when the client already has it.
+func (p *cachedPage) serve(w http.ResponseWriter, r *http.Request) {
+	copyHeader(w.Header(), p.header)
+	if etag := p.header.Get("ETag"); etag != "" && ifNoneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(p.body)
+}
+
+// ifNoneMatch reports whether an If-None-Match header lists etag, comparing weakly.
+func ifNoneMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// copyHeader copies src into dst, keeping cookies already set on dst, such as the
+// CSRF cookie issued before the page was rendered.
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		if k == "Set-Cookie" {
+			dst[k] = append(dst[k], v...)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// pageRecorder buffers a handler's response so it can be stored before being sent.
+type pageRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *pageRecorder) Header() http.Header { return r.header }
+
+func (r *pageRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
+
+func (r *pageRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
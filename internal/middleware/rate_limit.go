@@ -1,91 +1,390 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"math"
 	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"golang.org/x/time/rate"
 )
 
+// LimiterEntry is a point-in-time view of a tracked IP.
+type LimiterEntry = domain.RateLimitEntry
+
+// RateLimitStore holds the token buckets behind an IPRateLimiter. The in-memory store
+// suits a single instance; a shared store (e.g. postgres.RateLimitRepository) keeps
+// limits consistent across several instances.
+type RateLimitStore interface {
+	// Take consumes one token from key's bucket, reporting false if it is empty,
+	// along with the tokens left in the bucket afterwards.
+	Take(ctx context.Context, key string, r rate.Limit, b int) (allowed bool, remaining float64, err error)
+	// Tokens reports the tokens in key's bucket without consuming any. Unknown keys have a full bucket.
+	Tokens(ctx context.Context, key string, r rate.Limit, b int) (float64, error)
+	// Snapshot returns the current state of every tracked key starting with prefix, most
+	// recently seen first, with the prefix removed.
+	Snapshot(ctx context.Context, prefix string, r rate.Limit, b int) ([]LimiterEntry, error)
+	// Cleanup forgets keys that are no longer needed.
+	Cleanup(ctx context.Context) error
+}
+
 // IPRateLimiter manages rate limiters for each IP address.
 type IPRateLimiter struct {
-	ips map[string]*rate.Limiter
-	mu  *sync.RWMutex
-	r   rate.Limit
-	b   int
+	store     RateLimitStore
+	mu        *sync.RWMutex
+	r         rate.Limit
+	b         int
+	allowlist []netip.Prefix
+	prefix    string
 }
 
-// NewIPRateLimiter creates a new rate limiter that allows events up to rate r and permits bursts of at most b tokens.
+// NewIPRateLimiter creates a new in-memory rate limiter that allows events up to rate r and permits bursts of at most b tokens.
 func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
-	i := &IPRateLimiter{
-		ips: make(map[string]*rate.Limiter),
-		mu:  &sync.RWMutex{},
-		r:   r,
-		b:   b,
+	store := NewMemoryRateLimitStore(DefaultRateLimitIdleTTL)
+	go RunRateLimitCleanup(context.Background(), store)
+	return NewIPRateLimiterWithStore(r, b, store)
+}
+
+// NewIPRateLimiterWithStore creates a rate limiter whose buckets are kept in store. The
+// store is shared and cleaned up by its owner with RunRateLimitCleanup, not by the limiter.
+func NewIPRateLimiterWithStore(r rate.Limit, b int, store RateLimitStore) *IPRateLimiter {
+	return &IPRateLimiter{
+		store: store,
+		mu:    &sync.RWMutex{},
+		r:     r,
+		b:     b,
 	}
+}
 
-	// Start a background goroutine to cleanup old entries every 5 minutes
-	go i.cleanupLoop()
+// Allow consumes a token for key. Store errors fail open, so an unavailable
+// shared store degrades to no limiting rather than rejecting every request.
+func (i *IPRateLimiter) Allow(ctx context.Context, key string) bool {
+	allowed, _ := i.take(ctx, key)
+	return allowed
+}
+
+// take is Allow that also returns the tokens left in key's bucket. When the store
+// fails the bucket is reported as full, matching the fail-open behaviour.
+func (i *IPRateLimiter) take(ctx context.Context, key string) (bool, float64) {
+	allowed, remaining, err := i.store.Take(ctx, i.prefix+key, i.r, i.b)
+	if err != nil {
+		log.Printf("Rate limit store error for %s: %v", key, err)
+		return true, float64(i.b)
+	}
+	return allowed, remaining
+}
+
+// Blocked reports whether key's bucket is empty, without consuming a token. Like Allow,
+// it fails open when the store errors.
+func (i *IPRateLimiter) Blocked(ctx context.Context, key string) bool {
+	tokens, err := i.store.Tokens(ctx, i.prefix+key, i.r, i.b)
+	if err != nil {
+		log.Printf("Rate limit store error for %s: %v", key, err)
+		return false
+	}
+	return tokens < 1
+}
+
+// SetKeyPrefix namespaces this limiter's keys, so several limiters with different
+// rates can share one store without draining each other's buckets, and Snapshot lists
+// only this limiter's keys. Limiters sharing a store need distinct prefixes.
+func (i *IPRateLimiter) SetKeyPrefix(prefix string) {
+	i.prefix = prefix
+}
+
+// setHeaders describes the caller's budget: the burst size, the whole requests
+// still available, and the seconds until the bucket has fully refilled.
+func (i *IPRateLimiter) setHeaders(w http.ResponseWriter, remaining float64) {
+	remaining = max(remaining, 0)
+	reset := 0
+	if i.r > 0 && remaining < float64(i.b) {
+		reset = int(math.Ceil((float64(i.b) - remaining) / float64(i.r)))
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(i.b))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(int(remaining)))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(reset))
+}
+
+// retryAfter returns the whole seconds until a bucket holding remaining tokens has a
+// token to spend again, for the Retry-After header.
+func (i *IPRateLimiter) retryAfter(remaining float64) int {
+	if i.r <= 0 {
+		// The bucket never refills; suggest a retry much later
+		return 3600
+	}
+	return max(1, int(math.Ceil((1-max(remaining, 0))/float64(i.r))))
+}
+
+// tooManyRequests writes the 429 response with a Retry-After header. HTMX requests get a
+// small HTML notice with the wait, plus an error-toast trigger, since HTMX doesn't swap
+// error responses into the page by default.
+func tooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter int) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	if r.Header.Get("HX-Request") != "true" {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
 
-	return i
+	msg := "Too many requests. Please try again in " + formatWait(retryAfter) + "."
+	trigger, _ := json.Marshal(map[string]string{"error-toast": msg})
+	w.Header().Set("HX-Trigger", string(trigger))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+	fmt.Fprintf(w, `<div class="alert alert-warning" role="alert" data-retry-after="%d"><span>%s</span></div>`, retryAfter, html.EscapeString(msg))
 }
 
-// AddIP creates a new rate limiter for an IP if one doesn't exist, or returns the existing one.
-func (i *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
+// formatWait describes a wait in seconds as e.g. "1 second", "45 seconds" or "3 minutes".
+func formatWait(seconds int) string {
+	if seconds < 60 {
+		if seconds == 1 {
+			return "1 second"
+		}
+		return strconv.Itoa(seconds) + " seconds"
+	}
+	minutes := (seconds + 59) / 60
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return strconv.Itoa(minutes) + " minutes"
+}
+
+// SetAllowlist configures CIDR ranges (e.g. "10.0.0.0/8") or single IPs that bypass limiting.
+// Matching uses the client IP from ClientIP, so with the leftmost X-Forwarded-For strategy
+// only use an allowlist behind a proxy that overwrites that header, or it can be spoofed.
+func (i *IPRateLimiter) SetAllowlist(entries []string) error {
+	prefixes, err := parsePrefixes(entries)
+	if err != nil {
+		return err
+	}
+
 	i.mu.Lock()
-	defer i.mu.Unlock()
+	i.allowlist = prefixes
+	i.mu.Unlock()
 
-	limiter, exists := i.ips[ip]
-	if !exists {
-		limiter = rate.NewLimiter(i.r, i.b)
-		i.ips[ip] = limiter
+	return nil
+}
+
+// isAllowlisted reports whether the client IP matches an allowlisted range.
+func (i *IPRateLimiter) isAllowlisted(ip string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if len(i.allowlist) == 0 {
+		return false
+	}
+
+	addr, ok := parseClientIP(ip)
+	if !ok {
+		return false
+	}
+
+	for _, prefix := range i.allowlist {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClientIP extracts an address from "ip", "ip:port" or an X-Forwarded-For list (first entry).
+func parseClientIP(s string) (netip.Addr, bool) {
+	if idx := strings.Index(s, ","); idx != -1 {
+		s = s[:idx]
+	}
+	s = strings.TrimSpace(s)
+
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
 	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
 
-	return limiter
+// Snapshot returns the current state of every IP tracked by this limiter, most recently
+// seen first. Keys of other limiters sharing the store are left out. It is safe to call
+// concurrently with request handling.
+func (i *IPRateLimiter) Snapshot(ctx context.Context) ([]LimiterEntry, error) {
+	return i.store.Snapshot(ctx, i.prefix, i.r, i.b)
 }
 
-// cleanupLoop periodically removes unused limiters to prevent memory leaks.
-// Note: This is a simple implementation. For production, track last access time per IP.
-func (i *IPRateLimiter) cleanupLoop() {
-	ticker := time.NewTicker(5 * time.Minute)
-	for range ticker.C {
-		// In a real implementation we would check last access time.
-		// For now, we'll just clear the map if it gets too large to prevent leaks in this starter
-		i.mu.Lock()
-		if len(i.ips) > 10000 {
-			i.ips = make(map[string]*rate.Limiter)
+// rateLimitCleanupInterval is how often RunRateLimitCleanup asks a store to drop buckets.
+const rateLimitCleanupInterval = 5 * time.Minute
+
+// RunRateLimitCleanup periodically asks store to drop unused buckets, preventing unbounded
+// growth, until ctx is done. Run it once per store, however many limiters share it.
+func RunRateLimitCleanup(ctx context.Context, store RateLimitStore) {
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cleanupCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		if err := store.Cleanup(cleanupCtx); err != nil {
+			log.Printf("Rate limit store cleanup failed: %v", err)
 		}
-		i.mu.Unlock()
+		cancel()
 	}
 }
 
-// RateLimitMiddleware creates a middleware that limits requests by IP address.
-func RateLimitMiddleware(requestsPerSecond float64, burst int) func(http.Handler) http.Handler {
-	limiter := NewIPRateLimiter(rate.Limit(requestsPerSecond), burst)
+// DefaultRateLimitIdleTTL is how long an in-memory bucket must go unused before cleanup may drop it.
+const DefaultRateLimitIdleTTL = 15 * time.Minute
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := getIPAddress(r)
-			if ip == "" {
-				ip = r.RemoteAddr
-			}
+// memoryRateLimitStore keeps a rate.Limiter per key in process memory.
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	ips     map[string]*visitor
+	idleTTL time.Duration
+	now     func() time.Time // the clock, replaceable in tests
+}
 
-			if !limiter.GetLimiter(ip).Allow() {
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
-				return
-			}
+// visitor is a tracked IP's limiter and when it last made a request.
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
 
-			next.ServeHTTP(w, r)
+// NewMemoryRateLimitStore creates an in-memory store, the default for single-instance deployments.
+// Buckets unused for idleTTL (DefaultRateLimitIdleTTL if not positive) are dropped by Cleanup.
+func NewMemoryRateLimitStore(idleTTL time.Duration) RateLimitStore {
+	if idleTTL <= 0 {
+		idleTTL = DefaultRateLimitIdleTTL
+	}
+	return &memoryRateLimitStore{ips: make(map[string]*visitor), idleTTL: idleTTL, now: time.Now}
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, r rate.Limit, b int) (bool, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, exists := s.ips[key]
+	if !exists {
+		v = &visitor{limiter: rate.NewLimiter(r, b)}
+		s.ips[key] = v
+	}
+	now := s.now()
+	v.lastSeen = now
+
+	allowed := v.limiter.AllowN(now, 1)
+	return allowed, v.limiter.TokensAt(now), nil
+}
+
+func (s *memoryRateLimitStore) Tokens(_ context.Context, key string, _ rate.Limit, b int) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, exists := s.ips[key]
+	if !exists {
+		return float64(b), nil
+	}
+	return v.limiter.TokensAt(s.now()), nil
+}
+
+func (s *memoryRateLimitStore) Snapshot(_ context.Context, prefix string, _ rate.Limit, b int) ([]LimiterEntry, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	entries := make([]LimiterEntry, 0, len(s.ips))
+	for key, v := range s.ips {
+		ip, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		entries = append(entries, LimiterEntry{
+			IP:       ip,
+			Tokens:   v.limiter.TokensAt(now),
+			Burst:    b,
+			LastSeen: v.lastSeen,
 		})
 	}
+	s.mu.Unlock()
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].LastSeen.After(entries[b].LastSeen)
+	})
+
+	return entries, nil
 }
 
-// Helper to get IP (duplicate of logic in auth handler, maybe move to utils later)
-func getIPAddress(r *http.Request) string {
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return forwarded
+// Cleanup drops buckets idle for longer than the store's idle TTL. A bucket that hasn't
+// refilled yet is kept, since dropping it would hand a throttled client a fresh burst.
+func (s *memoryRateLimitStore) Cleanup(context.Context) error {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, v := range s.ips {
+		if now.Sub(v.lastSeen) > s.idleTTL && v.limiter.TokensAt(now) >= float64(v.limiter.Burst()) {
+			delete(s.ips, key)
+		}
 	}
-	return r.RemoteAddr
+	return nil
+}
+
+// Middleware limits requests by IP address using this limiter. Every limited response,
+// throttled or not, carries X-RateLimit-* headers so clients can pace themselves.
+func (i *IPRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r)
+
+		if i.isAllowlisted(ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, remaining := i.take(r.Context(), ip)
+		i.setHeaders(w, remaining)
+		if !allowed {
+			tooManyRequests(w, r, i.retryAfter(remaining))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GlobalMiddleware limits requests to next as a whole, with every client drawing from
+// one shared bucket. It caps the total rate of an endpoint that a distributed attacker
+// could otherwise hit from many IPs. Allowlisted IPs bypass it.
+func (i *IPRateLimiter) GlobalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r)
+
+		if i.isAllowlisted(ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed, remaining := i.take(r.Context(), "global"); !allowed {
+			tooManyRequests(w, r, i.retryAfter(remaining))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimitMiddleware creates a middleware that limits requests by IP address.
+func RateLimitMiddleware(requestsPerSecond float64, burst int) func(http.Handler) http.Handler {
+	return NewIPRateLimiter(rate.Limit(requestsPerSecond), burst).Middleware
 }
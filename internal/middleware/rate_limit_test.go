@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limitedServer is next wrapped in mw, for sending requests through a limiter.
+func limitedServer(mw func(http.Handler) http.Handler) http.Handler {
+	return mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestIPRateLimiter_RetryAfter(t *testing.T) {
+	// One request per 30 seconds: the second must wait the full interval
+	limiter := NewIPRateLimiter(rate.Every(30*time.Second), 1)
+
+	for name, mw := range map[string]func(http.Handler) http.Handler{
+		"per IP": limiter.Middleware,
+		"global": limiter.GlobalMiddleware,
+	} {
+		t.Run(name, func(t *testing.T) {
+			limiter.SetKeyPrefix(name + ":")
+			srv := limitedServer(mw)
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("Retry-After"); got != "" {
+				t.Errorf("allowed request has Retry-After %q", got)
+			}
+
+			rec = httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("second request status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+			}
+			header := rec.Header().Get("Retry-After")
+			seconds, err := strconv.Atoi(header)
+			if err != nil {
+				t.Fatalf("Retry-After = %q, want whole seconds", header)
+			}
+			if seconds < 1 || seconds > 30 {
+				t.Errorf("Retry-After = %d, want between 1 and 30", seconds)
+			}
+		})
+	}
+}
+
+func TestIPRateLimiter_TooManyRequestsHTMX(t *testing.T) {
+	limiter := NewIPRateLimiter(rate.Every(time.Minute), 1)
+	srv := limitedServer(limiter.Middleware)
+
+	var rec *httptest.ResponseRecorder
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/signin", nil)
+		req.Header.Set("HX-Request", "true")
+		rec = httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if _, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil {
+		t.Errorf("Retry-After = %q, want whole seconds", rec.Header().Get("Retry-After"))
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", got)
+	}
+	if !strings.Contains(rec.Header().Get("HX-Trigger"), "error-toast") {
+		t.Errorf("HX-Trigger = %q, want an error-toast", rec.Header().Get("HX-Trigger"))
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `role="alert"`) || !strings.Contains(body, "1 minute") {
+		t.Errorf("body = %q, want an alert with the wait", body)
+	}
+}
+
+func TestFormatWait(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{1, "1 second"},
+		{45, "45 seconds"},
+		{60, "1 minute"},
+		{61, "2 minutes"},
+		{180, "3 minutes"},
+	}
+	for _, tt := range tests {
+		if got := formatWait(tt.seconds); got != tt.want {
+			t.Errorf("formatWait(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryRateLimitStore_CleanupEvictsOnlyIdleBuckets(t *testing.T) {
+	store := NewMemoryRateLimitStore(15 * time.Minute).(*memoryRateLimitStore)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	take := func(key string, r rate.Limit, b int) {
+		t.Helper()
+		if _, _, err := store.Take(ctx, key, r, b); err != nil {
+			t.Fatalf("Take(%s) = %v", key, err)
+		}
+	}
+	tracked := func(key string) bool {
+		_, ok := store.ips[key]
+		return ok
+	}
+
+	fast := rate.Every(time.Second)
+	slow := rate.Every(time.Hour)
+	take("idle", fast, 5)
+	take("throttled", slow, 1) // empties a bucket that takes an hour to refill
+	take("active", fast, 5)
+
+	now = now.Add(20 * time.Minute)
+	take("active", fast, 5)
+	if err := store.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup() = %v", err)
+	}
+	if tracked("idle") {
+		t.Error("idle, refilled bucket was kept")
+	}
+	if !tracked("throttled") {
+		t.Error("idle bucket that hasn't refilled was dropped, handing its client a fresh burst")
+	}
+	if !tracked("active") {
+		t.Error("recently used bucket was dropped")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := store.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup() = %v", err)
+	}
+	if len(store.ips) != 0 {
+		t.Errorf("%d buckets left after every bucket went idle and refilled", len(store.ips))
+	}
+}
+
+func TestIPRateLimiter_SnapshotListsOnlyItsOwnKeys(t *testing.T) {
+	store := NewMemoryRateLimitStore(DefaultRateLimitIdleTTL)
+	auth := NewIPRateLimiterWithStore(rate.Every(2*time.Second), 5, store)
+	auth.SetKeyPrefix("auth:")
+	reset := NewIPRateLimiterWithStore(rate.Every(30*time.Second), 3, store)
+	reset.SetKeyPrefix("reset:")
+	ctx := context.Background()
+
+	auth.Allow(ctx, "203.0.113.7")
+	auth.Allow(ctx, "2001:db8::1")
+	reset.Allow(ctx, "198.51.100.4")
+	reset.Allow(ctx, "198.51.100.4")
+
+	entries, err := auth.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() = %v", err)
+	}
+	got := map[string]LimiterEntry{}
+	for _, e := range entries {
+		got[e.IP] = e
+	}
+	if len(got) != 2 || got["203.0.113.7"].Burst != 5 || got["2001:db8::1"].Burst != 5 {
+		t.Errorf("auth Snapshot() = %+v, want its two IPs with burst 5", entries)
+	}
+
+	entries, err = reset.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() = %v", err)
+	}
+	if len(entries) != 1 || entries[0].IP != "198.51.100.4" || entries[0].Burst != 3 {
+		t.Fatalf("reset Snapshot() = %+v, want 198.51.100.4 with burst 3", entries)
+	}
+	if entries[0].Tokens >= 2 {
+		t.Errorf("reset bucket has %.2f tokens after two requests, want under 2", entries[0].Tokens)
+	}
+}
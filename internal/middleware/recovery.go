@@ -5,18 +5,68 @@ import (
 	"log"
 	"net/http"
 	"runtime/debug"
+	"sync/atomic"
 )
 
-// Recovery recovers from panics and returns a 500 error.
-func Recovery(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("panic recovered: %v\n%s", err, debug.Stack())
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+// panicCount counts recovered panics since startup.
+var panicCount atomic.Int64
+
+// PanicCount returns the number of panics recovered since startup, for metrics.
+func PanicCount() int64 {
+	return panicCount.Load()
+}
+
+// Recovery recovers from panics, logs the request details and stack trace, and
+// responds with errorHandler (e.g. a themed 500 page). The stack trace is never
+// sent to the client. If errorHandler is nil a plain 500 is returned.
+// mux is used to resolve the matched route pattern for the log line.
+func Recovery(mux *http.ServeMux, errorHandler http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
 			}
-		}()
 
-		next.ServeHTTP(w, r)
-	})
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				// ErrAbortHandler is used to deliberately abort a response; let net/http handle it
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				panicCount.Add(1)
+
+				requestID := r.Header.Get("X-Request-ID")
+				if requestID == "" {
+					requestID = "-"
+				}
+
+				// Inner middleware may have replaced the request, so look the route up again
+				route := r.Pattern
+				if route == "" && mux != nil {
+					_, route = mux.Handler(r)
+				}
+
+				log.Printf("panic recovered: %v method=%s path=%s route=%q body_bytes=%d request_id=%s\n%s",
+					err, r.Method, r.URL.Path, route, r.ContentLength, requestID, debug.Stack())
+
+				// Too late to change the status if the handler already started responding
+				if wrapped.wroteHeader {
+					return
+				}
+
+				if errorHandler == nil {
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				errorHandler.ServeHTTP(w, r)
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
 }
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RouteStats aggregates request durations for one route pattern since startup.
+type RouteStats struct {
+	Route     string        `json:"route"`
+	Count     int64         `json:"count"`
+	SlowCount int64         `json:"slow_count"`
+	Total     time.Duration `json:"total_ns"`
+	Max       time.Duration `json:"max_ns"`
+}
+
+// Avg returns the mean request duration for the route.
+func (s RouteStats) Avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+var (
+	routeStatsMu sync.Mutex
+	routeStats   = make(map[string]*RouteStats)
+)
+
+// RouteMetricsSnapshot returns per-route stats, slowest (by max duration) first.
+func RouteMetricsSnapshot() []RouteStats {
+	routeStatsMu.Lock()
+	stats := make([]RouteStats, 0, len(routeStats))
+	for _, s := range routeStats {
+		stats = append(stats, *s)
+	}
+	routeStatsMu.Unlock()
+
+	sort.Slice(stats, func(a, b int) bool {
+		return stats[a].Max > stats[b].Max
+	})
+	return stats
+}
+
+// RouteMetrics records request durations per matched route pattern and logs any
+// request slower than slowThreshold with its route and status. Unmatched paths are
+// grouped together so arbitrary URLs can't grow the stats map. A zero threshold
+// keeps the stats but disables the slow-request log.
+func RouteMetrics(mux *http.ServeMux, slowThreshold time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r)
+
+			elapsed := time.Since(start)
+
+			// The mux sets Pattern on its own copy of the request, so look the route up here
+			_, route := mux.Handler(r)
+			if route == "" || route == "/" {
+				route = "unmatched"
+			}
+
+			slow := slowThreshold > 0 && elapsed > slowThreshold
+
+			routeStatsMu.Lock()
+			s, ok := routeStats[route]
+			if !ok {
+				s = &RouteStats{Route: route}
+				routeStats[route] = s
+			}
+			s.Count++
+			s.Total += elapsed
+			if elapsed > s.Max {
+				s.Max = elapsed
+			}
+			if slow {
+				s.SlowCount++
+			}
+			routeStatsMu.Unlock()
+
+			if slow {
+				log.Printf("slow request: %s %s route=%q status=%d duration=%s threshold=%s",
+					r.Method, r.URL.Path, route, wrapped.statusCode, elapsed, slowThreshold)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/service"
+)
+
+// twoFactorEnrollPath is where admins without two-factor authentication are sent.
+const twoFactorEnrollPath = "/u/settings/2fa/enable"
+
+// RequireAdminTwoFactor returns middleware that sends admins and super admins who haven't
+// turned on two-factor authentication to the enrollment page. Ordinary users pass through.
+// It only applies while the two-factor feature is on, since the enrollment page is
+// unavailable otherwise and every admin would be locked out. Place it inside RequireRole.
+func RequireAdminTwoFactor(twoFactorService service.TwoFactorService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUserFromContext(r.Context())
+			if user == nil || !user.HasPermission(domain.RoleAdmin) || !FeatureEnabled(r.Context(), domain.FeatureTwoFactor) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			status, err := twoFactorService.Status(r.Context(), user.ID)
+			if err != nil {
+				log.Printf("Failed to check two-factor status for admin %s: %v", user.ID, err)
+			}
+			if err == nil && status.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", twoFactorEnrollPath)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			http.Redirect(w, r, twoFactorEnrollPath, http.StatusSeeOther)
+		})
+	}
+}
@@ -28,3 +28,34 @@ func RequireVerifiedEmail(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Sensitive actions that can require a verified email via REQUIRE_VERIFIED_EMAIL_FOR.
+const (
+	VerifiedActionProfileUpdate  = "profile_update"
+	VerifiedActionPasswordChange = "password_change"
+	VerifiedActionMediaUpload    = "media_upload"
+	VerifiedActionBlogWrite      = "blog_write"
+)
+
+// VerifiedEmailGate applies RequireVerifiedEmail only to the actions an operator enabled.
+type VerifiedEmailGate struct {
+	actions map[string]bool
+}
+
+// NewVerifiedEmailGate creates a gate requiring verification for the given action names.
+func NewVerifiedEmailGate(actions []string) *VerifiedEmailGate {
+	g := &VerifiedEmailGate{actions: make(map[string]bool)}
+	for _, action := range actions {
+		g.actions[action] = true
+	}
+	return g
+}
+
+// For returns middleware enforcing a verified email for the action, or a pass-through
+// if the action is not configured. Place it inside RequireAuth.
+func (g *VerifiedEmailGate) For(action string) func(http.Handler) http.Handler {
+	if !g.actions[action] {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return RequireVerifiedEmail
+}
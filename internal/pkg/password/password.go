@@ -0,0 +1,186 @@
+// Package password provides pluggable password hashing.
+// Hashes carry their algorithm as a prefix ("$2a$" for bcrypt, "$argon2id$" for Argon2id),
+// so any hasher can verify hashes produced by the others.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// ErrUnknownAlgorithm is returned when a hash or configuration names an unsupported algorithm.
+var ErrUnknownAlgorithm = errors.New("unknown password hashing algorithm")
+
+// Hasher hashes and verifies passwords.
+type Hasher interface {
+	// Hash returns an algorithm-prefixed hash of the password.
+	Hash(password string) (string, error)
+
+	// Verify reports whether the password matches the hash, whichever supported algorithm produced it.
+	Verify(hash, password string) bool
+
+	// NeedsRehash reports whether the hash was produced by a different algorithm or weaker parameters.
+	NeedsRehash(hash string) bool
+}
+
+// New returns the hasher for the given algorithm name. An empty name selects bcrypt.
+// bcryptCost is only used by the bcrypt hasher; values outside bcrypt's range fall back to the default.
+func New(algorithm string, bcryptCost int) (Hasher, error) {
+	switch strings.ToLower(algorithm) {
+	case "", AlgorithmBcrypt:
+		return NewBcrypt(bcryptCost), nil
+	case AlgorithmArgon2id:
+		return NewArgon2id(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+// verify checks a password against a hash of any supported algorithm.
+func verify(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	default:
+		return false
+	}
+}
+
+// BcryptHasher hashes passwords with bcrypt.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcrypt creates a bcrypt hasher with the given cost.
+func NewBcrypt(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(bytes), err
+}
+
+func (h *BcryptHasher) Verify(hash, password string) bool {
+	return verify(hash, password)
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, "$2") {
+		return true
+	}
+
+	// Cost only parses the hash header, so this check is cheap on every login;
+	// the expensive rehash happens at most once per user after the cost is raised.
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost < h.cost
+}
+
+// Argon2idHasher hashes passwords with Argon2id using the RFC 9106 recommended parameters.
+type Argon2idHasher struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	keyLen  uint32
+	saltLen int
+}
+
+// NewArgon2id creates an Argon2id hasher.
+func NewArgon2id() *Argon2idHasher {
+	return &Argon2idHasher{
+		memory:  64 * 1024,
+		time:    3,
+		threads: 4,
+		keyLen:  32,
+		saltLen: 16,
+	}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.threads, h.keyLen)
+
+	// PHC string format, e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memory, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(hash, password string) bool {
+	return verify(hash, password)
+}
+
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params.memory < h.memory || params.time < h.time || params.threads < h.threads
+}
+
+func verifyArgon2id(hash, password string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func decodeArgon2id(hash string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, nil, nil, ErrUnknownAlgorithm
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, err
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,51 @@
+// Package startup tracks the tasks an instance must finish before it should receive
+// traffic, so readiness can report them rather than only whether the process is up.
+package startup
+
+import (
+	"log"
+	"slices"
+	"sync"
+)
+
+// Tracker records which startup steps are still pending. It is safe for concurrent use.
+type Tracker struct {
+	mu      sync.RWMutex
+	pending []string
+}
+
+// New creates a tracker waiting on the given steps.
+func New(steps ...string) *Tracker {
+	return &Tracker{pending: slices.Clone(steps)}
+}
+
+// Done marks step as complete, logging once every step has. Marking an unknown or already
+// completed step does nothing.
+func (t *Tracker) Done(step string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	i := slices.Index(t.pending, step)
+	if i < 0 {
+		return
+	}
+	t.pending = slices.Delete(t.pending, i, i+1)
+
+	if len(t.pending) == 0 {
+		log.Println("Startup complete; instance is ready")
+	}
+}
+
+// Pending returns the steps that have not completed yet.
+func (t *Tracker) Pending() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return slices.Clone(t.pending)
+}
+
+// Ready reports whether every step has completed.
+func (t *Tracker) Ready() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.pending) == 0
+}
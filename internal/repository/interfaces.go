@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
@@ -13,18 +14,34 @@ type UserRepository interface {
 	// Create inserts a new user into the database.
 	Create(ctx context.Context, user *domain.User) error
 
+	// CreateWithFirstUserRole inserts a new user, promoting them to super admin if no users exist yet.
+	// The check and insert are atomic, so concurrent first signups yield exactly one super admin.
+	CreateWithFirstUserRole(ctx context.Context, user *domain.User) error
+
 	// GetByID retrieves a user by their unique identifier.
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
 
 	// GetByEmail retrieves a user by their email address.
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
 
+	// GetByUsername retrieves a user by their username, ignoring case.
+	GetByUsername(ctx context.Context, username string) (*domain.User, error)
+
 	// GetByVerificationToken retrieves a user by their verification token.
 	GetByVerificationToken(ctx context.Context, token string) (*domain.User, error)
 
 	// List retrieves all users with optional pagination.
 	List(ctx context.Context, limit, offset int) ([]*domain.User, error)
 
+	// ListActiveByRole retrieves active users with exactly the given role.
+	ListActiveByRole(ctx context.Context, role domain.Role) ([]*domain.User, error)
+
+	// ListActivityDigestRecipients retrieves active, opted-in users not sent a digest since sentBefore.
+	ListActivityDigestRecipients(ctx context.Context, sentBefore time.Time) ([]*domain.User, error)
+
+	// MarkActivityDigestSent records when the user was last sent an activity digest.
+	MarkActivityDigestSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error
+
 	// Update modifies an existing user in the database.
 	Update(ctx context.Context, user *domain.User) error
 
@@ -33,6 +50,34 @@ type UserRepository interface {
 
 	// Count returns the total number of users.
 	Count(ctx context.Context) (int64, error)
+
+	// RevokeCredentials atomically revokes the user's sessions, reset/verification/email change tokens and stored OAuth tokens.
+	RevokeCredentials(ctx context.Context, id uuid.UUID) error
+}
+
+// SessionStore is the subset of session operations used on the request path. It is
+// implemented by the PostgreSQL repository and by caches layered in front of it.
+type SessionStore interface {
+	// Create inserts a new session.
+	Create(ctx context.Context, session *domain.Session) error
+
+	// GetByID retrieves a session by its ID.
+	GetByID(ctx context.Context, id string) (*domain.Session, error)
+
+	// ListByUserID returns a user's unexpired sessions, most recently active first.
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.Session, error)
+
+	// Touch records that a session was just used.
+	Touch(ctx context.Context, id string) error
+
+	// UpdateExpiry moves a session's expiry and records it as just used.
+	UpdateExpiry(ctx context.Context, id string, expiresAt time.Time) error
+
+	// Delete removes a session by its ID.
+	Delete(ctx context.Context, id string) error
+
+	// DeleteByUserID removes all sessions for a user.
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
 }
 
 // SessionRepository defines the interface for session data access operations.
@@ -43,17 +88,29 @@ type SessionRepository interface {
 	// GetByID retrieves a session by its ID.
 	GetByID(ctx context.Context, id string) (*domain.Session, error)
 
+	// ListByUserID returns a user's unexpired sessions, most recently active first.
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.Session, error)
+
 	// Delete removes a session by its ID.
 	Delete(ctx context.Context, id string) error
 
 	// DeleteByUserID removes all sessions for a user.
 	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
 
-	// DeleteExpired removes all expired sessions.
-	DeleteExpired(ctx context.Context) error
+	// UpdateExpiry moves a session's expiry and records it as just used.
+	UpdateExpiry(ctx context.Context, id string, expiresAt time.Time) error
+
+	// DeleteExpired removes all expired sessions and returns how many were removed.
+	DeleteExpired(ctx context.Context) (int64, error)
 
 	// CountActive returns the number of active (non-expired) sessions.
 	CountActive(ctx context.Context) (int64, error)
+
+	// Touch records that a session was just used.
+	Touch(ctx context.Context, id string) error
+
+	// ListOnline returns users with a non-expired session used since the given time, most recent first.
+	ListOnline(ctx context.Context, since time.Time) ([]*domain.OnlineUser, error)
 }
 
 // OAuthRepository defines the interface for OAuth data access operations.
@@ -65,14 +122,67 @@ type OAuthRepository interface {
 	ListProviders(ctx context.Context) ([]*domain.OAuthProvider, error)
 
 	// UpdateProvider updates an OAuth provider's configuration.
+	// An empty ClientSecret keeps the stored secret.
 	UpdateProvider(ctx context.Context, provider *domain.OAuthProvider) error
 
 	// CreateUserOAuth creates a new link between a user and an OAuth provider.
 	CreateUserOAuth(ctx context.Context, userOAuth *domain.UserOAuth) error
 
+	// UpdateUserOAuth stores new tokens and expiry for an existing user OAuth link.
+	UpdateUserOAuth(ctx context.Context, userOAuth *domain.UserOAuth) error
+
 	// GetUserOAuth retrieves a user OAuth link by provider and provider user ID.
 	GetUserOAuth(ctx context.Context, provider domain.OAuthProviderType, providerUserID string) (*domain.UserOAuth, error)
 
 	// GetUserOAuthByUserID retrieves a user OAuth link by user ID and provider.
 	GetUserOAuthByUserID(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) (*domain.UserOAuth, error)
+
+	// ListByUserID retrieves all OAuth links for a user without their tokens.
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.UserOAuth, error)
+
+	// DeleteUserOAuth removes a user's link to a provider.
+	DeleteUserOAuth(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) error
+}
+
+// EmailChangeRepository defines the interface for email changes awaiting confirmation.
+type EmailChangeRepository interface {
+	// Save stores a pending email change, replacing any earlier one for the user.
+	Save(ctx context.Context, change *domain.PendingEmailChange) error
+
+	// Get retrieves the user's pending email change, returning domain.ErrNotFound if there is none.
+	Get(ctx context.Context, userID uuid.UUID) (*domain.PendingEmailChange, error)
+
+	// GetByHash retrieves a pending email change by its token hash.
+	GetByHash(ctx context.Context, hash string) (*domain.PendingEmailChange, error)
+
+	// Delete removes the user's pending email change.
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
+// TwoFactorRepository defines the interface for TOTP secrets and recovery codes.
+type TwoFactorRepository interface {
+	// Get retrieves a user's TOTP secret, returning domain.ErrNotFound if they have none.
+	Get(ctx context.Context, userID uuid.UUID) (*domain.TOTPSecret, error)
+
+	// SavePending stores a new, not yet enabled secret, replacing any pending or enabled one.
+	SavePending(ctx context.Context, secret *domain.TOTPSecret) error
+
+	// Enable marks the user's secret as confirmed and records step as used.
+	Enable(ctx context.Context, userID uuid.UUID, step int64) error
+
+	// UseStep records step as the last used code, returning false if it is not newer than
+	// the last one, so each code works only once.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+
+	// Delete removes the user's secret and recovery codes.
+	Delete(ctx context.Context, userID uuid.UUID) error
+
+	// ReplaceRecoveryCodes swaps the user's recovery codes for the given hashes.
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error
+
+	// UseRecoveryCode marks an unused code with the given hash as used, returning false if none matched.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error)
+
+	// CountRecoveryCodes returns how many unused recovery codes the user has left.
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
 }
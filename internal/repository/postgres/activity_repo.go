@@ -4,7 +4,9 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -46,17 +48,17 @@ func (r *ActivityLogRepository) Create(ctx context.Context, log *domain.Activity
 	return nil
 }
 
-// ListByUser retrieves activity logs for a specific user.
-func (r *ActivityLogRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.ActivityLog, error) {
+// ListByUser retrieves a page of activity logs for a specific user, newest first.
+func (r *ActivityLogRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.ActivityLog, error) {
 	query := `
 		SELECT id, user_id, activity_type, description, ip_address, user_agent, created_at
 		FROM activity_logs
 		WHERE user_id = $1
 		ORDER BY created_at DESC
-		LIMIT $2
+		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, userID, limit)
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query activity logs: %w", err)
 	}
@@ -83,6 +85,104 @@ func (r *ActivityLogRepository) ListByUser(ctx context.Context, userID uuid.UUID
 	return logs, nil
 }
 
+// CountByUser returns the number of activity logs recorded for a user.
+func (r *ActivityLogRepository) CountByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM activity_logs WHERE user_id = $1`
+
+	var count int
+	if err := r.db.Pool.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count activity logs: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListByUserSince retrieves a user's activity logs created at or after the given time, oldest first.
+func (r *ActivityLogRepository) ListByUserSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]*domain.ActivityLog, error) {
+	query := `
+		SELECT id, user_id, activity_type, description, ip_address, user_agent, created_at
+		FROM activity_logs
+		WHERE user_id = $1 AND created_at >= $2
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*domain.ActivityLog
+	for rows.Next() {
+		log := &domain.ActivityLog{}
+		err := rows.Scan(
+			&log.ID,
+			&log.UserID,
+			&log.ActivityType,
+			&log.Description,
+			&log.IPAddress,
+			&log.UserAgent,
+			&log.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read activity logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+// HasLoginFromUserAgent reports whether the user signed in with the given user agent before the given time.
+func (r *ActivityLogRepository) HasLoginFromUserAgent(ctx context.Context, userID uuid.UUID, userAgent string, before time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM activity_logs
+			WHERE user_id = $1 AND activity_type = $2 AND user_agent = $3 AND created_at < $4
+		)
+	`
+
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, query, userID, domain.ActivityLogin, userAgent, before).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check login user agent: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetLatestByType retrieves the most recent activity log of the given type for a user.
+func (r *ActivityLogRepository) GetLatestByType(ctx context.Context, userID uuid.UUID, activityType domain.ActivityType) (*domain.ActivityLog, error) {
+	query := `
+		SELECT id, user_id, activity_type, description, ip_address, user_agent, created_at
+		FROM activity_logs
+		WHERE user_id = $1 AND activity_type = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	log := &domain.ActivityLog{}
+	err := r.db.Pool.QueryRow(ctx, query, userID, activityType).Scan(
+		&log.ID,
+		&log.UserID,
+		&log.ActivityType,
+		&log.Description,
+		&log.IPAddress,
+		&log.UserAgent,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest activity log: %w", err)
+	}
+
+	return log, nil
+}
+
 // AuditLogRepository handles audit log data operations.
 type AuditLogRepository struct {
 	db *DB
@@ -157,6 +257,45 @@ func (r *AuditLogRepository) List(ctx context.Context, limit, offset int) ([]*do
 	return r.scanAuditLogs(rows)
 }
 
+// GetByID retrieves a single audit log entry, with the admin's name and email.
+func (r *AuditLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.AuditLog, error) {
+	query := `
+		SELECT a.id, a.admin_id, u.name as admin_name, u.email as admin_email, a.action, a.resource_type,
+		       a.resource_id, a.old_values, a.new_values, a.ip_address, a.created_at
+		FROM audit_logs a
+		JOIN users u ON a.admin_id = u.id
+		WHERE a.id = $1
+	`
+
+	log := &domain.AuditLog{}
+	var oldValuesJSON, newValuesJSON []byte
+	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+		&log.ID,
+		&log.AdminID,
+		&log.AdminName,
+		&log.AdminEmail,
+		&log.Action,
+		&log.ResourceType,
+		&log.ResourceID,
+		&oldValuesJSON,
+		&newValuesJSON,
+		&log.IPAddress,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+
+	if err := decodeAuditValues(log, oldValuesJSON, newValuesJSON); err != nil {
+		return nil, err
+	}
+
+	return log, nil
+}
+
 // ListByAdmin retrieves audit logs for a specific admin.
 func (r *AuditLogRepository) ListByAdmin(ctx context.Context, adminID uuid.UUID, limit int) ([]*domain.AuditLog, error) {
 	query := `
@@ -215,16 +354,8 @@ func (r *AuditLogRepository) scanAuditLogs(rows pgx.Rows) ([]*domain.AuditLog, e
 			return nil, fmt.Errorf("failed to scan audit log: %w", err)
 		}
 
-		if oldValuesJSON != nil {
-			if err := json.Unmarshal(oldValuesJSON, &log.OldValues); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal old values: %w", err)
-			}
-		}
-
-		if newValuesJSON != nil {
-			if err := json.Unmarshal(newValuesJSON, &log.NewValues); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal new values: %w", err)
-			}
+		if err := decodeAuditValues(log, oldValuesJSON, newValuesJSON); err != nil {
+			return nil, err
 		}
 
 		logs = append(logs, log)
@@ -232,3 +363,20 @@ func (r *AuditLogRepository) scanAuditLogs(rows pgx.Rows) ([]*domain.AuditLog, e
 
 	return logs, nil
 }
+
+// decodeAuditValues unmarshals the stored old and new values JSON into the log entry.
+func decodeAuditValues(log *domain.AuditLog, oldValuesJSON, newValuesJSON []byte) error {
+	if oldValuesJSON != nil {
+		if err := json.Unmarshal(oldValuesJSON, &log.OldValues); err != nil {
+			return fmt.Errorf("failed to unmarshal old values: %w", err)
+		}
+	}
+
+	if newValuesJSON != nil {
+		if err := json.Unmarshal(newValuesJSON, &log.NewValues); err != nil {
+			return fmt.Errorf("failed to unmarshal new values: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -34,7 +35,13 @@ func (r *BlogRepository) Create(ctx context.Context, blog *domain.Blog) error {
 		blog.MetaTitle, blog.MetaDescription, blog.MetaKeywords,
 		blog.OGImage, blog.OGImageType, blog.OGImageSize,
 	)
-	return err
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	return nil
 }
 
 func (r *BlogRepository) Update(ctx context.Context, blog *domain.Blog) error {
@@ -46,20 +53,35 @@ func (r *BlogRepository) Update(ctx context.Context, blog *domain.Blog) error {
 			og_image = $12, og_image_type = $13, og_image_size = $14
 		WHERE id = $15
 	`
-	_, err := r.db.Pool.Exec(ctx, query,
+	tag, err := r.db.Pool.Exec(ctx, query,
 		blog.Title, blog.Slug, blog.Content, blog.Excerpt, blog.IsPublished, blog.PublishedAt, blog.UpdatedAt,
 		blog.CoverMediaID,
 		blog.MetaTitle, blog.MetaDescription, blog.MetaKeywords,
 		blog.OGImage, blog.OGImageType, blog.OGImageSize,
 		blog.ID,
 	)
-	return err
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
 }
 
 func (r *BlogRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM blogs WHERE id = $1`
-	_, err := r.db.Pool.Exec(ctx, query, id)
-	return err
+	tag, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
 }
 
 func (r *BlogRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Blog, error) {
@@ -118,17 +140,26 @@ func (r *BlogRepository) List(ctx context.Context, filter domain.BlogFilter) ([]
 		return nil, 0, err
 	}
 
+	// Cover media metadata is only joined when asked for; the data column is never selected.
+	mediaColumns, mediaJoin := "", ""
+	if filter.WithCoverMedia {
+		mediaColumns = `,
+		       m.filename, m.content_type, m.size_bytes, m.width, m.height, m.alt_text, m.storage_provider, m.public_url`
+		mediaJoin = "LEFT JOIN media m ON b.cover_media_id = m.id"
+	}
+
 	query := fmt.Sprintf(`
 		SELECT b.id, b.title, b.slug, b.content, b.excerpt, b.author_id, b.is_published, b.published_at, b.created_at, b.updated_at,
 		       b.cover_media_id, b.meta_title, b.meta_description, b.meta_keywords,
 		       b.og_image_type, b.og_image_size,
-		       u.id, u.name, u.email, u.profile_media_id IS NOT NULL as has_image
+		       u.id, u.name, u.email, u.profile_media_id IS NOT NULL as has_image%s
 		FROM blogs b
 		JOIN users u ON b.author_id = u.id
 		%s
+		%s
 		ORDER BY b.created_at DESC
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argIdx, argIdx+1)
+	`, mediaColumns, mediaJoin, whereClause, argIdx, argIdx+1)
 
 	args = append(args, filter.Limit, filter.Offset)
 
@@ -140,16 +171,117 @@ func (r *BlogRepository) List(ctx context.Context, filter domain.BlogFilter) ([]
 
 	var blogs []*domain.Blog
 	for rows.Next() {
-		blog, err := scanBlogRow(rows)
+		var blog *domain.Blog
+		if filter.WithCoverMedia {
+			blog, err = scanBlogRowWithCover(rows)
+		} else {
+			blog, err = scanBlogRow(rows)
+		}
 		if err != nil {
 			return nil, 0, err
 		}
 		blogs = append(blogs, blog)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
 
 	return blogs, total, nil
 }
 
+// IncrementViewCountBySlug records one view of the published post with slug; unknown
+// slugs and drafts are ignored.
+func (r *BlogRepository) IncrementViewCountBySlug(ctx context.Context, slug string) error {
+	_, err := r.db.Pool.Exec(ctx, `UPDATE blogs SET view_count = view_count + 1 WHERE slug = $1 AND is_published = true`, slug)
+	return err
+}
+
+// CountPublished returns the number of published posts.
+func (r *BlogRepository) CountPublished(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM blogs WHERE is_published = true`).Scan(&count)
+	return count, err
+}
+
+// CountDrafts returns the number of unpublished posts.
+func (r *BlogRepository) CountDrafts(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM blogs WHERE is_published = false`).Scan(&count)
+	return count, err
+}
+
+// ListCreatedSince returns the creation times of posts created at or after since.
+func (r *BlogRepository) ListCreatedSince(ctx context.Context, since time.Time) ([]time.Time, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT created_at FROM blogs WHERE created_at >= $1`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
+
+// ListMostViewed returns the published posts with the most views, with ViewCount set.
+func (r *BlogRepository) ListMostViewed(ctx context.Context, limit int) ([]*domain.Blog, error) {
+	query := `
+		SELECT id, title, slug, view_count, published_at
+		FROM blogs
+		WHERE is_published = true AND view_count > 0
+		ORDER BY view_count DESC, published_at DESC
+		LIMIT $1
+	`
+	rows, err := r.db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blogs []*domain.Blog
+	for rows.Next() {
+		b := &domain.Blog{IsPublished: true}
+		if err := rows.Scan(&b.ID, &b.Title, &b.Slug, &b.ViewCount, &b.PublishedAt); err != nil {
+			return nil, err
+		}
+		blogs = append(blogs, b)
+	}
+	return blogs, rows.Err()
+}
+
+// ListPublishedSlugs returns the slug and last update time of published posts, newest first,
+// without loading their content.
+func (r *BlogRepository) ListPublishedSlugs(ctx context.Context, limit int) ([]*domain.Blog, error) {
+	query := `
+		SELECT slug, updated_at
+		FROM blogs
+		WHERE is_published = true
+		ORDER BY published_at DESC
+		LIMIT $1
+	`
+	rows, err := r.db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blogs []*domain.Blog
+	for rows.Next() {
+		b := &domain.Blog{IsPublished: true}
+		if err := rows.Scan(&b.Slug, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		blogs = append(blogs, b)
+	}
+	return blogs, rows.Err()
+}
+
 func scanBlog(row pgx.Row) (*domain.Blog, error) {
 	var b domain.Blog
 	var u domain.User
@@ -165,7 +297,7 @@ func scanBlog(row pgx.Row) (*domain.Blog, error) {
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil // Or return a specific ErrNotFound
+			return nil, domain.ErrNotFound
 		}
 		return nil, err
 	}
@@ -228,3 +360,49 @@ func scanBlogRow(rows pgx.Rows) (*domain.Blog, error) {
 	b.Author = &u
 	return &b, nil
 }
+
+// scanBlogRowWithCover scans a List row that includes the joined cover media columns.
+// CoverMedia is left nil when the post has no cover or the media row is gone.
+func scanBlogRowWithCover(rows pgx.Rows) (*domain.Blog, error) {
+	var b domain.Blog
+	var u domain.User
+	var hasImage bool
+	var metaTitle, metaDescription, metaKeywords, ogImageType sql.NullString
+	var ogImageSize sql.NullInt32
+	var filename, contentType, altText, storageProvider, publicURL sql.NullString
+	var sizeBytes, width, height sql.NullInt32
+
+	err := rows.Scan(
+		&b.ID, &b.Title, &b.Slug, &b.Content, &b.Excerpt, &b.AuthorID, &b.IsPublished, &b.PublishedAt, &b.CreatedAt, &b.UpdatedAt,
+		&b.CoverMediaID, &metaTitle, &metaDescription, &metaKeywords,
+		&ogImageType, &ogImageSize,
+		&u.ID, &u.Name, &u.Email, &hasImage,
+		&filename, &contentType, &sizeBytes, &width, &height, &altText, &storageProvider, &publicURL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	b.MetaTitle = metaTitle.String
+	b.MetaDescription = metaDescription.String
+	b.MetaKeywords = metaKeywords.String
+	b.OGImageType = ogImageType.String
+	b.OGImageSize = int(ogImageSize.Int32)
+
+	if b.CoverMediaID != nil && contentType.Valid {
+		b.CoverMedia = &domain.Media{
+			ID:              *b.CoverMediaID,
+			Filename:        filename.String,
+			ContentType:     contentType.String,
+			SizeBytes:       int(sizeBytes.Int32),
+			Width:           int(width.Int32),
+			Height:          int(height.Int32),
+			AltText:         altText.String,
+			StorageProvider: storageProvider.String,
+			PublicURL:       publicURL.String,
+		}
+	}
+
+	b.Author = &u
+	return &b, nil
+}
@@ -0,0 +1,67 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres/postgrestest"
+)
+
+func TestBlogRepository_GetMissingReturnsNotFound(t *testing.T) {
+	db := postgrestest.New(t)
+	blogs := postgres.NewBlogRepository(db)
+	ctx := context.Background()
+
+	blog, err := blogs.GetBySlug(ctx, "no-such-post")
+	if !domain.IsNotFoundError(err) {
+		t.Errorf("GetBySlug() error = %v, want ErrNotFound", err)
+	}
+	if blog != nil {
+		t.Errorf("GetBySlug() blog = %+v, want nil", blog)
+	}
+
+	blog, err = blogs.GetByID(ctx, uuid.New())
+	if !domain.IsNotFoundError(err) {
+		t.Errorf("GetByID() error = %v, want ErrNotFound", err)
+	}
+	if blog != nil {
+		t.Errorf("GetByID() blog = %+v, want nil", blog)
+	}
+}
+
+func TestBlogRepository_GetExisting(t *testing.T) {
+	db := postgrestest.New(t)
+	blogs := postgres.NewBlogRepository(db)
+	ctx := context.Background()
+
+	author := createUser(t, postgres.NewUserRepository(db), "author@example.com")
+	now := time.Now()
+	post := &domain.Blog{
+		ID:        uuid.New(),
+		Title:     "Hello",
+		Slug:      "hello",
+		Content:   "<p>Hello</p>",
+		AuthorID:  author.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := blogs.Create(ctx, post); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	got, err := blogs.GetBySlug(ctx, "hello")
+	if err != nil {
+		t.Fatalf("GetBySlug() = %v", err)
+	}
+	if got.ID != post.ID || got.Author == nil || got.Author.ID != author.ID {
+		t.Errorf("GetBySlug() = %+v, want post %s by %s", got, post.ID, author.ID)
+	}
+
+	if _, err := blogs.GetByID(ctx, post.ID); err != nil {
+		t.Errorf("GetByID() = %v", err)
+	}
+}
@@ -4,7 +4,11 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -13,6 +17,12 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
+// pgxQuerier runs statements on either the pool or a transaction, so a write can be
+// shared between a plain call and one that is part of a larger transaction.
+type pgxQuerier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
 // New creates a new database connection pool.
 func New(ctx context.Context, databaseURL string) (*DB, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
@@ -31,12 +41,42 @@ func New(ctx context.Context, databaseURL string) (*DB, error) {
 
 	// Test the connection
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	return &DB{Pool: pool}, nil
 }
 
+// maxConnectBackoff caps the wait between connection attempts in NewWithRetry.
+const maxConnectBackoff = 30 * time.Second
+
+// NewWithRetry is New for services that may start before the database is up: it makes up
+// to attempts connection attempts, waiting interval after the first failure and doubling
+// the wait after each one (up to 30s). It gives up early if ctx is cancelled.
+func NewWithRetry(ctx context.Context, databaseURL string, attempts int, interval time.Duration) (*DB, error) {
+	attempts = max(attempts, 1)
+	wait := interval
+
+	for attempt := 1; ; attempt++ {
+		db, err := New(ctx, databaseURL)
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= attempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.Printf("Database not ready (attempt %d/%d): %v; retrying in %s", attempt, attempts, err, wait)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, maxConnectBackoff)
+	}
+}
+
 // Close closes the database connection pool.
 func (db *DB) Close() {
 	db.Pool.Close()
@@ -47,19 +87,183 @@ func (db *DB) Health(ctx context.Context) error {
 	return db.Pool.Ping(ctx)
 }
 
-// RunMigrations executes database migrations from embedded SQL files.
-// In a production app, consider using a proper migration tool like golang-migrate.
+// migrationLockID is the advisory lock key that serializes migrations across instances.
+const migrationLockID = 4072201
+
+// ensureMigrationsTable creates the schema_migrations table recording applied versions.
+func (db *DB) ensureMigrationsTable(ctx context.Context) error {
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	return err
+}
+
+// RunMigrations applies every embedded migration not yet recorded in schema_migrations,
+// each in its own transaction. The migrations are idempotent, so a database created before
+// versions were recorded simply re-runs them once.
 func (db *DB) RunMigrations(ctx context.Context) error {
-	migrations, err := GetMigrations()
+	migrations, err := LoadMigrations()
 	if err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
 
-	for _, migration := range migrations {
-		if _, err := db.Pool.Exec(ctx, migration); err != nil {
-			return fmt.Errorf("failed to run migration: %w", err)
+	for _, m := range migrations {
+		if err := db.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("failed to run migration %03d_%s: %w", m.Version, m.Name, err)
 		}
 	}
 
 	return nil
 }
+
+func (db *DB) applyMigration(ctx context.Context, m Migration) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Another instance may be migrating at the same time; wait for it, then re-check
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	var applied bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, m.Up); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MigrateDown reverts applied migrations newest first until none above target remain,
+// each in its own transaction, and returns the versions it reverted. Target 0 reverts
+// everything; a negative target is refused. It stops at the first migration without a
+// down file, leaving that one and everything below it in place.
+func (db *DB) MigrateDown(ctx context.Context, target int) ([]int, error) {
+	if target < 0 {
+		return nil, fmt.Errorf("refusing to migrate below version 0 (got %d)", target)
+	}
+
+	applied, err := db.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.revertAbove(ctx, applied, target)
+}
+
+// MigrateDownOne reverts only the most recently applied migration.
+func (db *DB) MigrateDownOne(ctx context.Context) ([]int, error) {
+	applied, err := db.appliedVersions(ctx)
+	if err != nil || len(applied) == 0 {
+		return nil, err
+	}
+	target := 0
+	if len(applied) > 1 {
+		target = applied[1]
+	}
+	return db.revertAbove(ctx, applied, target)
+}
+
+// appliedVersions returns the versions recorded in schema_migrations, newest first.
+func (db *DB) appliedVersions(ctx context.Context) ([]int, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := db.Pool.Query(ctx, `SELECT version FROM schema_migrations ORDER BY version DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied = append(applied, v)
+	}
+	return applied, rows.Err()
+}
+
+// revertAbove reverts the applied versions (newest first) that are above target.
+func (db *DB) revertAbove(ctx context.Context, applied []int, target int) ([]int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	var reverted []int
+	for _, v := range applied {
+		if v <= target {
+			break
+		}
+		m, ok := byVersion[v]
+		if !ok {
+			return reverted, fmt.Errorf("migration %03d is applied but not embedded in this build", v)
+		}
+		if m.Down == "" {
+			return reverted, fmt.Errorf("migration %03d_%s has no down migration", m.Version, m.Name)
+		}
+		if err := db.revertMigration(ctx, m); err != nil {
+			return reverted, fmt.Errorf("failed to revert migration %03d_%s: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, v)
+	}
+
+	return reverted, nil
+}
+
+func (db *DB) revertMigration(ctx context.Context, m Migration) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	// A down file holding only comments documents a migration with nothing to undo
+	if !commentsOnly(m.Down) {
+		if _, err := tx.Exec(ctx, m.Down); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// commentsOnly reports whether sql has nothing but blank lines and -- comments.
+func commentsOnly(sql string) bool {
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
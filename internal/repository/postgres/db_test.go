@@ -0,0 +1,117 @@
+package postgres_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres/postgrestest"
+)
+
+// latestVersion returns the newest version recorded in schema_migrations, or 0.
+func latestVersion(t *testing.T, db *postgres.DB) int {
+	t.Helper()
+	var v int
+	if err := db.Pool.QueryRow(context.Background(), `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		t.Fatalf("read schema_migrations: %v", err)
+	}
+	return v
+}
+
+// tableExists reports whether table is visible on the test schema's search path.
+func tableExists(t *testing.T, db *postgres.DB, table string) bool {
+	t.Helper()
+	var exists bool
+	if err := db.Pool.QueryRow(context.Background(), `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+		t.Fatalf("look up table %s: %v", table, err)
+	}
+	return exists
+}
+
+func TestDB_MigrateDownAndUpAgain(t *testing.T) {
+	db := postgrestest.NewUnmigrated(t)
+	ctx := context.Background()
+
+	migrations, err := postgres.LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations() = %v", err)
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	if err := db.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations() = %v", err)
+	}
+	if got := latestVersion(t, db); got != latest {
+		t.Fatalf("latest applied version = %d, want %d", got, latest)
+	}
+
+	// Revert everything that has a down file, newest first
+	reverted, err := db.MigrateDown(ctx, 3)
+	if err != nil {
+		t.Fatalf("MigrateDown(3) = %v", err)
+	}
+	var want []int
+	for v := latest; v > 3; v-- {
+		want = append(want, v)
+	}
+	if !slices.Equal(reverted, want) {
+		t.Errorf("MigrateDown(3) reverted %v, want %v", reverted, want)
+	}
+	if got := latestVersion(t, db); got != 3 {
+		t.Errorf("latest applied version = %d, want 3", got)
+	}
+	for _, table := range []string{"rate_limit_buckets", "app_settings", "email_changes"} {
+		if tableExists(t, db, table) {
+			t.Errorf("table %s still exists after migrating down", table)
+		}
+	}
+
+	// The down migrations leave a schema the up migrations apply to cleanly
+	if err := db.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations() after migrating down = %v", err)
+	}
+	if got := latestVersion(t, db); got != latest {
+		t.Errorf("latest applied version = %d, want %d", got, latest)
+	}
+	if !tableExists(t, db, "email_changes") {
+		t.Error("email_changes was not recreated")
+	}
+}
+
+func TestDB_MigrateDownOne(t *testing.T) {
+	db := postgrestest.New(t)
+	ctx := context.Background()
+	latest := latestVersion(t, db)
+
+	reverted, err := db.MigrateDownOne(ctx)
+	if err != nil {
+		t.Fatalf("MigrateDownOne() = %v", err)
+	}
+	if !slices.Equal(reverted, []int{latest}) {
+		t.Errorf("MigrateDownOne() reverted %v, want [%d]", reverted, latest)
+	}
+	if got := latestVersion(t, db); got != latest-1 {
+		t.Errorf("latest applied version = %d, want %d", got, latest-1)
+	}
+}
+
+func TestDB_MigrateDownRefusesIrreversible(t *testing.T) {
+	db := postgrestest.New(t)
+	ctx := context.Background()
+
+	if _, err := db.MigrateDown(ctx, -1); err == nil {
+		t.Error("MigrateDown(-1) = nil, want an error")
+	}
+
+	// 001-003 have no down files, so reverting everything stops above them
+	if _, err := db.MigrateDown(ctx, 0); err == nil {
+		t.Error("MigrateDown(0) = nil, want an error for the migrations without down files")
+	}
+	if got := latestVersion(t, db); got != 3 {
+		t.Errorf("latest applied version = %d, want 3", got)
+	}
+	if !tableExists(t, db, "users") {
+		t.Error("users table was dropped")
+	}
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+)
+
+// EmailChangeRepository implements the repository.EmailChangeRepository interface.
+type EmailChangeRepository struct {
+	db *DB
+}
+
+// NewEmailChangeRepository creates a new PostgreSQL email change repository.
+func NewEmailChangeRepository(db *DB) *EmailChangeRepository {
+	return &EmailChangeRepository{db: db}
+}
+
+// Save stores a pending email change, replacing any earlier one for the user.
+func (r *EmailChangeRepository) Save(ctx context.Context, change *domain.PendingEmailChange) error {
+	query := `
+		INSERT INTO email_changes (user_id, new_email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET new_email = EXCLUDED.new_email,
+			token_hash = EXCLUDED.token_hash,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		change.UserID,
+		change.NewEmail,
+		change.TokenHash,
+		change.ExpiresAt,
+		change.CreatedAt,
+	)
+	return err
+}
+
+// Get retrieves the user's pending email change.
+func (r *EmailChangeRepository) Get(ctx context.Context, userID uuid.UUID) (*domain.PendingEmailChange, error) {
+	return r.scanOne(ctx, `
+		SELECT user_id, new_email, token_hash, expires_at, created_at
+		FROM email_changes
+		WHERE user_id = $1
+	`, userID)
+}
+
+// GetByHash retrieves a pending email change by its token hash.
+func (r *EmailChangeRepository) GetByHash(ctx context.Context, hash string) (*domain.PendingEmailChange, error) {
+	return r.scanOne(ctx, `
+		SELECT user_id, new_email, token_hash, expires_at, created_at
+		FROM email_changes
+		WHERE token_hash = $1
+	`, hash)
+}
+
+// Delete removes the user's pending email change.
+func (r *EmailChangeRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM email_changes WHERE user_id = $1`
+	_, err := r.db.Pool.Exec(ctx, query, userID)
+	return err
+}
+
+func (r *EmailChangeRepository) scanOne(ctx context.Context, query string, arg any) (*domain.PendingEmailChange, error) {
+	change := &domain.PendingEmailChange{}
+	err := r.db.Pool.QueryRow(ctx, query, arg).Scan(
+		&change.UserID,
+		&change.NewEmail,
+		&change.TokenHash,
+		&change.ExpiresAt,
+		&change.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return change, nil
+}
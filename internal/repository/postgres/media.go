@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -20,9 +21,9 @@ func NewMediaRepository(db *DB) *MediaRepository {
 
 func (r *MediaRepository) Create(ctx context.Context, input domain.CreateMediaInput) (*domain.Media, error) {
 	query := `
-		INSERT INTO media (user_id, filename, data, content_type, size_bytes, alt_text, storage_provider)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, user_id, filename, content_type, size_bytes, alt_text, storage_provider, file_key, public_url, created_at, updated_at
+		INSERT INTO media (user_id, filename, data, content_type, size_bytes, width, height, alt_text, storage_provider)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, 0), $8, $9)
+		RETURNING id, user_id, filename, content_type, size_bytes, COALESCE(width, 0), COALESCE(height, 0), alt_text, storage_provider, file_key, public_url, created_at, updated_at
 	`
 
 	m := &domain.Media{}
@@ -40,6 +41,8 @@ func (r *MediaRepository) Create(ctx context.Context, input domain.CreateMediaIn
 		data,
 		input.ContentType,
 		input.SizeBytes,
+		input.Width,
+		input.Height,
 		input.AltText,
 		input.StorageProvider,
 	).Scan(
@@ -48,6 +51,8 @@ func (r *MediaRepository) Create(ctx context.Context, input domain.CreateMediaIn
 		&m.Filename,
 		&m.ContentType,
 		&m.SizeBytes,
+		&m.Width,
+		&m.Height,
 		&m.AltText,
 		&m.StorageProvider,
 		&fileKey,   // May be null
@@ -73,9 +78,53 @@ func (r *MediaRepository) Create(ctx context.Context, input domain.CreateMediaIn
 	return m, nil
 }
 
+// GetMetadataByID retrieves a media item without loading its binary data.
+func (r *MediaRepository) GetMetadataByID(ctx context.Context, id uuid.UUID) (*domain.Media, error) {
+	query := `
+		SELECT id, user_id, filename, content_type, size_bytes, COALESCE(width, 0), COALESCE(height, 0), alt_text, storage_provider, file_key, public_url, created_at, updated_at
+		FROM media
+		WHERE id = $1
+	`
+
+	m := &domain.Media{}
+	var fileKey, publicURL *string // Temp vars for nullable strings
+
+	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+		&m.ID,
+		&m.UserID,
+		&m.Filename,
+		&m.ContentType,
+		&m.SizeBytes,
+		&m.Width,
+		&m.Height,
+		&m.AltText,
+		&m.StorageProvider,
+		&fileKey,
+		&publicURL,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get media metadata: %w", err)
+	}
+
+	if fileKey != nil {
+		m.FileKey = *fileKey
+	}
+	if publicURL != nil {
+		m.PublicURL = *publicURL
+	}
+
+	return m, nil
+}
+
 func (r *MediaRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Media, error) {
 	query := `
-		SELECT id, user_id, filename, data, content_type, size_bytes, alt_text, storage_provider, file_key, public_url, created_at, updated_at
+		SELECT id, user_id, filename, data, content_type, size_bytes, COALESCE(width, 0), COALESCE(height, 0), alt_text, storage_provider, file_key, public_url, created_at, updated_at
 		FROM media
 		WHERE id = $1
 	`
@@ -90,6 +139,8 @@ func (r *MediaRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Me
 		&m.Data,
 		&m.ContentType,
 		&m.SizeBytes,
+		&m.Width,
+		&m.Height,
 		&m.AltText,
 		&m.StorageProvider,
 		&fileKey,
@@ -115,6 +166,150 @@ func (r *MediaRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Me
 	return m, nil
 }
 
+func (r *MediaRepository) Update(ctx context.Context, m *domain.Media) error {
+	query := `
+		UPDATE media
+		SET alt_text = $1, filename = $2, content_type = $3, storage_provider = $4, file_key = $5, public_url = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING updated_at
+	`
+
+	// Store empty keys/URLs as NULL to match rows created without them
+	var fileKey, publicURL *string
+	if m.FileKey != "" {
+		fileKey = &m.FileKey
+	}
+	if m.PublicURL != "" {
+		publicURL = &m.PublicURL
+	}
+
+	err := r.db.Pool.QueryRow(ctx, query,
+		m.AltText,
+		m.Filename,
+		m.ContentType,
+		m.StorageProvider,
+		fileKey,
+		publicURL,
+		m.ID,
+	).Scan(&m.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("failed to update media: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MediaRepository) List(ctx context.Context, filter domain.MediaFilter) ([]*domain.Media, error) {
+	whereClause, args := buildMediaWhere(filter)
+	argIdx := len(args) + 1
+
+	// Data is intentionally not selected; listings only need metadata
+	query := fmt.Sprintf(`
+		SELECT id, user_id, filename, content_type, size_bytes, COALESCE(width, 0), COALESCE(height, 0), alt_text, storage_provider, file_key, public_url, created_at, updated_at
+		FROM media
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIdx, argIdx+1)
+
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*domain.Media
+	for rows.Next() {
+		m := &domain.Media{}
+		var fileKey, publicURL *string
+
+		if err := rows.Scan(
+			&m.ID,
+			&m.UserID,
+			&m.Filename,
+			&m.ContentType,
+			&m.SizeBytes,
+			&m.Width,
+			&m.Height,
+			&m.AltText,
+			&m.StorageProvider,
+			&fileKey,
+			&publicURL,
+			&m.CreatedAt,
+			&m.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+
+		if fileKey != nil {
+			m.FileKey = *fileKey
+		}
+		if publicURL != nil {
+			m.PublicURL = *publicURL
+		}
+
+		items = append(items, m)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *MediaRepository) Count(ctx context.Context, filter domain.MediaFilter) (int, error) {
+	whereClause, args := buildMediaWhere(filter)
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM media %s", whereClause)
+
+	var total int
+	if err := r.db.Pool.QueryRow(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count media: %w", err)
+	}
+
+	return total, nil
+}
+
+func buildMediaWhere(filter domain.MediaFilter) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	argIdx := 1
+
+	if filter.UserID != nil {
+		where = append(where, fmt.Sprintf("user_id = $%d", argIdx))
+		args = append(args, *filter.UserID)
+		argIdx++
+	}
+
+	if filter.ContentType != "" {
+		where = append(where, fmt.Sprintf("content_type LIKE $%d", argIdx))
+		args = append(args, filter.ContentType+"%")
+		argIdx++
+	}
+
+	if filter.CreatedAfter != nil {
+		where = append(where, fmt.Sprintf("created_at >= $%d", argIdx))
+		args = append(args, *filter.CreatedAfter)
+		argIdx++
+	}
+
+	if filter.CreatedBefore != nil {
+		where = append(where, fmt.Sprintf("created_at < $%d", argIdx))
+		args = append(args, *filter.CreatedBefore)
+		argIdx++
+	}
+
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	return whereClause, args
+}
+
 func (r *MediaRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM media WHERE id = $1`
 
@@ -129,3 +324,44 @@ func (r *MediaRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+// ListMissingDimensions returns the IDs of database-stored images without recorded
+// dimensions, in ID order after afterID, so a backfill can page through them even
+// when some images can't be decoded.
+func (r *MediaRepository) ListMissingDimensions(ctx context.Context, afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM media
+		WHERE width IS NULL AND content_type LIKE 'image/%' AND data IS NOT NULL AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media missing dimensions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan media id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetDimensions records the pixel dimensions of an image.
+func (r *MediaRepository) SetDimensions(ctx context.Context, id uuid.UUID, width, height int) error {
+	tag, err := r.db.Pool.Exec(ctx, `UPDATE media SET width = $1, height = $2 WHERE id = $3`, width, height, id)
+	if err != nil {
+		return fmt.Errorf("failed to set media dimensions: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+-- Optional username for signing in without an email address.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(50);
+
+-- Usernames are unique regardless of case.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
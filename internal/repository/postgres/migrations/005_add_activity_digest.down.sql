@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_activity_digest;
+ALTER TABLE users DROP COLUMN IF EXISTS activity_digest_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS activity_digest_enabled;
@@ -0,0 +1,5 @@
+-- Opt-in weekly account-activity digest emails.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS activity_digest_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS activity_digest_sent_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_activity_digest ON users (activity_digest_sent_at) WHERE activity_digest_enabled;
@@ -0,0 +1,2 @@
+-- Nothing to undo: the original casing of the emails isn't kept, and the app
+-- treats emails case-insensitively either way.
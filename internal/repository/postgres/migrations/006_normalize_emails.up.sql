@@ -0,0 +1,9 @@
+-- Store emails trimmed and lowercased so lookups by the normalized address match.
+-- Rows whose normalized form would collide with another account are left untouched.
+UPDATE users u
+SET email = LOWER(TRIM(u.email))
+WHERE u.email <> LOWER(TRIM(u.email))
+  AND NOT EXISTS (
+      SELECT 1 FROM users o
+      WHERE o.id <> u.id AND LOWER(TRIM(o.email)) = LOWER(TRIM(u.email))
+  );
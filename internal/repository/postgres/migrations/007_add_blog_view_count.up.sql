@@ -0,0 +1,2 @@
+-- Number of times a published post has been viewed, for the analytics dashboard.
+ALTER TABLE blogs ADD COLUMN IF NOT EXISTS view_count BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS auth_provider;
+ALTER TABLE oauth_providers DROP COLUMN IF EXISTS end_session_url;
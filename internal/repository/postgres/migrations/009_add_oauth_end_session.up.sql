@@ -0,0 +1,6 @@
+-- Optional OIDC end_session_endpoint per provider; when set, signing out of a session
+-- created through that provider also ends the user's session at the provider.
+ALTER TABLE oauth_providers ADD COLUMN IF NOT EXISTS end_session_url TEXT NOT NULL DEFAULT '';
+
+-- Provider a session was signed in through, empty for password and magic link sessions.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS auth_provider VARCHAR(50) NOT NULL DEFAULT '';
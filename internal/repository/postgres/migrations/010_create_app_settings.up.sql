@@ -0,0 +1,6 @@
+-- Editable site settings (e.g. landing page copy) as key/value pairs; missing keys use built-in defaults.
+CREATE TABLE IF NOT EXISTS app_settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,8 @@
+-- Put the seeded LinkedIn row back on the legacy scopes, again only while it is unconfigured.
+UPDATE oauth_providers
+SET scopes = ARRAY['r_liteprofile', 'r_emailaddress'],
+    user_info_url = 'https://api.linkedin.com/v2/me',
+    updated_at = NOW()
+WHERE provider = 'linkedin'
+  AND client_id = ''
+  AND user_info_url = 'https://api.linkedin.com/v2/userinfo';
@@ -0,0 +1,10 @@
+-- LinkedIn retired r_liteprofile/r_emailaddress and /v2/me for new apps in favour of
+-- "Sign In with LinkedIn using OpenID Connect". Move the seeded row over while it is
+-- still unconfigured; providers an admin has already set up are left alone.
+UPDATE oauth_providers
+SET scopes = ARRAY['openid', 'profile', 'email'],
+    user_info_url = 'https://api.linkedin.com/v2/userinfo',
+    updated_at = NOW()
+WHERE provider = 'linkedin'
+  AND client_id = ''
+  AND user_info_url = 'https://api.linkedin.com/v2/me';
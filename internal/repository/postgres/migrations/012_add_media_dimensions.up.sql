@@ -0,0 +1,4 @@
+-- Pixel dimensions of images, recorded at upload so pages can reserve space for them.
+-- NULL until known; existing rows are filled in by `go run ./cmd/maintenance -task media-dimensions`.
+ALTER TABLE media ADD COLUMN IF NOT EXISTS width INTEGER;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS height INTEGER;
@@ -0,0 +1,9 @@
+-- A new email address waiting to be confirmed. The account keeps its current email until
+-- the link sent to new_email is followed; one pending change per user, stored as a SHA-256 hash.
+CREATE TABLE IF NOT EXISTS email_changes (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    new_email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(255) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+-- When each rate limit bucket will have refilled, so cleanup drops only buckets that are
+-- full whatever their limiter's rate. NULL for buckets that never refill (a zero rate).
+ALTER TABLE rate_limit_buckets ADD COLUMN IF NOT EXISTS full_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing buckets don't record their rate; a day is longer than any default limiter
+-- takes to refill. Each bucket gets its exact time the next time it is used.
+UPDATE rate_limit_buckets SET full_at = updated_at + INTERVAL '1 day' WHERE full_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_full_at ON rate_limit_buckets(full_at);
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -23,9 +24,52 @@ func NewOAuthRepository(db *DB, authSecret string) *OAuthRepository {
 	}
 }
 
+// decryptProvider decrypts the provider's client credentials in place. Values that fail to
+// decrypt (usually because AUTH_SECRET changed) are cleared rather than passed on as ciphertext,
+// and the provider is flagged so it can be shown as misconfigured.
+func (r *OAuthRepository) decryptProvider(p *domain.OAuthProvider) {
+	var ok bool
+	if p.ClientSecret != "" {
+		if p.ClientSecret, ok = r.decrypt(p.ClientSecret); !ok {
+			p.DecryptionFailed = true
+		}
+	}
+	if p.ClientID != "" {
+		if p.ClientID, ok = r.decrypt(p.ClientID); !ok {
+			p.DecryptionFailed = true
+		}
+	}
+	if p.DecryptionFailed {
+		log.Printf("WARNING: OAuth secret for provider %s could not be decrypted - AUTH_SECRET may have changed; re-enter its client credentials", p.Provider)
+	}
+}
+
+// decryptUserOAuth decrypts a user's provider tokens in place, clearing any that fail to decrypt.
+func (r *OAuthRepository) decryptUserOAuth(u *domain.UserOAuth) {
+	accessOK, refreshOK := true, true
+	if u.AccessToken != "" {
+		u.AccessToken, accessOK = r.decrypt(u.AccessToken)
+	}
+	if u.RefreshToken != "" {
+		u.RefreshToken, refreshOK = r.decrypt(u.RefreshToken)
+	}
+	if !accessOK || !refreshOK {
+		log.Printf("WARNING: OAuth tokens for user %s (%s) could not be decrypted - AUTH_SECRET may have changed", u.UserID, u.Provider)
+	}
+}
+
+// decrypt returns the plaintext, or "" and false if the value cannot be decrypted with the current secret.
+func (r *OAuthRepository) decrypt(value string) (string, bool) {
+	decrypted, err := encryption.Decrypt(value, r.authSecret)
+	if err != nil {
+		return "", false
+	}
+	return decrypted, true
+}
+
 func (r *OAuthRepository) GetProvider(ctx context.Context, name domain.OAuthProviderType) (*domain.OAuthProvider, error) {
 	query := `
-		SELECT provider, client_id, client_secret, enabled, scopes, auth_url, token_url, user_info_url, created_at, updated_at
+		SELECT provider, client_id, client_secret, enabled, scopes, auth_url, token_url, user_info_url, end_session_url, created_at, updated_at
 		FROM oauth_providers
 		WHERE provider = $1
 	`
@@ -41,6 +85,7 @@ func (r *OAuthRepository) GetProvider(ctx context.Context, name domain.OAuthProv
 		&p.AuthURL,
 		&p.TokenURL,
 		&p.UserInfoURL,
+		&p.EndSessionURL,
 		&p.CreatedAt,
 		&p.UpdatedAt,
 	)
@@ -52,20 +97,7 @@ func (r *OAuthRepository) GetProvider(ctx context.Context, name domain.OAuthProv
 		return nil, fmt.Errorf("failed to get oauth provider: %w", err)
 	}
 
-	// Decrypt secrets
-	if p.ClientSecret != "" {
-		decrypted, err := encryption.Decrypt(p.ClientSecret, r.authSecret)
-		if err == nil {
-			p.ClientSecret = decrypted
-		}
-		// If error, keep original (maybe not encrypted yet or legacy)
-	}
-	if p.ClientID != "" {
-		decrypted, err := encryption.Decrypt(p.ClientID, r.authSecret)
-		if err == nil {
-			p.ClientID = decrypted
-		}
-	}
+	r.decryptProvider(&p)
 
 	p.Scopes = scopes
 	return &p, nil
@@ -73,7 +105,7 @@ func (r *OAuthRepository) GetProvider(ctx context.Context, name domain.OAuthProv
 
 func (r *OAuthRepository) ListProviders(ctx context.Context) ([]*domain.OAuthProvider, error) {
 	query := `
-		SELECT provider, client_id, client_secret, enabled, scopes, auth_url, token_url, user_info_url, created_at, updated_at
+		SELECT provider, client_id, client_secret, enabled, scopes, auth_url, token_url, user_info_url, end_session_url, created_at, updated_at
 		FROM oauth_providers
 		ORDER BY provider
 	`
@@ -97,25 +129,14 @@ func (r *OAuthRepository) ListProviders(ctx context.Context) ([]*domain.OAuthPro
 			&p.AuthURL,
 			&p.TokenURL,
 			&p.UserInfoURL,
+			&p.EndSessionURL,
 			&p.CreatedAt,
 			&p.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan oauth provider: %w", err)
 		}
 
-		// Decrypt secrets
-		if p.ClientSecret != "" {
-			decrypted, err := encryption.Decrypt(p.ClientSecret, r.authSecret)
-			if err == nil {
-				p.ClientSecret = decrypted
-			}
-		}
-		if p.ClientID != "" {
-			decrypted, err := encryption.Decrypt(p.ClientID, r.authSecret)
-			if err == nil {
-				p.ClientID = decrypted
-			}
-		}
+		r.decryptProvider(&p)
 
 		p.Scopes = scopes
 		providers = append(providers, &p)
@@ -124,18 +145,22 @@ func (r *OAuthRepository) ListProviders(ctx context.Context) ([]*domain.OAuthPro
 	return providers, nil
 }
 
+// UpdateProvider creates or updates an OAuth provider's configuration.
+// An empty ClientSecret keeps the stored secret instead of overwriting it.
 func (r *OAuthRepository) UpdateProvider(ctx context.Context, provider *domain.OAuthProvider) error {
 	query := `
-		INSERT INTO oauth_providers (provider, client_id, client_secret, enabled, scopes, auth_url, token_url, user_info_url, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		INSERT INTO oauth_providers (provider, client_id, client_secret, enabled, scopes, auth_url, token_url, user_info_url, end_session_url, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $10, NOW())
 		ON CONFLICT (provider) DO UPDATE SET
 			client_id = EXCLUDED.client_id,
-			client_secret = EXCLUDED.client_secret,
+			-- An empty incoming secret keeps the stored one, so a blank form field never clears credentials
+			client_secret = CASE WHEN $9 THEN EXCLUDED.client_secret ELSE oauth_providers.client_secret END,
 			enabled = EXCLUDED.enabled,
 			scopes = EXCLUDED.scopes,
 			auth_url = EXCLUDED.auth_url,
 			token_url = EXCLUDED.token_url,
 			user_info_url = EXCLUDED.user_info_url,
+			end_session_url = EXCLUDED.end_session_url,
 			updated_at = NOW()
 	`
 
@@ -161,6 +186,8 @@ func (r *OAuthRepository) UpdateProvider(ctx context.Context, provider *domain.O
 		provider.AuthURL,
 		provider.TokenURL,
 		provider.UserInfoURL,
+		provider.ClientSecret != "",
+		provider.EndSessionURL,
 	)
 
 	if err != nil {
@@ -211,9 +238,42 @@ func (r *OAuthRepository) CreateUserOAuth(ctx context.Context, userOAuth *domain
 	return nil
 }
 
+// UpdateUserOAuth re-encrypts and stores the link's access token, refresh token and expiry,
+// returning domain.ErrNotFound if the link no longer exists.
+func (r *OAuthRepository) UpdateUserOAuth(ctx context.Context, userOAuth *domain.UserOAuth) error {
+	query := `
+		UPDATE user_oauths
+		SET access_token = $1, refresh_token = $2, expires_at = $3
+		WHERE id = $4
+	`
+
+	encAccessToken, err := encryption.Encrypt(userOAuth.AccessToken, r.authSecret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+
+	encRefreshToken := ""
+	if userOAuth.RefreshToken != "" {
+		enc, err := encryption.Encrypt(userOAuth.RefreshToken, r.authSecret)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt refresh token: %w", err)
+		}
+		encRefreshToken = enc
+	}
+
+	tag, err := r.db.Pool.Exec(ctx, query, encAccessToken, encRefreshToken, userOAuth.ExpiresAt, userOAuth.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user oauth: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
 func (r *OAuthRepository) GetUserOAuth(ctx context.Context, provider domain.OAuthProviderType, providerUserID string) (*domain.UserOAuth, error) {
 	query := `
-		SELECT id, user_id, provider, provider_user_id, access_token, refresh_token, expires_at, created_at
+		SELECT id, user_id, provider, provider_user_id, COALESCE(access_token, ''), COALESCE(refresh_token, ''), expires_at, created_at
 		FROM user_oauths
 		WHERE provider = $1 AND provider_user_id = $2
 	`
@@ -237,27 +297,14 @@ func (r *OAuthRepository) GetUserOAuth(ctx context.Context, provider domain.OAut
 		return nil, fmt.Errorf("failed to get user oauth: %w", err)
 	}
 
-	// Decrypt tokens (silently ignore errors for robust reading? or error out?)
-	// Let's decrypt if present.
-	if u.AccessToken != "" {
-		dec, err := encryption.Decrypt(u.AccessToken, r.authSecret)
-		if err == nil {
-			u.AccessToken = dec
-		}
-	}
-	if u.RefreshToken != "" {
-		dec, err := encryption.Decrypt(u.RefreshToken, r.authSecret)
-		if err == nil {
-			u.RefreshToken = dec
-		}
-	}
+	r.decryptUserOAuth(&u)
 
 	return &u, nil
 }
 
 func (r *OAuthRepository) GetUserOAuthByUserID(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) (*domain.UserOAuth, error) {
 	query := `
-		SELECT id, user_id, provider, provider_user_id, access_token, refresh_token, expires_at, created_at
+		SELECT id, user_id, provider, provider_user_id, COALESCE(access_token, ''), COALESCE(refresh_token, ''), expires_at, created_at
 		FROM user_oauths
 		WHERE user_id = $1 AND provider = $2
 	`
@@ -281,18 +328,55 @@ func (r *OAuthRepository) GetUserOAuthByUserID(ctx context.Context, userID uuid.
 		return nil, fmt.Errorf("failed to get user oauth by user id: %w", err)
 	}
 
-	if u.AccessToken != "" {
-		dec, err := encryption.Decrypt(u.AccessToken, r.authSecret)
-		if err == nil {
-			u.AccessToken = dec
-		}
+	r.decryptUserOAuth(&u)
+
+	return &u, nil
+}
+
+func (r *OAuthRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.UserOAuth, error) {
+	// Tokens are deliberately not selected; callers only need to know which providers are linked
+	query := `
+		SELECT id, user_id, provider, provider_user_id, expires_at, created_at
+		FROM user_oauths
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user oauths: %w", err)
 	}
-	if u.RefreshToken != "" {
-		dec, err := encryption.Decrypt(u.RefreshToken, r.authSecret)
-		if err == nil {
-			u.RefreshToken = dec
+	defer rows.Close()
+
+	var links []*domain.UserOAuth
+	for rows.Next() {
+		var u domain.UserOAuth
+		if err := rows.Scan(
+			&u.ID,
+			&u.UserID,
+			&u.Provider,
+			&u.ProviderUserID,
+			&u.ExpiresAt,
+			&u.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user oauth: %w", err)
 		}
+		links = append(links, &u)
 	}
 
-	return &u, nil
+	return links, rows.Err()
+}
+
+// DeleteUserOAuth removes a user's link to a provider, returning domain.ErrNotFound if there is none.
+func (r *OAuthRepository) DeleteUserOAuth(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) error {
+	query := `DELETE FROM user_oauths WHERE user_id = $1 AND provider = $2`
+
+	tag, err := r.db.Pool.Exec(ctx, query, userID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to delete user oauth: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
 }
@@ -3,39 +3,74 @@ package postgres
 
 import (
 	"embed"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
-// GetMigrations returns all migration SQL statements from the embedded schema files.
-func GetMigrations() ([]string, error) {
+// Migration is one numbered schema change, read from NNN_name.up.sql (or NNN_name.sql)
+// and its optional NNN_name.down.sql counterpart.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	// Down reverts Up; empty when there is no down file and the migration cannot be rolled back
+	Down string
+}
+
+// LoadMigrations returns the embedded migrations ordered by version.
+func LoadMigrations() ([]Migration, error) {
 	entries, err := migrationsFS.ReadDir("migrations")
 	if err != nil {
 		return nil, err
 	}
 
-	var migrations []string
-
-	// Basic sort is provided by ReadDir (lexical), which works for 001, 002 prefix.
+	byVersion := make(map[int]*Migration)
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
 			continue
 		}
 
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		down := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(strings.TrimSuffix(base, ".down"), ".up")
+
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", entry.Name())
+		}
+
 		content, err := migrationsFS.ReadFile("migrations/" + entry.Name())
 		if err != nil {
 			return nil, err
 		}
 
-		// Don't split by semicolon. pgx.Exec handles multiple statements (or we rely on it for DO blocks).
-		// We return the whole file as one migration step.
-		strContent := string(content)
-		if strings.TrimSpace(strContent) != "" {
-			migrations = append(migrations, strContent)
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		// pgx runs a whole file as one multi-statement Exec, so DO blocks need no splitting
+		if down {
+			m.Down = string(content)
+		} else {
+			m.Up = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no up migration", m.Version, m.Name)
 		}
+		migrations = append(migrations, *m)
 	}
+	sort.Slice(migrations, func(a, b int) bool { return migrations[a].Version < migrations[b].Version })
 
 	return migrations, nil
 }
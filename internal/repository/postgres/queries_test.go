@@ -0,0 +1,48 @@
+package postgres
+
+import "testing"
+
+// firstReversible is the oldest migration with a down file; everything before it
+// predates versioned migrations and can't be rolled back.
+const firstReversible = 4
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations() = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("LoadMigrations() returned no migrations")
+	}
+
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("migration %03d_%s is out of order", m.Version, m.Name)
+		}
+		if m.Name == "" {
+			t.Errorf("migration %03d has no name", m.Version)
+		}
+		if m.Version >= firstReversible && m.Down == "" {
+			t.Errorf("migration %03d_%s has no down migration", m.Version, m.Name)
+		}
+	}
+}
+
+func TestCommentsOnly(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{sql: "", want: true},
+		{sql: "-- Nothing to undo\n\n  -- still nothing\n", want: true},
+		{sql: "DROP TABLE IF EXISTS email_changes;", want: false},
+		{sql: "-- Drop the table\nDROP TABLE IF EXISTS email_changes;", want: false},
+		{sql: "DROP TABLE t; -- trailing comment", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := commentsOnly(tt.sql); got != tt.want {
+			t.Errorf("commentsOnly(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
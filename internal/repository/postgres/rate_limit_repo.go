@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitRepository stores rate limiter token buckets in PostgreSQL so that every
+// instance behind a load balancer shares the same limits.
+type RateLimitRepository struct {
+	db *DB
+}
+
+// NewRateLimitRepository creates a new PostgreSQL rate limit repository.
+func NewRateLimitRepository(db *DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Take atomically refills key's bucket for the time since it was last updated and
+// consumes one token, reporting false if less than one token is available, along
+// with the tokens left. A denied request leaves the row untouched, since refill is
+// computed lazily, and reports no whole tokens remaining. Each write also records
+// when the bucket will be full again, for Cleanup.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, limit rate.Limit, burst int) (bool, float64, error) {
+	query := `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at, full_at)
+		VALUES ($1, $3 - 1, NOW(), NOW() + make_interval(secs => 1 / NULLIF($2::double precision, 0)))
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = LEAST($3, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $2) - 1,
+			updated_at = NOW(),
+			full_at = NOW() + make_interval(secs =>
+				($3 - LEAST($3, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $2) + 1) / NULLIF($2::double precision, 0))
+		WHERE LEAST($3, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $2) >= 1
+		RETURNING tokens
+	`
+
+	var tokens float64
+	err := r.db.Pool.QueryRow(ctx, query, key, float64(limit), float64(burst)).Scan(&tokens)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	return true, tokens, nil
+}
+
+// Tokens returns key's bucket refilled up to now without consuming a token. A key
+// with no row has a full bucket.
+func (r *RateLimitRepository) Tokens(ctx context.Context, key string, limit rate.Limit, burst int) (float64, error) {
+	query := `
+		SELECT LEAST($3, tokens + EXTRACT(EPOCH FROM NOW() - updated_at) * $2)
+		FROM rate_limit_buckets
+		WHERE key = $1
+	`
+
+	var tokens float64
+	err := r.db.Pool.QueryRow(ctx, query, key, float64(limit), float64(burst)).Scan(&tokens)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return float64(burst), nil
+		}
+		return 0, err
+	}
+	return tokens, nil
+}
+
+// Snapshot returns the buckets whose key starts with prefix, with their tokens refilled up
+// to now and the prefix removed from the key, most recently used first.
+func (r *RateLimitRepository) Snapshot(ctx context.Context, prefix string, limit rate.Limit, burst int) ([]domain.RateLimitEntry, error) {
+	query := `
+		SELECT substr(key, length($3) + 1), LEAST($2, tokens + EXTRACT(EPOCH FROM NOW() - updated_at) * $1), updated_at
+		FROM rate_limit_buckets
+		WHERE starts_with(key, $3)
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, float64(limit), float64(burst), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.RateLimitEntry
+	for rows.Next() {
+		e := domain.RateLimitEntry{Burst: burst}
+		if err := rows.Scan(&e.IP, &e.Tokens, &e.LastSeen); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Cleanup deletes buckets that have refilled, since a missing row behaves the same as a
+// full bucket. Buckets still refilling are kept however long ago they were used, so a
+// limiter with a slow rate doesn't hand a throttled client a fresh burst.
+func (r *RateLimitRepository) Cleanup(ctx context.Context) error {
+	query := `DELETE FROM rate_limit_buckets WHERE full_at <= NOW()`
+	_, err := r.db.Pool.Exec(ctx, query)
+	return err
+}
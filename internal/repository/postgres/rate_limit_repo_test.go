@@ -0,0 +1,68 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres/postgrestest"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitRepository_SnapshotFiltersByPrefix(t *testing.T) {
+	db := postgrestest.New(t)
+	store := postgres.NewRateLimitRepository(db)
+	ctx := context.Background()
+
+	for _, key := range []string{"auth:203.0.113.7", "auth:2001:db8::1", "reset:198.51.100.4", "email:user@example.com"} {
+		if _, _, err := store.Take(ctx, key, rate.Every(time.Minute), 5); err != nil {
+			t.Fatalf("Take(%s) = %v", key, err)
+		}
+	}
+
+	entries, err := store.Snapshot(ctx, "auth:", rate.Every(time.Minute), 5)
+	if err != nil {
+		t.Fatalf("Snapshot() = %v", err)
+	}
+	got := map[string]bool{}
+	for _, e := range entries {
+		got[e.IP] = true
+	}
+	if len(entries) != 2 || !got["203.0.113.7"] || !got["2001:db8::1"] {
+		t.Errorf("Snapshot(auth:) = %+v, want the two auth IPs without their prefix", entries)
+	}
+}
+
+func TestRateLimitRepository_CleanupDeletesOnlyFullBuckets(t *testing.T) {
+	db := postgrestest.New(t)
+	store := postgres.NewRateLimitRepository(db)
+	ctx := context.Background()
+
+	// A fast limiter refills almost at once; a slow one is still refilling long after
+	// the hour the old cleanup waited.
+	if _, _, err := store.Take(ctx, "fast", 1000, 1); err != nil {
+		t.Fatalf("Take(fast) = %v", err)
+	}
+	if _, _, err := store.Take(ctx, "slow", rate.Every(24*time.Hour), 1); err != nil {
+		t.Fatalf("Take(slow) = %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx, `UPDATE rate_limit_buckets SET updated_at = updated_at - INTERVAL '2 hours'`); err != nil {
+		t.Fatalf("backdate buckets: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if err := store.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup() = %v", err)
+	}
+
+	for key, want := range map[string]bool{"fast": false, "slow": true} {
+		var exists bool
+		if err := db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM rate_limit_buckets WHERE key = $1)`, key).Scan(&exists); err != nil {
+			t.Fatalf("look up %s: %v", key, err)
+		}
+		if exists != want {
+			t.Errorf("bucket %q kept = %v, want %v", key, exists, want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -23,8 +24,8 @@ func NewSessionRepository(db *DB) *SessionRepository {
 // Create inserts a new session into the database.
 func (r *SessionRepository) Create(ctx context.Context, session *domain.Session) error {
 	query := `
-		INSERT INTO sessions (id, user_id, expires_at, created_at, ip_address, user_agent, last_activity_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO sessions (id, user_id, expires_at, created_at, ip_address, user_agent, last_activity_at, auth_provider)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
 	_, err := r.db.Pool.Exec(ctx, query,
@@ -35,6 +36,7 @@ func (r *SessionRepository) Create(ctx context.Context, session *domain.Session)
 		session.IPAddress,
 		session.UserAgent,
 		session.LastActivityAt,
+		session.AuthProvider,
 	)
 
 	return err
@@ -43,7 +45,7 @@ func (r *SessionRepository) Create(ctx context.Context, session *domain.Session)
 // GetByID retrieves a session by its ID.
 func (r *SessionRepository) GetByID(ctx context.Context, id string) (*domain.Session, error) {
 	query := `
-		SELECT id, user_id, expires_at, created_at, ip_address, user_agent, last_activity_at
+		SELECT id, user_id, expires_at, created_at, ip_address, user_agent, last_activity_at, auth_provider
 		FROM sessions
 		WHERE id = $1
 	`
@@ -57,6 +59,7 @@ func (r *SessionRepository) GetByID(ctx context.Context, id string) (*domain.Ses
 		&session.IPAddress,
 		&session.UserAgent,
 		&session.LastActivityAt,
+		&session.AuthProvider,
 	)
 
 	if err != nil {
@@ -69,6 +72,42 @@ func (r *SessionRepository) GetByID(ctx context.Context, id string) (*domain.Ses
 	return session, nil
 }
 
+// ListByUserID returns a user's unexpired sessions, most recently active first.
+func (r *SessionRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.Session, error) {
+	query := `
+		SELECT id, user_id, expires_at, created_at, ip_address, user_agent, last_activity_at, auth_provider
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_activity_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*domain.Session
+	for rows.Next() {
+		session := &domain.Session{}
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.ExpiresAt,
+			&session.CreatedAt,
+			&session.IPAddress,
+			&session.UserAgent,
+			&session.LastActivityAt,
+			&session.AuthProvider,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
 // Delete removes a session by its ID.
 func (r *SessionRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM sessions WHERE id = $1`
@@ -83,11 +122,14 @@ func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID
 	return err
 }
 
-// DeleteExpired removes all expired sessions.
-func (r *SessionRepository) DeleteExpired(ctx context.Context) error {
+// DeleteExpired removes all expired sessions and returns how many were removed.
+func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
 	query := `DELETE FROM sessions WHERE expires_at < NOW()`
-	_, err := r.db.Pool.Exec(ctx, query)
-	return err
+	tag, err := r.db.Pool.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
 
 // CountActive returns the number of active (non-expired) sessions.
@@ -100,3 +142,51 @@ func (r *SessionRepository) CountActive(ctx context.Context) (int64, error) {
 	}
 	return count, nil
 }
+
+// Touch sets a session's last activity time to now.
+func (r *SessionRepository) Touch(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET last_activity_at = NOW() WHERE id = $1`
+	_, err := r.db.Pool.Exec(ctx, query, id)
+	return err
+}
+
+// UpdateExpiry moves a session's expiry and records it as just used.
+func (r *SessionRepository) UpdateExpiry(ctx context.Context, id string, expiresAt time.Time) error {
+	query := `UPDATE sessions SET expires_at = $2, last_activity_at = NOW() WHERE id = $1`
+	_, err := r.db.Pool.Exec(ctx, query, id, expiresAt)
+	return err
+}
+
+// ListOnline returns one row per user with a non-expired session active since the given time.
+// The IP address is the one from the user's most recently active session.
+func (r *SessionRepository) ListOnline(ctx context.Context, since time.Time) ([]*domain.OnlineUser, error) {
+	query := `
+		SELECT u.id, u.name, u.role, COALESCE(s.ip_address, ''), s.last_activity_at, s.session_count
+		FROM (
+			SELECT DISTINCT ON (user_id) user_id, ip_address, last_activity_at,
+			       COUNT(*) OVER (PARTITION BY user_id) AS session_count
+			FROM sessions
+			WHERE expires_at > NOW() AND last_activity_at >= $1
+			ORDER BY user_id, last_activity_at DESC
+		) s
+		JOIN users u ON u.id = s.user_id
+		ORDER BY s.last_activity_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var online []*domain.OnlineUser
+	for rows.Next() {
+		u := &domain.OnlineUser{}
+		if err := rows.Scan(&u.UserID, &u.Name, &u.Role, &u.IPAddress, &u.LastActivityAt, &u.Sessions); err != nil {
+			return nil, err
+		}
+		online = append(online, u)
+	}
+
+	return online, rows.Err()
+}
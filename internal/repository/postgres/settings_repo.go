@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+)
+
+// SettingsRepository stores editable application settings as key/value pairs.
+type SettingsRepository struct {
+	db *DB
+}
+
+// NewSettingsRepository creates a new settings repository.
+func NewSettingsRepository(db *DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// GetAll returns every stored setting.
+func (r *SettingsRepository) GetAll(ctx context.Context) (map[string]string, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT key, value FROM app_settings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+
+	return values, rows.Err()
+}
+
+// SetMany upserts the given settings in a single transaction.
+func (r *SettingsRepository) SetMany(ctx context.Context, values map[string]string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO app_settings (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()
+	`
+	for key, value := range values {
+		if _, err := tx.Exec(ctx, query, key, value); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
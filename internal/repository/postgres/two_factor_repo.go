@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/encryption"
+)
+
+// TwoFactorRepository implements the repository.TwoFactorRepository interface. TOTP secrets
+// are encrypted with AUTH_SECRET, like OAuth credentials.
+type TwoFactorRepository struct {
+	db         *DB
+	authSecret string
+}
+
+// NewTwoFactorRepository creates a new PostgreSQL two-factor repository.
+func NewTwoFactorRepository(db *DB, authSecret string) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db, authSecret: authSecret}
+}
+
+// Get retrieves a user's TOTP secret.
+func (r *TwoFactorRepository) Get(ctx context.Context, userID uuid.UUID) (*domain.TOTPSecret, error) {
+	query := `
+		SELECT user_id, secret_encrypted, enabled_at, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+	secret := &domain.TOTPSecret{}
+	var encrypted string
+	err := r.db.Pool.QueryRow(ctx, query, userID).Scan(
+		&secret.UserID,
+		&encrypted,
+		&secret.EnabledAt,
+		&secret.LastUsedStep,
+		&secret.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if secret.Secret, err = encryption.Decrypt(encrypted, r.authSecret); err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret - AUTH_SECRET may have changed: %w", err)
+	}
+	return secret, nil
+}
+
+// SavePending stores a new secret awaiting confirmation, replacing any existing one.
+func (r *TwoFactorRepository) SavePending(ctx context.Context, secret *domain.TOTPSecret) error {
+	encrypted, err := encryption.Encrypt(secret.Secret, r.authSecret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_totp (user_id, secret_encrypted, enabled_at, last_used_step, created_at)
+		VALUES ($1, $2, NULL, 0, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, enabled_at = NULL, last_used_step = 0, created_at = EXCLUDED.created_at
+	`
+	_, err = r.db.Pool.Exec(ctx, query, secret.UserID, encrypted, secret.CreatedAt)
+	return err
+}
+
+// Enable marks the user's secret as confirmed.
+func (r *TwoFactorRepository) Enable(ctx context.Context, userID uuid.UUID, step int64) error {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE user_totp SET enabled_at = NOW(), last_used_step = $2
+		WHERE user_id = $1
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// UseStep records step as used if it is newer than the last used one. The check and
+// update are a single statement, so two requests can't both use the same code.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// Delete removes the user's secret and recovery codes.
+func (r *TwoFactorRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete totp secret: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes for the given hashes in one transaction.
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range hashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseRecoveryCode marks an unused code as used.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	result, err := r.db.Pool.Exec(ctx, `
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, hash)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left.
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	return count, err
+}
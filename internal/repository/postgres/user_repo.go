@@ -4,6 +4,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -24,12 +25,18 @@ func NewUserRepository(db *DB) *UserRepository {
 
 // Create inserts a new user into the database.
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
+	return insertUser(ctx, r.db.Pool, user)
+}
+
+// insertUser inserts user with q, which may be the pool or a transaction. A duplicate
+// email or username is reported as domain.ErrConflict.
+func insertUser(ctx context.Context, q pgxQuerier, user *domain.User) error {
 	query := `
-		INSERT INTO users (id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO users (id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
-	_, err := r.db.Pool.Exec(ctx, query,
+	_, err := q.Exec(ctx, query,
 		user.ID,
 		user.Email,
 		user.Name,
@@ -42,6 +49,8 @@ func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 		user.VerificationToken,
 		user.VerificationTokenExpiresAt,
 		user.ProfileMediaID,
+		user.Username,
+		user.ActivityDigestEnabled,
 	)
 
 	if err != nil {
@@ -54,10 +63,41 @@ func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	return nil
 }
 
+// firstUserLockKey is the advisory lock key serializing first-user role assignment.
+const firstUserLockKey = 727001
+
+// CreateWithFirstUserRole inserts a new user, promoting them to super admin if no users exist yet.
+// An advisory transaction lock serializes the emptiness check and insert across connections.
+func (r *UserRepository) CreateWithFirstUserRole(ctx context.Context, user *domain.User) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, firstUserLockKey); err != nil {
+		return fmt.Errorf("failed to acquire first user lock: %w", err)
+	}
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users)`).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check for existing users: %w", err)
+	}
+	if !exists {
+		user.Role = domain.RoleSuperAdmin
+	}
+
+	if err := insertUser(ctx, tx, user); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 // GetByID retrieves a user by their unique identifier.
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	query := `
-		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id
+		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled
 		FROM users
 		WHERE id = $1
 	`
@@ -76,6 +116,8 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Use
 		&user.VerificationToken,
 		&user.VerificationTokenExpiresAt,
 		&user.ProfileMediaID,
+		&user.Username,
+		&user.ActivityDigestEnabled,
 	)
 
 	if err != nil {
@@ -91,13 +133,49 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Use
 // GetByEmail retrieves a user by their email address.
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	query := `
-		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id
+		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled
 		FROM users
 		WHERE email = $1
 	`
 
 	user := &domain.User{}
-	err := r.db.Pool.QueryRow(ctx, query, email).Scan(
+	err := r.db.Pool.QueryRow(ctx, query, domain.NormalizeEmail(email)).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.PasswordHash,
+		&user.Role,
+		&user.Status,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.EmailVerified,
+		&user.VerificationToken,
+		&user.VerificationTokenExpiresAt,
+		&user.ProfileMediaID,
+		&user.Username,
+		&user.ActivityDigestEnabled,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetByUsername retrieves a user by their username, ignoring case.
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	query := `
+		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled
+		FROM users
+		WHERE LOWER(username) = LOWER($1)
+	`
+
+	user := &domain.User{}
+	err := r.db.Pool.QueryRow(ctx, query, username).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Name,
@@ -110,6 +188,8 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 		&user.VerificationToken,
 		&user.VerificationTokenExpiresAt,
 		&user.ProfileMediaID,
+		&user.Username,
+		&user.ActivityDigestEnabled,
 	)
 
 	if err != nil {
@@ -125,7 +205,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 // GetByVerificationToken retrieves a user by their verification token.
 func (r *UserRepository) GetByVerificationToken(ctx context.Context, token string) (*domain.User, error) {
 	query := `
-		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id
+		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled
 		FROM users
 		WHERE verification_token = $1
 	`
@@ -144,6 +224,8 @@ func (r *UserRepository) GetByVerificationToken(ctx context.Context, token strin
 		&user.VerificationToken,
 		&user.VerificationTokenExpiresAt,
 		&user.ProfileMediaID,
+		&user.Username,
+		&user.ActivityDigestEnabled,
 	)
 
 	if err != nil {
@@ -159,7 +241,7 @@ func (r *UserRepository) GetByVerificationToken(ctx context.Context, token strin
 // List retrieves all users with pagination.
 func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*domain.User, error) {
 	query := `
-		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id
+		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled
 		FROM users
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -187,6 +269,8 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*domain
 			&user.VerificationToken,
 			&user.VerificationTokenExpiresAt,
 			&user.ProfileMediaID,
+			&user.Username,
+			&user.ActivityDigestEnabled,
 		); err != nil {
 			return nil, err
 		}
@@ -200,13 +284,116 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*domain
 	return users, nil
 }
 
+// ListActiveByRole retrieves active users with exactly the given role, oldest first.
+func (r *UserRepository) ListActiveByRole(ctx context.Context, role domain.Role) ([]*domain.User, error) {
+	query := `
+		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled
+		FROM users
+		WHERE role = $1 AND status = $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, role, domain.UserStatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users by role: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user := &domain.User{}
+		if err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.PasswordHash,
+			&user.Role,
+			&user.Status,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.EmailVerified,
+			&user.VerificationToken,
+			&user.VerificationTokenExpiresAt,
+			&user.ProfileMediaID,
+			&user.Username,
+			&user.ActivityDigestEnabled,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ListActivityDigestRecipients retrieves active users who opted in to the activity digest
+// and have not been sent one since the given time.
+func (r *UserRepository) ListActivityDigestRecipients(ctx context.Context, sentBefore time.Time) ([]*domain.User, error) {
+	query := `
+		SELECT id, email, name, password_hash, role, status, created_at, updated_at, email_verified, verification_token, verification_token_expires_at, profile_media_id, username, activity_digest_enabled
+		FROM users
+		WHERE activity_digest_enabled AND status = $1
+		AND (activity_digest_sent_at IS NULL OR activity_digest_sent_at < $2)
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, domain.UserStatusActive, sentBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user := &domain.User{}
+		if err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.PasswordHash,
+			&user.Role,
+			&user.Status,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.EmailVerified,
+			&user.VerificationToken,
+			&user.VerificationTokenExpiresAt,
+			&user.ProfileMediaID,
+			&user.Username,
+			&user.ActivityDigestEnabled,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// MarkActivityDigestSent records when the user was last sent an activity digest.
+func (r *UserRepository) MarkActivityDigestSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error {
+	_, err := r.db.Pool.Exec(ctx, `UPDATE users SET activity_digest_sent_at = $2 WHERE id = $1`, id, sentAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark activity digest sent: %w", err)
+	}
+	return nil
+}
+
 // Update modifies an existing user in the database.
 func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	user.UpdatedAt = time.Now()
 
 	query := `
 		UPDATE users
-		SET email = $2, name = $3, password_hash = $4, role = $5, status = $6, updated_at = $7, email_verified = $8, verification_token = $9, verification_token_expires_at = $10, profile_media_id = $11
+		SET email = $2, name = $3, password_hash = $4, role = $5, status = $6, updated_at = $7, email_verified = $8, verification_token = $9, verification_token_expires_at = $10, profile_media_id = $11, username = $12, activity_digest_enabled = $13
 		WHERE id = $1
 	`
 
@@ -222,6 +409,8 @@ func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 		user.VerificationToken,
 		user.VerificationTokenExpiresAt,
 		user.ProfileMediaID,
+		user.Username,
+		user.ActivityDigestEnabled,
 	)
 
 	if err != nil {
@@ -238,11 +427,35 @@ func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	return nil
 }
 
-// Delete removes a user from the database.
+// Delete removes a user from the database along with their uploaded media. Rows that hang off
+// the user, such as OAuth links, sessions and their blog posts, go with it through ON DELETE
+// CASCADE. Everything runs in one transaction so a failed delete leaves no partial cleanup behind.
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM users WHERE id = $1`
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// media.user_id is ON DELETE SET NULL, so note the user's uploads before the user goes
+	var mediaIDs []uuid.UUID
+	rows, err := tx.Query(ctx, `SELECT id FROM media WHERE user_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to list user media: %w", err)
+	}
+	for rows.Next() {
+		var mediaID uuid.UUID
+		if err := rows.Scan(&mediaID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan user media: %w", err)
+		}
+		mediaIDs = append(mediaIDs, mediaID)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list user media: %w", err)
+	}
 
-	result, err := r.db.Pool.Exec(ctx, query, id)
+	result, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
 	if err != nil {
 		return err
 	}
@@ -251,7 +464,64 @@ func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 		return domain.ErrNotFound
 	}
 
-	return nil
+	// Remove the uploads instead of leaving them behind as orphans, except those another
+	// user's post or profile still shows. The user's own posts are already gone by now.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM media m
+		WHERE m.id = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM blogs b WHERE b.cover_media_id = m.id)
+		  AND NOT EXISTS (SELECT 1 FROM blog_images bi WHERE bi.media_id = m.id)
+		  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.profile_media_id = m.id)
+	`, mediaIDs); err != nil {
+		return fmt.Errorf("failed to delete user media: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RevokeCredentials invalidates everything that grants access to the user's account in one
+// transaction: sessions, password reset tokens, the pending verification token and stored
+// OAuth provider tokens. OAuth links themselves are kept so the user can still sign in with them.
+func (r *UserRepository) RevokeCredentials(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users
+		SET verification_token = NULL, verification_token_expires_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to clear verification token: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM sessions WHERE user_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM password_reset_tokens WHERE user_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to revoke password reset tokens: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM email_changes WHERE user_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to revoke email change links: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE user_oauths
+		SET access_token = NULL, refresh_token = NULL, expires_at = NULL
+		WHERE user_id = $1
+	`, id); err != nil {
+		return fmt.Errorf("failed to revoke oauth tokens: %w", err)
+	}
+
+	return tx.Commit(ctx)
 }
 
 // Count returns the total number of users.
@@ -0,0 +1,231 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres/postgrestest"
+)
+
+// countRows returns how many rows of table have column equal to id.
+func countRows(t *testing.T, db *postgres.DB, table, column string, id uuid.UUID) int {
+	t.Helper()
+	var n int
+	if err := db.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+table+" WHERE "+column+" = $1", id).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+// createUser inserts an active user with the given email.
+func createUser(t *testing.T, repo *postgres.UserRepository, email string) *domain.User {
+	t.Helper()
+	user := domain.NewUser(email, "Test User", "hash", domain.RoleUser)
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return user
+}
+
+func TestUserRepository_DeleteLeavesNoOrphans(t *testing.T) {
+	db := postgrestest.New(t)
+	ctx := context.Background()
+	users := postgres.NewUserRepository(db)
+	oauth := postgres.NewOAuthRepository(db, "test-secret")
+	media := postgres.NewMediaRepository(db)
+	sessions := postgres.NewSessionRepository(db)
+	activity := postgres.NewActivityLogRepository(db)
+
+	user := createUser(t, users, "deleted@example.com")
+	other := createUser(t, users, "kept@example.com")
+
+	for _, u := range []*domain.User{user, other} {
+		if err := oauth.CreateUserOAuth(ctx, &domain.UserOAuth{UserID: u.ID, Provider: domain.OAuthProviderGoogle, ProviderUserID: u.Email, AccessToken: "token"}); err != nil {
+			t.Fatalf("create oauth link: %v", err)
+		}
+		if _, err := media.Create(ctx, domain.CreateMediaInput{UserID: &u.ID, Filename: "avatar.png", Data: []byte("png"), ContentType: "image/png", SizeBytes: 3, StorageProvider: domain.StorageProviderDatabase}); err != nil {
+			t.Fatalf("create media: %v", err)
+		}
+		if err := sessions.Create(ctx, domain.NewSession(u.ID, "127.0.0.1", "test")); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		if err := activity.Create(ctx, &domain.ActivityLog{UserID: u.ID, ActivityType: domain.ActivityLogin, Description: "Signed in"}); err != nil {
+			t.Fatalf("create activity: %v", err)
+		}
+	}
+
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+
+	owned := []struct{ table, column string }{
+		{"users", "id"},
+		{"user_oauths", "user_id"},
+		{"media", "user_id"},
+		{"sessions", "user_id"},
+		{"activity_logs", "user_id"},
+	}
+	for _, o := range owned {
+		if n := countRows(t, db, o.table, o.column, user.ID); n != 0 {
+			t.Errorf("%s still has %d rows for the deleted user", o.table, n)
+		}
+		if n := countRows(t, db, o.table, o.column, other.ID); n != 1 {
+			t.Errorf("%s has %d rows for the other user, want 1", o.table, n)
+		}
+	}
+
+	// Media rows are deleted rather than left with a NULL uploader
+	var orphans int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM media WHERE user_id IS NULL`).Scan(&orphans); err != nil {
+		t.Fatalf("count orphaned media: %v", err)
+	}
+	if orphans != 0 {
+		t.Errorf("%d media rows were left without an uploader", orphans)
+	}
+}
+
+func TestUserRepository_DeleteKeepsMediaOthersUse(t *testing.T) {
+	db := postgrestest.New(t)
+	ctx := context.Background()
+	users := postgres.NewUserRepository(db)
+	media := postgres.NewMediaRepository(db)
+	blogs := postgres.NewBlogRepository(db)
+
+	user := createUser(t, users, "deleted@example.com")
+	other := createUser(t, users, "kept@example.com")
+
+	upload := func(name string) uuid.UUID {
+		t.Helper()
+		m, err := media.Create(ctx, domain.CreateMediaInput{UserID: &user.ID, Filename: name, Data: []byte("png"), ContentType: "image/png", SizeBytes: 3, StorageProvider: domain.StorageProviderDatabase})
+		if err != nil {
+			t.Fatalf("create media: %v", err)
+		}
+		return m.ID
+	}
+	post := func(author uuid.UUID, slug string, cover uuid.UUID) {
+		t.Helper()
+		now := time.Now()
+		if err := blogs.Create(ctx, &domain.Blog{ID: uuid.New(), Title: slug, Slug: slug, Content: "<p>Hi</p>", AuthorID: author, CoverMediaID: &cover, CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatalf("create blog: %v", err)
+		}
+	}
+
+	sharedCover := upload("shared.png")
+	post(other.ID, "others-post", sharedCover)
+	ownCover := upload("own.png")
+	post(user.ID, "own-post", ownCover)
+	avatar := upload("avatar.png")
+	if _, err := db.Pool.Exec(ctx, `UPDATE users SET profile_media_id = $1 WHERE id = $2`, avatar, other.ID); err != nil {
+		t.Fatalf("set profile media: %v", err)
+	}
+	unused := upload("unused.png")
+
+	if err := users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   uuid.UUID
+		want int
+	}{
+		{name: "cover of another author's post", id: sharedCover, want: 1},
+		{name: "another user's profile picture", id: avatar, want: 1},
+		{name: "cover of the deleted user's own post", id: ownCover, want: 0},
+		{name: "unreferenced upload", id: unused, want: 0},
+	}
+	for _, tt := range tests {
+		if n := countRows(t, db, "media", "id", tt.id); n != tt.want {
+			t.Errorf("%s: %d media rows left, want %d", tt.name, n, tt.want)
+		}
+	}
+}
+
+func TestUserRepository_DeleteMissingUser(t *testing.T) {
+	db := postgrestest.New(t)
+	users := postgres.NewUserRepository(db)
+
+	if err := users.Delete(context.Background(), uuid.New()); !domain.IsNotFoundError(err) {
+		t.Errorf("Delete() of unknown user = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUserRepository_CreateWithFirstUserRoleConcurrent(t *testing.T) {
+	db := postgrestest.New(t)
+	users := postgres.NewUserRepository(db)
+	ctx := context.Background()
+
+	// Simultaneous first signups: exactly one may become super admin
+	const signups = 10
+	created := make([]*domain.User, signups)
+	errs := make(chan error, signups)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range signups {
+		created[i] = domain.NewUser(fmt.Sprintf("first%d@example.com", i), "First", "hash", domain.RoleUser)
+		wg.Add(1)
+		go func(user *domain.User) {
+			defer wg.Done()
+			<-start
+			errs <- users.CreateWithFirstUserRole(ctx, user)
+		}(created[i])
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("CreateWithFirstUserRole() = %v", err)
+		}
+	}
+
+	superAdmins := 0
+	for _, user := range created {
+		stored, err := users.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetByID() = %v", err)
+		}
+		if stored.Role != user.Role {
+			t.Errorf("user %s stored as %s but returned as %s", user.Email, stored.Role, user.Role)
+		}
+		if stored.Role == domain.RoleSuperAdmin {
+			superAdmins++
+		}
+	}
+	if superAdmins != 1 {
+		t.Errorf("%d of %d concurrent first signups became super admin, want exactly 1", superAdmins, signups)
+	}
+
+	// Later signups keep the role they asked for
+	later := domain.NewUser("later@example.com", "Later", "hash", domain.RoleUser)
+	if err := users.CreateWithFirstUserRole(ctx, later); err != nil {
+		t.Fatalf("CreateWithFirstUserRole() = %v", err)
+	}
+	if later.Role != domain.RoleUser {
+		t.Errorf("later signup got role %s, want %s", later.Role, domain.RoleUser)
+	}
+}
+
+func TestUserRepository_CreateDuplicateEmail(t *testing.T) {
+	db := postgrestest.New(t)
+	users := postgres.NewUserRepository(db)
+
+	createUser(t, users, "taken@example.com")
+	for name, create := range map[string]func(context.Context, *domain.User) error{
+		"Create":                  users.Create,
+		"CreateWithFirstUserRole": users.CreateWithFirstUserRole,
+	} {
+		user := domain.NewUser("taken@example.com", "Again", "hash", domain.RoleUser)
+		if err := create(context.Background(), user); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("%s() with a taken email = %v, want ErrConflict", name, err)
+		}
+	}
+}
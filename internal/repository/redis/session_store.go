@@ -0,0 +1,169 @@
+// Package redis provides Redis-backed caches in front of the PostgreSQL repositories.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	redisclient "github.com/noruj-official/full-stack-go-template/internal/pkg/redis"
+	"github.com/noruj-official/full-stack-go-template/internal/repository"
+)
+
+// SessionStore is a read-through Redis cache in front of another session store
+// (normally the PostgreSQL repository, which stays the source of truth). Sessions
+// are cached for at most ttl, and never past their own expiry. Redis failures are
+// logged and fall back to the underlying store.
+type SessionStore struct {
+	client *redisclient.Client
+	next   repository.SessionStore
+	ttl    time.Duration
+}
+
+// NewSessionStore creates a Redis-cached session store.
+func NewSessionStore(client *redisclient.Client, next repository.SessionStore, ttl time.Duration) *SessionStore {
+	return &SessionStore{client: client, next: next, ttl: ttl}
+}
+
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+// userSessionsKey holds the IDs of a user's cached sessions, so they can all be evicted at once.
+func userSessionsKey(userID uuid.UUID) string {
+	return "user_sessions:" + userID.String()
+}
+
+// Create stores the session and caches it.
+func (s *SessionStore) Create(ctx context.Context, session *domain.Session) error {
+	if err := s.next.Create(ctx, session); err != nil {
+		return err
+	}
+	s.cache(ctx, session)
+	return nil
+}
+
+// GetByID returns the cached session, loading and caching it from the underlying store on a miss.
+func (s *SessionStore) GetByID(ctx context.Context, id string) (*domain.Session, error) {
+	if session, ok := s.cached(ctx, id); ok {
+		return session, nil
+	}
+
+	session, err := s.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.cache(ctx, session)
+	return session, nil
+}
+
+// Touch records activity in the underlying store and refreshes the cached copy.
+func (s *SessionStore) Touch(ctx context.Context, id string) error {
+	if err := s.next.Touch(ctx, id); err != nil {
+		return err
+	}
+	if session, ok := s.cached(ctx, id); ok {
+		session.LastActivityAt = time.Now()
+		s.cache(ctx, session)
+	}
+	return nil
+}
+
+// ListByUserID lists the user's sessions from the underlying store, which holds every session.
+func (s *SessionStore) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.Session, error) {
+	return s.next.ListByUserID(ctx, userID)
+}
+
+// UpdateExpiry moves the session's expiry in the underlying store and keeps the cached copy in step.
+func (s *SessionStore) UpdateExpiry(ctx context.Context, id string, expiresAt time.Time) error {
+	if err := s.next.UpdateExpiry(ctx, id, expiresAt); err != nil {
+		return err
+	}
+	if session, ok := s.cached(ctx, id); ok {
+		session.ExpiresAt = expiresAt
+		session.LastActivityAt = time.Now()
+		s.cache(ctx, session)
+	}
+	return nil
+}
+
+// Delete removes the session from the underlying store and the cache.
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	if err := s.next.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.client.Del(ctx, sessionKey(id)); err != nil {
+		log.Printf("Failed to evict session from redis: %v", err)
+	}
+	return nil
+}
+
+// DeleteByUserID removes all of a user's sessions from the underlying store and the cache.
+func (s *SessionStore) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	if err := s.next.DeleteByUserID(ctx, userID); err != nil {
+		return err
+	}
+	s.Evict(ctx, userID)
+	return nil
+}
+
+// Evict drops every cached session for the user without touching the underlying
+// store, for callers that revoke sessions there directly.
+func (s *SessionStore) Evict(ctx context.Context, userID uuid.UUID) {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID))
+	if err != nil {
+		log.Printf("Failed to list cached sessions for user %s: %v", userID, err)
+		return
+	}
+
+	keys := []string{userSessionsKey(userID)}
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+	if err := s.client.Del(ctx, keys...); err != nil {
+		log.Printf("Failed to evict sessions for user %s: %v", userID, err)
+	}
+}
+
+func (s *SessionStore) cached(ctx context.Context, id string) (*domain.Session, bool) {
+	data, err := s.client.Get(ctx, sessionKey(id))
+	if err != nil {
+		if !errors.Is(err, redisclient.ErrNil) {
+			log.Printf("Failed to read session from redis: %v", err)
+		}
+		return nil, false
+	}
+
+	var session domain.Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, false
+	}
+	return &session, true
+}
+
+func (s *SessionStore) cache(ctx context.Context, session *domain.Session) {
+	ttl := min(s.ttl, time.Until(session.ExpiresAt))
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return
+	}
+
+	if err := s.client.Set(ctx, sessionKey(session.ID), string(data), ttl); err != nil {
+		log.Printf("Failed to cache session in redis: %v", err)
+		return
+	}
+
+	// The index only needs to outlive the sessions it lists
+	indexKey := userSessionsKey(session.UserID)
+	if err := s.client.SAdd(ctx, indexKey, session.ID); err == nil {
+		_ = s.client.Expire(ctx, indexKey, max(domain.SessionDuration, time.Until(session.ExpiresAt)))
+	}
+}
@@ -0,0 +1,89 @@
+// Package service implements the business logic layer.
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/repository"
+)
+
+// ActivityDigestJob periodically emails opted-in users a summary of their recent account activity.
+type ActivityDigestJob struct {
+	userRepo        repository.UserRepository
+	activityService ActivityService
+	emailService    EmailService
+	interval        time.Duration
+}
+
+// NewActivityDigestJob creates a digest job that sends each opted-in user one digest per interval.
+func NewActivityDigestJob(userRepo repository.UserRepository, activityService ActivityService, emailService EmailService, interval time.Duration) *ActivityDigestJob {
+	return &ActivityDigestJob{
+		userRepo:        userRepo,
+		activityService: activityService,
+		emailService:    emailService,
+		interval:        interval,
+	}
+}
+
+// Start runs the job in the background until ctx is cancelled.
+// Due users are checked at least hourly, and the last send time is stored per user,
+// so restarts neither skip nor repeat digests.
+func (j *ActivityDigestJob) Start(ctx context.Context) {
+	checkEvery := time.Hour
+	if j.interval < checkEvery {
+		checkEvery = j.interval
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkEvery)
+		defer ticker.Stop()
+
+		for {
+			j.RunOnce(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce sends digests to every opted-in user who is due one.
+func (j *ActivityDigestJob) RunOnce(ctx context.Context) {
+	now := time.Now()
+	since := now.Add(-j.interval)
+
+	users, err := j.userRepo.ListActivityDigestRecipients(ctx, since)
+	if err != nil {
+		log.Printf("Activity digest: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return
+		}
+
+		digest, err := j.activityService.GetDigest(ctx, user.ID, since, now)
+		if err != nil {
+			log.Printf("Activity digest for user %s: %v", user.ID, err)
+			continue
+		}
+
+		// Quiet periods are skipped but still marked, so the user is not rechecked every tick.
+		if !digest.IsEmpty() {
+			if err := j.emailService.SendActivityDigest(ctx, user.Email, user.Name, digest); err != nil {
+				log.Printf("Failed to send activity digest to user %s: %v", user.ID, err)
+				continue
+			}
+		}
+
+		if err := j.userRepo.MarkActivityDigestSent(ctx, user.ID, now); err != nil {
+			log.Printf("Activity digest for user %s: %v", user.ID, err)
+		}
+	}
+}
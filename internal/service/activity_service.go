@@ -4,6 +4,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
@@ -13,7 +14,9 @@ import (
 // ActivityService handles activity log operations.
 type ActivityService interface {
 	LogActivity(ctx context.Context, userID uuid.UUID, activityType domain.ActivityType, description string, ipAddress, userAgent *string) error
-	GetUserActivities(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.ActivityLog, error)
+	GetUserActivities(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.ActivityLog, int, error)
+	GetLastLogin(ctx context.Context, userID uuid.UUID) (*domain.ActivityLog, error)
+	GetDigest(ctx context.Context, userID uuid.UUID, since, until time.Time) (*domain.ActivityDigest, error)
 }
 
 type activityService struct {
@@ -44,20 +47,76 @@ func (s *activityService) LogActivity(ctx context.Context, userID uuid.UUID, act
 	return nil
 }
 
-// GetUserActivities retrieves user activities.
-func (s *activityService) GetUserActivities(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.ActivityLog, error) {
-	logs, err := s.activityRepo.ListByUser(ctx, userID, limit)
+// GetUserActivities retrieves a page of user activities along with the user's total activity count.
+func (s *activityService) GetUserActivities(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.ActivityLog, int, error) {
+	logs, err := s.activityRepo.ListByUser(ctx, userID, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user activities: %w", err)
+		return nil, 0, fmt.Errorf("failed to get user activities: %w", err)
 	}
 
-	return logs, nil
+	total, err := s.activityRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get user activities: %w", err)
+	}
+
+	return logs, total, nil
+}
+
+// GetLastLogin retrieves the user's most recent login, or nil if they have never signed in.
+func (s *activityService) GetLastLogin(ctx context.Context, userID uuid.UUID) (*domain.ActivityLog, error) {
+	log, err := s.activityRepo.GetLatestByType(ctx, userID, domain.ActivityLogin)
+	if err != nil {
+		if err == domain.ErrNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get last login: %w", err)
+	}
+
+	return log, nil
+}
+
+// GetDigest summarizes the user's logins, new-device logins and profile changes between since and until.
+func (s *activityService) GetDigest(ctx context.Context, userID uuid.UUID, since, until time.Time) (*domain.ActivityDigest, error) {
+	logs, err := s.activityRepo.ListByUserSince(ctx, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity for digest: %w", err)
+	}
+
+	digest := &domain.ActivityDigest{Since: since, Until: until}
+	seenAgents := make(map[string]bool)
+	for _, log := range logs {
+		if log.CreatedAt.After(until) {
+			break
+		}
+
+		switch log.ActivityType {
+		case domain.ActivityLogin:
+			digest.Logins = append(digest.Logins, log)
+			if log.UserAgent == nil || seenAgents[*log.UserAgent] {
+				continue
+			}
+			seenAgents[*log.UserAgent] = true
+
+			known, err := s.activityRepo.HasLoginFromUserAgent(ctx, userID, *log.UserAgent, since)
+			if err != nil {
+				return nil, err
+			}
+			if !known {
+				digest.NewDevices = append(digest.NewDevices, log)
+			}
+		case domain.ActivityProfileUpdate, domain.ActivityPasswordChange, domain.ActivitySettingsUpdate, domain.ActivityOAuthUnlink, domain.ActivityTwoFactorChange, domain.ActivityEmailChange:
+			digest.ProfileChanges = append(digest.ProfileChanges, log)
+		}
+	}
+
+	return digest, nil
 }
 
 // AuditService handles audit log operations.
 type AuditService interface {
 	LogAudit(ctx context.Context, adminID uuid.UUID, action domain.AuditAction, resourceType string, resourceID *uuid.UUID, oldValues, newValues map[string]interface{}, ipAddress *string) error
 	GetAuditLogs(ctx context.Context, limit, offset int) ([]*domain.AuditLog, int, error)
+	GetAuditLog(ctx context.Context, id uuid.UUID) (*domain.AuditLog, error)
 	GetAdminAuditLogs(ctx context.Context, adminID uuid.UUID, limit int) ([]*domain.AuditLog, error)
 }
 
@@ -106,6 +165,16 @@ func (s *auditService) GetAuditLogs(ctx context.Context, limit, offset int) ([]*
 	return logs, count, nil
 }
 
+// GetAuditLog retrieves a single audit log entry. It returns domain.ErrNotFound if there is none.
+func (s *auditService) GetAuditLog(ctx context.Context, id uuid.UUID) (*domain.AuditLog, error) {
+	log, err := s.auditRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+
+	return log, nil
+}
+
 // GetAdminAuditLogs retrieves audit logs for a specific admin.
 func (s *auditService) GetAdminAuditLogs(ctx context.Context, adminID uuid.UUID, limit int) ([]*domain.AuditLog, error) {
 	logs, err := s.auditRepo.ListByAdmin(ctx, adminID, limit)
@@ -0,0 +1,48 @@
+package service
+
+import "testing"
+
+// tokensEqual guards verification and reset token checks. It must behave like ==
+// while taking the same time however much of a guess matches; a length mismatch
+// returns false without comparing contents.
+func TestTokensEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{name: "equal", a: "3f9a1c7e", b: "3f9a1c7e", want: true},
+		{name: "both empty", a: "", b: "", want: true},
+		{name: "differ in last byte", a: "3f9a1c7e", b: "3f9a1c7f"},
+		{name: "differ in first byte", a: "3f9a1c7e", b: "4f9a1c7e"},
+		{name: "prefix of the other", a: "3f9a1c7e", b: "3f9a1c"},
+		{name: "longer than the other", a: "3f9a1c7e00", b: "3f9a1c7e"},
+		{name: "empty against non-empty", a: "", b: "3f9a1c7e"},
+		{name: "case matters", a: "ABCDEF", b: "abcdef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokensEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("tokensEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := tokensEqual(tt.b, tt.a); got != tt.want {
+				t.Errorf("tokensEqual(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetTokenHashMatches(t *testing.T) {
+	stored := hashResetToken("reset-token")
+
+	if !tokensEqual(stored, hashResetToken("reset-token")) {
+		t.Error("hash of the same token does not match")
+	}
+	if tokensEqual(stored, hashResetToken("reset-tokeN")) {
+		t.Error("hash of a different token matches")
+	}
+	if tokensEqual(stored, "reset-token") {
+		t.Error("raw token matches its stored hash")
+	}
+}
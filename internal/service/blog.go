@@ -18,17 +18,32 @@ type BlogRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Blog, error)
 	GetBySlug(ctx context.Context, slug string) (*domain.Blog, error)
 	List(ctx context.Context, filter domain.BlogFilter) ([]*domain.Blog, int, error)
+	IncrementViewCountBySlug(ctx context.Context, slug string) error
+	ListPublishedSlugs(ctx context.Context, limit int) ([]*domain.Blog, error)
 }
 
 type BlogService struct {
 	repo         BlogRepository
 	mediaService *MediaService
+	onChange     []func()
 }
 
 func NewBlogService(repo BlogRepository, mediaService *MediaService) *BlogService {
 	return &BlogService{repo: repo, mediaService: mediaService}
 }
 
+// OnChange registers fn to run after a post is created, updated or deleted, so caches of
+// rendered pages can be dropped. It must be called before the service is in use.
+func (s *BlogService) OnChange(fn func()) {
+	s.onChange = append(s.onChange, fn)
+}
+
+func (s *BlogService) changed() {
+	for _, fn := range s.onChange {
+		fn()
+	}
+}
+
 func (s *BlogService) Create(ctx context.Context, input domain.CreateBlogInput, authorID uuid.UUID) (*domain.Blog, error) {
 	if err := input.Validate(); err != nil {
 		return nil, err
@@ -100,17 +115,19 @@ func (s *BlogService) Create(ctx context.Context, input domain.CreateBlogInput,
 		return nil, err
 	}
 
+	s.changed()
 	return blog, nil
 }
 
 func (s *BlogService) Update(ctx context.Context, id uuid.UUID, input domain.UpdateBlogInput) (*domain.Blog, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
 	blog, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if blog == nil {
-		return nil, fmt.Errorf("blog not found")
-	}
 
 	if input.Title != nil {
 		blog.Title = *input.Title
@@ -184,21 +201,56 @@ func (s *BlogService) Update(ctx context.Context, id uuid.UUID, input domain.Upd
 		return nil, err
 	}
 
+	s.changed()
 	return blog, nil
 }
 
 func (s *BlogService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
 }
 
 func (s *BlogService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Blog, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
+// GetForManagement returns a post that user is allowed to edit or delete.
+// It returns domain.ErrUnauthorized without a user and domain.ErrForbidden for another author's post.
+func (s *BlogService) GetForManagement(ctx context.Context, id uuid.UUID, user *domain.User) (*domain.Blog, error) {
+	if user == nil {
+		return nil, domain.ErrUnauthorized
+	}
+
+	blog, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !blog.CanBeManagedBy(user) {
+		return nil, domain.ErrForbidden
+	}
+
+	return blog, nil
+}
+
 func (s *BlogService) GetBySlug(ctx context.Context, slug string) (*domain.Blog, error) {
 	return s.repo.GetBySlug(ctx, slug)
 }
 
+// RecordViewBySlug counts a view of the published post with slug, if there is one. It lets
+// views be counted for requests answered from a page cache without loading the post.
+func (s *BlogService) RecordViewBySlug(ctx context.Context, slug string) error {
+	return s.repo.IncrementViewCountBySlug(ctx, slug)
+}
+
+// ListPublishedSlugs returns up to limit published posts with only Slug and UpdatedAt set, for the sitemap.
+func (s *BlogService) ListPublishedSlugs(ctx context.Context, limit int) ([]*domain.Blog, error) {
+	return s.repo.ListPublishedSlugs(ctx, limit)
+}
+
 func (s *BlogService) List(ctx context.Context, filter domain.BlogFilter) ([]*domain.Blog, int, error) {
 	if filter.Limit <= 0 {
 		filter.Limit = 10
@@ -6,16 +6,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
 	"github.com/noruj-official/full-stack-go-template/internal/templates/email"
 )
 
 type resendEmailService struct {
 	apiKey    string
 	fromEmail string
-	appURL    string
+	appURL    string // public base URL for links in emails
 }
 
 // NewResendEmailService creates a new email service using Resend.
@@ -122,6 +125,54 @@ func (s *resendEmailService) SendPasswordResetEmail(ctx context.Context, emailAd
 	return nil
 }
 
+// SendEmailChangeEmail sends the link confirming a new email address to that address.
+func (s *resendEmailService) SendEmailChangeEmail(ctx context.Context, emailAddr, name, token string) error {
+	if s.apiKey == "" {
+		fmt.Printf("[MOCK EMAIL] Email Change -> To: %s, Token: %s\n", emailAddr, token)
+		return nil
+	}
+
+	url := "https://api.resend.com/emails"
+
+	// Create confirmation link
+	confirmLink := fmt.Sprintf("%s/verify-email-change?token=%s", s.appURL, token)
+
+	htmlContent := email.GetEmailChangeEmailContent(name, confirmLink)
+
+	payload := map[string]interface{}{
+		"from":    s.fromEmail,
+		"to":      []string{emailAddr},
+		"subject": "Confirm your new email address",
+		"html":    htmlContent,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return errors.New("failed to send email via Resend")
+	}
+
+	return nil
+}
+
 // SendEmailAuthLink sends a magic link email to the user.
 func (s *resendEmailService) SendEmailAuthLink(ctx context.Context, emailAddr, token string) error {
 	link := fmt.Sprintf("%s/auth/email/verify?token=%s", s.appURL, token)
@@ -168,3 +219,203 @@ func (s *resendEmailService) SendEmailAuthLink(ctx context.Context, emailAddr, t
 	}
 	return nil
 }
+
+// SendOAuthChangeAlert tells a super admin that changedBy altered the listed settings of an OAuth provider.
+func (s *resendEmailService) SendOAuthChangeAlert(ctx context.Context, emailAddr, name, provider, changedBy string, fields []string) error {
+	settingsLink := fmt.Sprintf("%s/a/oauth", s.appURL)
+
+	htmlContent := email.GetOAuthChangeAlertEmailContent(name, provider, changedBy, fields, settingsLink)
+
+	if s.apiKey == "" {
+		fmt.Printf("[MOCK EMAIL] OAuth Change Alert -> To: %s, Provider: %s, Changed: %v\n", emailAddr, provider, fields)
+		return nil
+	}
+
+	url := "https://api.resend.com/emails"
+
+	payload := map[string]interface{}{
+		"from":    s.fromEmail,
+		"to":      []string{emailAddr},
+		"subject": fmt.Sprintf("OAuth settings changed: %s", provider),
+		"html":    htmlContent,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return errors.New("failed to send email via Resend")
+	}
+	return nil
+}
+
+// SendTestEmail sends a message that only checks email delivery is working. Unlike the
+// other emails, a rejection by Resend includes its response, since the point is to see why.
+func (s *resendEmailService) SendTestEmail(ctx context.Context, emailAddr, name string) error {
+	htmlContent := email.GetTestEmailContent(name, s.appURL)
+
+	if s.apiKey == "" {
+		fmt.Printf("[MOCK EMAIL] Test Email -> To: %s\n", emailAddr)
+		return nil
+	}
+
+	url := "https://api.resend.com/emails"
+
+	payload := map[string]interface{}{
+		"from":    s.fromEmail,
+		"to":      []string{emailAddr},
+		"subject": "Test email",
+		"html":    htmlContent,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("email provider returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// SendActivityDigest sends a summary of recent account activity to the user.
+func (s *resendEmailService) SendActivityDigest(ctx context.Context, emailAddr, name string, digest *domain.ActivityDigest) error {
+	settingsLink := fmt.Sprintf("%s/u/settings", s.appURL)
+
+	htmlContent := email.GetActivityDigestEmailContent(name, digest, settingsLink)
+
+	if s.apiKey == "" {
+		fmt.Printf("[MOCK EMAIL] Activity Digest -> To: %s, Logins: %d, New devices: %d, Profile changes: %d\n",
+			emailAddr, len(digest.Logins), len(digest.NewDevices), len(digest.ProfileChanges))
+		return nil
+	}
+
+	url := "https://api.resend.com/emails"
+
+	payload := map[string]interface{}{
+		"from":    s.fromEmail,
+		"to":      []string{emailAddr},
+		"subject": "Your account activity summary",
+		"html":    htmlContent,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return errors.New("failed to send email via Resend")
+	}
+	return nil
+}
+
+// SendWelcomeEmail sends the onboarding email, using any non-empty overrides in content.
+func (s *resendEmailService) SendWelcomeEmail(ctx context.Context, emailAddr, name string, content domain.WelcomeEmailSettings) error {
+	appName := "Go Template"
+	subject := content.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("Welcome to %s", appName)
+	}
+	body := content.Body
+	if body == "" {
+		body = email.DefaultWelcomeEmailBody
+	}
+
+	htmlContent, err := email.GetWelcomeEmailContent(body, email.WelcomeEmailData{
+		Name:          name,
+		AppName:       appName,
+		DashboardLink: fmt.Sprintf("%s/dashboard", s.appURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render welcome email: %w", err)
+	}
+
+	if s.apiKey == "" {
+		fmt.Printf("[MOCK EMAIL] Welcome -> To: %s, Subject: %s\n", emailAddr, subject)
+		return nil
+	}
+
+	url := "https://api.resend.com/emails"
+
+	payload := map[string]interface{}{
+		"from":    s.fromEmail,
+		"to":      []string{emailAddr},
+		"subject": subject,
+		"html":    htmlContent,
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return errors.New("failed to send email via Resend")
+	}
+	return nil
+}
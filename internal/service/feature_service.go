@@ -12,6 +12,7 @@ import (
 type featureService struct {
 	repo      *postgres.FeatureRepository
 	oauthRepo repository.OAuthRepository
+	onChange  []func()
 }
 
 // NewFeatureService creates a new feature service.
@@ -22,6 +23,18 @@ func NewFeatureService(repo *postgres.FeatureRepository, oauthRepo repository.OA
 	}
 }
 
+// OnChange registers fn to run after a flag is toggled or updated, so caches of rendered
+// pages can be dropped. It must be called before the service is in use.
+func (s *featureService) OnChange(fn func()) {
+	s.onChange = append(s.onChange, fn)
+}
+
+func (s *featureService) changed() {
+	for _, fn := range s.onChange {
+		fn()
+	}
+}
+
 // Get retrieves a single feature flag by name.
 func (s *featureService) Get(ctx context.Context, name string) (*domain.FeatureFlag, error) {
 	feature, err := s.repo.Get(ctx, name)
@@ -117,13 +130,21 @@ func (s *featureService) Toggle(ctx context.Context, name string, enabled bool)
 		feature.Enabled = enabled
 	}
 
-	return s.repo.Upsert(ctx, feature)
+	if err := s.repo.Upsert(ctx, feature); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
 }
 
 // Upsert creates or updates a feature flag with full details.
 func (s *featureService) Upsert(ctx context.Context, name, description string, enabled bool) error {
 	feature := domain.NewFeatureFlag(name, enabled, description)
-	return s.repo.Upsert(ctx, feature)
+	if err := s.repo.Upsert(ctx, feature); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
 }
 
 // SyncFeatures ensures that the specified features exist in the database.
@@ -3,6 +3,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
@@ -16,42 +17,88 @@ type UserService interface {
 	// GetUser retrieves a user by ID.
 	GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error)
 
+	// GetUserDetails retrieves a user together with their OAuth links and last login.
+	GetUserDetails(ctx context.Context, id uuid.UUID) (*domain.UserDetails, error)
+
 	// ListUsers retrieves all users with pagination.
 	ListUsers(ctx context.Context, page, pageSize int) ([]*domain.User, int64, error)
 
 	// UpdateUser updates an existing user.
+	// A changed email is not applied while email verification is on; the new address is
+	// sent a confirmation link instead and the user keeps their current one until then.
 	UpdateUser(ctx context.Context, id uuid.UUID, input *domain.UpdateUserInput) (*domain.User, error)
 
+	// PendingEmailChange returns the user's unconfirmed new email address, or domain.ErrNotFound.
+	PendingEmailChange(ctx context.Context, id uuid.UUID) (*domain.PendingEmailChange, error)
+
+	// ConfirmEmailChange applies the email change the token was sent for.
+	ConfirmEmailChange(ctx context.Context, token string) (*domain.User, error)
+
+	// ListActiveByRole retrieves active users with exactly the given role, e.g. to alert super admins.
+	ListActiveByRole(ctx context.Context, role domain.Role) ([]*domain.User, error)
+
 	// UpdateStatus updates the status of a user.
 	UpdateStatus(ctx context.Context, id uuid.UUID, status domain.UserStatus) error
 
 	// UpdatePassword updates the user's password.
 	UpdatePassword(ctx context.Context, id uuid.UUID, input *domain.UpdatePasswordInput) error
 
+	// SecureAccount revokes all of the user's sessions and outstanding tokens in one step.
+	SecureAccount(ctx context.Context, id uuid.UUID) error
+
+	// RevokeOAuthLink unlinks a provider from the user and revokes its stored token with the provider
+	// where supported, reporting whether the provider confirmed the revocation.
+	RevokeOAuthLink(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) (bool, error)
+
+	// ListOAuthLinks returns the providers linked to the user, without their tokens.
+	ListOAuthLinks(ctx context.Context, userID uuid.UUID) ([]*domain.UserOAuth, error)
+
+	// UnlinkOAuth removes one of the user's own linked accounts. It fails with
+	// domain.ErrLastSignInMethod if the user would be left with no way to sign in.
+	UnlinkOAuth(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) error
+
 	// DeleteUser removes a user.
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 }
 
 // AuthService defines the interface for authentication operations.
 type AuthService interface {
+	// PasswordPolicy returns the rules new passwords must meet, for forms to list.
+	PasswordPolicy(ctx context.Context) domain.PasswordPolicy
+
 	// Register creates a new user account.
 	Register(ctx context.Context, input *domain.RegisterInput, ip, userAgent string) (*domain.User, error)
 
 	// Login authenticates a user and creates a session.
 	Login(ctx context.Context, input *domain.LoginInput, ip, userAgent string) (*domain.User, *domain.Session, error)
 
-	// Logout destroys a user session.
-	Logout(ctx context.Context, sessionID string) error
+	// Logout destroys a user session. For a session signed in through a provider with an
+	// end session URL it also returns where to send the browser to sign out there, else "".
+	Logout(ctx context.Context, sessionID string) (string, error)
+
+	// ListSessions returns the user's active sessions, most recently used first.
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]*domain.Session, error)
+
+	// RevokeSession signs out one of the user's sessions by its public ID.
+	RevokeSession(ctx context.Context, userID uuid.UUID, publicID string) error
 
 	// ValidateSession checks if a session is valid and returns the user.
 	ValidateSession(ctx context.Context, sessionID string) (*domain.User, error)
 
+	// ExtendSession validates a session and, when it is close to expiring, extends it by the
+	// session lifetime. The returned time is the new expiry, or zero if it was not extended.
+	ExtendSession(ctx context.Context, sessionID string) (*domain.User, time.Time, error)
+
 	// GetCurrentUser retrieves the authenticated user from session.
 	GetCurrentUser(ctx context.Context, sessionID string) (*domain.User, error)
 
 	// VerifyEmail verifies a user's email address using a token.
 	VerifyEmail(ctx context.Context, token string) error
 
+	// ResendVerificationEmail sends a new verification link if email belongs to an
+	// unverified account, and silently does nothing otherwise.
+	ResendVerificationEmail(ctx context.Context, email string) error
+
 	// RequestPasswordReset initiates the password reset flow.
 	RequestPasswordReset(ctx context.Context, email string) error
 
@@ -61,12 +108,20 @@ type AuthService interface {
 	// SignOutAllDevices invalidates all sessions for a user.
 	SignOutAllDevices(ctx context.Context, userID uuid.UUID) error
 
+	// StartSession creates a session for an already authenticated user without any sign-in
+	// checks, e.g. to keep the current browser signed in after SignOutAllDevices.
+	StartSession(ctx context.Context, userID uuid.UUID, ip, userAgent string) (*domain.Session, error)
+
 	// GetOAuthLoginURL generates a login URL for the specified provider.
 	GetOAuthLoginURL(ctx context.Context, provider domain.OAuthProviderType, state string) (string, error)
 
 	// LoginWithOAuth handles the OAuth callback and logs in the user.
 	LoginWithOAuth(ctx context.Context, provider domain.OAuthProviderType, code, state string, ip, userAgent string) (*domain.User, *domain.Session, error)
 
+	// RefreshUserOAuthToken exchanges the stored refresh token for a new access token when the
+	// current one has expired, persists it, and returns the updated link.
+	RefreshUserOAuthToken(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) (*domain.UserOAuth, error)
+
 	// ListEnabledProviders returns a map of enabled providers.
 	ListEnabledProviders(ctx context.Context) (map[string]bool, error)
 
@@ -81,6 +136,38 @@ type AuthService interface {
 
 	// SendEmailAuthLink sends a magic link email to the user.
 	SendEmailAuthLink(ctx context.Context, emailAddr, token string) error
+
+	// CompleteTwoFactorLogin finishes a sign-in that returned a *domain.TOTPChallengeError,
+	// creating the session once code is verified.
+	CompleteTwoFactorLogin(ctx context.Context, token, code string, ip, userAgent string) (*domain.User, *domain.Session, error)
+}
+
+// TwoFactorService defines the interface for TOTP two-factor authentication.
+type TwoFactorService interface {
+	// Required reports whether the user must enter a code to sign in.
+	Required(ctx context.Context, userID uuid.UUID) (bool, error)
+
+	// Status returns the user's two-factor setup.
+	Status(ctx context.Context, userID uuid.UUID) (*domain.TwoFactorStatus, error)
+
+	// BeginEnrollment creates a pending secret and returns it with its otpauth:// URI.
+	BeginEnrollment(ctx context.Context, user *domain.User) (string, string, error)
+
+	// PendingEnrollment returns the secret and URI of an unconfirmed enrollment.
+	PendingEnrollment(ctx context.Context, user *domain.User) (string, string, error)
+
+	// ConfirmEnrollment verifies the first code, enables two-factor authentication and
+	// returns the recovery codes, which can't be retrieved again.
+	ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+
+	// Verify checks an authenticator code or uses up a recovery code.
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+
+	// RegenerateRecoveryCodes verifies code and replaces the user's recovery codes.
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+
+	// Disable verifies code and turns two-factor authentication off.
+	Disable(ctx context.Context, userID uuid.UUID, code string) error
 }
 
 // EmailService defines the interface for email operations.
@@ -91,8 +178,23 @@ type EmailService interface {
 	// SendPasswordResetEmail sends a password reset email to the user.
 	SendPasswordResetEmail(ctx context.Context, emailAddr, name, token string) error
 
+	// SendEmailChangeEmail sends the link confirming a new email address to that address.
+	SendEmailChangeEmail(ctx context.Context, emailAddr, name, token string) error
+
 	// SendEmailAuthLink sends a magic link email to the user.
 	SendEmailAuthLink(ctx context.Context, emailAddr, token string) error
+
+	// SendOAuthChangeAlert tells a super admin that changedBy altered the listed settings of an OAuth provider.
+	SendOAuthChangeAlert(ctx context.Context, emailAddr, name, provider, changedBy string, fields []string) error
+
+	// SendTestEmail sends a message that only checks email delivery is working.
+	SendTestEmail(ctx context.Context, emailAddr, name string) error
+
+	// SendActivityDigest sends a summary of recent account activity to the user.
+	SendActivityDigest(ctx context.Context, emailAddr, name string, digest *domain.ActivityDigest) error
+
+	// SendWelcomeEmail sends the onboarding email, using any non-empty overrides in content.
+	SendWelcomeEmail(ctx context.Context, emailAddr, name string, content domain.WelcomeEmailSettings) error
 }
 
 // FeatureService defines the interface for feature flag operations.
@@ -114,4 +216,31 @@ type FeatureService interface {
 
 	// Upsert creates or updates a feature flag with full details.
 	Upsert(ctx context.Context, name, description string, enabled bool) error
+
+	// OnChange registers fn to run after a flag is toggled or updated.
+	OnChange(fn func())
+}
+
+// SettingsService defines the interface for editable application settings.
+type SettingsService interface {
+	// HomePage returns the landing page content. On error it also returns the defaults.
+	HomePage(ctx context.Context) (domain.HomePageSettings, error)
+
+	// UpdateHomePage validates and saves the landing page content.
+	UpdateHomePage(ctx context.Context, settings *domain.HomePageSettings) error
+
+	// WelcomeEmail returns the welcome email overrides; empty fields mean the built-in defaults.
+	WelcomeEmail(ctx context.Context) (domain.WelcomeEmailSettings, error)
+
+	// UpdateWelcomeEmail validates and saves the welcome email overrides.
+	UpdateWelcomeEmail(ctx context.Context, settings *domain.WelcomeEmailSettings) error
+
+	// PasswordPolicy returns the password policy. On error it also returns the default policy.
+	PasswordPolicy(ctx context.Context) (domain.PasswordPolicy, error)
+
+	// UpdatePasswordPolicy validates and saves the password policy.
+	UpdatePasswordPolicy(ctx context.Context, policy *domain.PasswordPolicy) error
+
+	// OnChange registers fn to run after any settings are saved.
+	OnChange(fn func())
 }
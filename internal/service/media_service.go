@@ -1,11 +1,18 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
+	_ "image/gif" // Register decoders so imageDimensions can read these formats
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
@@ -26,13 +33,122 @@ func (s *MediaService) Upload(ctx context.Context, input domain.CreateMediaInput
 		return nil, fmt.Errorf("validate input: %w", err)
 	}
 
+	if input.Width == 0 && input.Height == 0 {
+		input.Width, input.Height, _ = imageDimensions(input.ContentType, input.Data)
+	}
+
 	return s.repo.Create(ctx, input)
 }
 
+// imageDimensions reads the pixel size from an image's header without decoding the
+// whole image. ok is false for non-images and formats there is no decoder for.
+func imageDimensions(contentType string, data []byte) (width, height int, ok bool) {
+	if !strings.HasPrefix(contentType, "image/") || len(data) == 0 {
+		return 0, 0, false
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false
+	}
+	return cfg.Width, cfg.Height, true
+}
+
+// BackfillDimensions records the dimensions of stored images uploaded before they were
+// captured. Images that can't be decoded are skipped and logged. It returns how many
+// images were updated.
+func (s *MediaService) BackfillDimensions(ctx context.Context) (int, error) {
+	const batchSize = 100
+
+	updated := 0
+	var after uuid.UUID
+	for {
+		ids, err := s.repo.ListMissingDimensions(ctx, after, batchSize)
+		if err != nil {
+			return updated, err
+		}
+		if len(ids) == 0 {
+			return updated, nil
+		}
+
+		for _, id := range ids {
+			after = id
+
+			media, err := s.repo.GetByID(ctx, id)
+			if err != nil {
+				return updated, err
+			}
+			width, height, ok := imageDimensions(media.ContentType, media.Data)
+			if !ok {
+				log.Printf("media %s: could not read image dimensions, skipping", id)
+				continue
+			}
+			if err := s.repo.SetDimensions(ctx, id, width, height); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
+
 func (s *MediaService) GetByID(ctx context.Context, id uuid.UUID) (*domain.Media, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
+// GetMetadata returns a media item without its binary data.
+func (s *MediaService) GetMetadata(ctx context.Context, id uuid.UUID) (*domain.Media, error) {
+	return s.repo.GetMetadataByID(ctx, id)
+}
+
+func (s *MediaService) Update(ctx context.Context, id uuid.UUID, input domain.UpdateMediaInput) (*domain.Media, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("validate input: %w", err)
+	}
+
+	media, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Filename != nil {
+		media.Filename = *input.Filename
+	}
+	if input.AltText != nil {
+		media.AltText = *input.AltText
+	}
+	if input.ContentType != nil {
+		media.ContentType = *input.ContentType
+	}
+	if input.StorageProvider != nil {
+		media.StorageProvider = *input.StorageProvider
+	}
+	if input.FileKey != nil {
+		media.FileKey = *input.FileKey
+	}
+	if input.PublicURL != nil {
+		media.PublicURL = *input.PublicURL
+	}
+
+	if err := s.repo.Update(ctx, media); err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+func (s *MediaService) List(ctx context.Context, filter domain.MediaFilter) ([]*domain.Media, int, error) {
+	items, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
 func (s *MediaService) Delete(ctx context.Context, id uuid.UUID) error {
 	return s.repo.Delete(ctx, id)
 }
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres/postgrestest"
+)
+
+func TestMediaService_Update(t *testing.T) {
+	db := postgrestest.New(t)
+	svc := NewMediaService(postgres.NewMediaRepository(db))
+	ctx := context.Background()
+
+	media, err := svc.Upload(ctx, domain.CreateMediaInput{
+		Filename:        "IMG_0001.png",
+		Data:            []byte("not really a png"),
+		ContentType:     "image/png",
+		SizeBytes:       16,
+		StorageProvider: domain.StorageProviderDatabase,
+	})
+	if err != nil {
+		t.Fatalf("Upload() = %v", err)
+	}
+
+	// Correct the alt text and backfill the S3 location, leaving the rest alone
+	alt, provider, key, url := "A sunset over the bay", domain.StorageProviderS3, "media/sunset.png", "https://cdn.example.com/media/sunset.png"
+	if _, err := svc.Update(ctx, media.ID, domain.UpdateMediaInput{
+		AltText:         &alt,
+		StorageProvider: &provider,
+		FileKey:         &key,
+		PublicURL:       &url,
+	}); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+
+	got, err := svc.GetMetadata(ctx, media.ID)
+	if err != nil {
+		t.Fatalf("GetMetadata() = %v", err)
+	}
+	if got.AltText != alt || got.StorageProvider != provider || got.FileKey != key || got.PublicURL != url {
+		t.Errorf("stored alt %q, provider %q, key %q, url %q; want %q, %q, %q, %q",
+			got.AltText, got.StorageProvider, got.FileKey, got.PublicURL, alt, provider, key, url)
+	}
+	if got.Filename != "IMG_0001.png" || got.ContentType != "image/png" {
+		t.Errorf("untouched fields changed: filename %q, content type %q", got.Filename, got.ContentType)
+	}
+}
+
+func TestMediaService_UpdateErrors(t *testing.T) {
+	db := postgrestest.New(t)
+	svc := NewMediaService(postgres.NewMediaRepository(db))
+	ctx := context.Background()
+
+	alt := "Missing"
+	if _, err := svc.Update(ctx, uuid.New(), domain.UpdateMediaInput{AltText: &alt}); !domain.IsNotFoundError(err) {
+		t.Errorf("Update() of unknown media = %v, want ErrNotFound", err)
+	}
+
+	bad := "ftp"
+	if _, err := svc.Update(ctx, uuid.New(), domain.UpdateMediaInput{StorageProvider: &bad}); !domain.IsValidationError(err) {
+		t.Errorf("Update() with invalid provider = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+)
+
+// revokeOAuthToken asks the provider to revoke a user's stored token, preferring the
+// refresh token since revoking it also invalidates the access tokens issued from it.
+// It returns false without error when the provider has no revocation endpoint or there
+// is no token left to revoke.
+func revokeOAuthToken(ctx context.Context, provider *domain.OAuthProvider, link *domain.UserOAuth) (bool, error) {
+	preset, ok := domain.OAuthProviderPresets[provider.Provider]
+	if !ok || preset.RevokeURL == "" {
+		return false, nil
+	}
+
+	token, hint := link.RefreshToken, "refresh_token"
+	if token == "" {
+		token, hint = link.AccessToken, "access_token"
+	}
+	if token == "" {
+		return false, nil
+	}
+
+	var req *http.Request
+	var err error
+	if provider.Provider == domain.OAuthProviderGitHub {
+		// GitHub revokes through its REST API rather than RFC 7009, and only for access tokens.
+		if link.AccessToken == "" {
+			return false, nil
+		}
+		body, _ := json.Marshal(map[string]string{"access_token": link.AccessToken})
+		endpoint := strings.Replace(preset.RevokeURL, "{client_id}", url.PathEscape(provider.ClientID), 1)
+		req, err = http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth(provider.ClientID, provider.ClientSecret)
+	} else {
+		form := url.Values{
+			"token":           {token},
+			"token_type_hint": {hint},
+			"client_id":       {provider.ClientID},
+			"client_secret":   {provider.ClientSecret},
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, preset.RevokeURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("%s token revocation failed: status %d", provider.Provider, resp.StatusCode)
+	}
+	return true, nil
+}
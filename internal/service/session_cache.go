@@ -0,0 +1,125 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+)
+
+// sessionCacheSweepSize is the entry count above which expired entries are swept on insert.
+const sessionCacheSweepSize = 10000
+
+// SessionCache is an in-process TTL cache of validated sessions and their users, so
+// authenticated requests can skip the session and user queries. The auth and user
+// services evict entries when they sign a user out or change the user; changes made
+// elsewhere (such as on another instance) are picked up once the TTL expires.
+type SessionCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]sessionCacheEntry
+}
+
+type sessionCacheEntry struct {
+	user       *domain.User
+	expiresAt  time.Time // the session's own expiry
+	validUntil time.Time
+}
+
+// NewSessionCache returns a cache holding entries for ttl, or nil (caching disabled) if ttl is not positive.
+func NewSessionCache(ttl time.Duration) *SessionCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &SessionCache{ttl: ttl, entries: make(map[string]sessionCacheEntry)}
+}
+
+// get returns a copy of the cached user for the session and the session's expiry, if present and fresh.
+func (c *SessionCache) get(sessionID string) (*domain.User, time.Time, bool) {
+	if c == nil {
+		return nil, time.Time{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[sessionID]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	if time.Now().After(e.validUntil) {
+		delete(c.entries, sessionID)
+		return nil, time.Time{}, false
+	}
+
+	user := *e.user
+	return &user, e.expiresAt, true
+}
+
+// set caches the user for the session, never beyond the session's own expiry.
+func (c *SessionCache) set(session *domain.Session, user *domain.User) {
+	if c == nil {
+		return
+	}
+
+	validUntil := time.Now().Add(c.ttl)
+	if session.ExpiresAt.Before(validUntil) {
+		validUntil = session.ExpiresAt
+	}
+	cached := *user
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= sessionCacheSweepSize {
+		now := time.Now()
+		for id, e := range c.entries {
+			if now.After(e.validUntil) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[session.ID] = sessionCacheEntry{user: &cached, expiresAt: session.ExpiresAt, validUntil: validUntil}
+}
+
+// extend records a session's new expiry on its cached entry, if any.
+func (c *SessionCache) extend(sessionID string, expiresAt time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[sessionID]; ok {
+		e.expiresAt = expiresAt
+		c.entries[sessionID] = e
+	}
+}
+
+// delete evicts one session.
+func (c *SessionCache) delete(sessionID string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.entries, sessionID)
+	c.mu.Unlock()
+}
+
+// deleteUser evicts every session belonging to the user.
+func (c *SessionCache) deleteUser(userID uuid.UUID) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	for id, e := range c.entries {
+		if e.user.ID == userID {
+			delete(c.entries, id)
+		}
+	}
+	c.mu.Unlock()
+}
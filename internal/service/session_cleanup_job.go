@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/repository"
+)
+
+// SessionCleanupJob periodically deletes expired sessions, which are otherwise only
+// removed when someone presents one.
+type SessionCleanupJob struct {
+	sessionRepo repository.SessionRepository
+	interval    time.Duration
+}
+
+// NewSessionCleanupJob creates a job that purges expired sessions once per interval.
+func NewSessionCleanupJob(sessionRepo repository.SessionRepository, interval time.Duration) *SessionCleanupJob {
+	return &SessionCleanupJob{sessionRepo: sessionRepo, interval: interval}
+}
+
+// Start runs the job in the background until ctx is cancelled.
+func (j *SessionCleanupJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.RunOnce(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce deletes every expired session.
+func (j *SessionCleanupJob) RunOnce(ctx context.Context) {
+	removed, err := j.sessionRepo.DeleteExpired(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Session cleanup: %v", err)
+		}
+		return
+	}
+	if removed > 0 {
+		log.Printf("Session cleanup: removed %d expired sessions", removed)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/repository/postgres"
+	"github.com/noruj-official/full-stack-go-template/internal/templates/email"
+)
+
+// settingsCacheTTL bounds how stale another instance's view of the settings can be;
+// the instance that saves a change sees it immediately.
+const settingsCacheTTL = time.Minute
+
+// settingsService implements the SettingsService interface with an in-process cache,
+// since the landing page reads the settings on every anonymous visit.
+type settingsService struct {
+	repo *postgres.SettingsRepository
+
+	mu       sync.RWMutex
+	values   map[string]string
+	loadedAt time.Time
+	onChange []func()
+}
+
+// NewSettingsService creates a new settings service.
+func NewSettingsService(repo *postgres.SettingsRepository) SettingsService {
+	return &settingsService{repo: repo}
+}
+
+// OnChange registers fn to run after settings are saved, so caches of rendered pages can
+// be dropped. It must be called before the service is in use.
+func (s *settingsService) OnChange(fn func()) {
+	s.onChange = append(s.onChange, fn)
+}
+
+// HomePage returns the landing page content, with defaults for anything not yet saved.
+func (s *settingsService) HomePage(ctx context.Context) (domain.HomePageSettings, error) {
+	values, err := s.load(ctx)
+	if err != nil {
+		return domain.DefaultHomePageSettings(), err
+	}
+	return domain.HomePageSettingsFromMap(values), nil
+}
+
+// UpdateHomePage validates and saves the landing page content.
+func (s *settingsService) UpdateHomePage(ctx context.Context, settings *domain.HomePageSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	return s.save(ctx, settings.Map())
+}
+
+// WelcomeEmail returns the welcome email overrides; empty fields mean the built-in defaults.
+func (s *settingsService) WelcomeEmail(ctx context.Context) (domain.WelcomeEmailSettings, error) {
+	values, err := s.load(ctx)
+	if err != nil {
+		return domain.WelcomeEmailSettings{}, err
+	}
+	return domain.WelcomeEmailSettingsFromMap(values), nil
+}
+
+// UpdateWelcomeEmail validates and saves the welcome email overrides, rejecting a body
+// that is not a valid template so a typo can't break every welcome email.
+func (s *settingsService) UpdateWelcomeEmail(ctx context.Context, settings *domain.WelcomeEmailSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if settings.Body != "" {
+		if _, err := email.ParseWelcomeEmailBody(settings.Body); err != nil {
+			return domain.ErrValidation{Field: "body", Message: "body is not a valid template: " + err.Error()}
+		}
+	}
+	return s.save(ctx, settings.Map())
+}
+
+// PasswordPolicy returns the password policy, with defaults for anything not yet saved.
+func (s *settingsService) PasswordPolicy(ctx context.Context) (domain.PasswordPolicy, error) {
+	values, err := s.load(ctx)
+	if err != nil {
+		return domain.DefaultPasswordPolicy(), err
+	}
+	return domain.PasswordPolicyFromMap(values), nil
+}
+
+// UpdatePasswordPolicy validates and saves the password policy. It applies to passwords
+// chosen from now on; existing passwords keep working.
+func (s *settingsService) UpdatePasswordPolicy(ctx context.Context, policy *domain.PasswordPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	return s.save(ctx, policy.Map())
+}
+
+// currentPasswordPolicy loads the password policy for checking a new password. If the
+// settings can't be loaded it uses the default rather than blocking password changes.
+func currentPasswordPolicy(ctx context.Context, settings SettingsService) domain.PasswordPolicy {
+	policy, err := settings.PasswordPolicy(ctx)
+	if err != nil {
+		log.Printf("Failed to load password policy, using the default: %v", err)
+	}
+	return policy
+}
+
+// save stores values and drops the cache so the change is visible immediately.
+func (s *settingsService) save(ctx context.Context, values map[string]string) error {
+	if err := s.repo.SetMany(ctx, values); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.values = nil
+	s.mu.Unlock()
+
+	for _, fn := range s.onChange {
+		fn()
+	}
+	return nil
+}
+
+// load returns the cached settings, reloading them once they are older than settingsCacheTTL.
+func (s *settingsService) load(ctx context.Context) (map[string]string, error) {
+	s.mu.RLock()
+	values, loadedAt := s.values, s.loadedAt
+	s.mu.RUnlock()
+	if values != nil && time.Since(loadedAt) < settingsCacheTTL {
+		return values, nil
+	}
+
+	values, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.values, s.loadedAt = values, time.Now()
+	s.mu.Unlock()
+	return values, nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/totp"
+	"github.com/noruj-official/full-stack-go-template/internal/repository"
+)
+
+// recoveryCodeAlphabet leaves out characters that are easily misread, such as 0/o and 1/l.
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// twoFactorService implements the TwoFactorService interface.
+type twoFactorService struct {
+	repo           repository.TwoFactorRepository
+	featureService FeatureService
+	issuer         string // shown as the account's name in authenticator apps
+}
+
+// NewTwoFactorService creates a new two-factor service. issuer labels the account in
+// authenticator apps, usually the app name.
+func NewTwoFactorService(repo repository.TwoFactorRepository, featureService FeatureService, issuer string) TwoFactorService {
+	return &twoFactorService{repo: repo, featureService: featureService, issuer: issuer}
+}
+
+// Required reports whether the user must enter a code to sign in: the feature is on and
+// they have confirmed an authenticator. If the feature is turned off, accounts sign in
+// with their first factor alone but keep their enrollment for when it's turned back on.
+func (s *twoFactorService) Required(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if enabled, err := s.featureService.IsEnabled(ctx, domain.FeatureTwoFactor); err != nil || !enabled {
+		return false, err
+	}
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return secret.Enabled(), nil
+}
+
+// Status returns whether the user has two-factor authentication on and how many
+// recovery codes they have left.
+func (s *twoFactorService) Status(ctx context.Context, userID uuid.UUID) (*domain.TwoFactorStatus, error) {
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			return &domain.TwoFactorStatus{}, nil
+		}
+		return nil, err
+	}
+	if !secret.Enabled() {
+		return &domain.TwoFactorStatus{}, nil
+	}
+
+	remaining, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TwoFactorStatus{Enabled: true, EnabledAt: secret.EnabledAt, RecoveryCodesRemaining: remaining}, nil
+}
+
+// BeginEnrollment generates a new secret for the user and stores it as pending. It returns
+// the secret and the otpauth:// URI to add it to an authenticator app. Users who already
+// have two-factor authentication on get domain.ErrConflict.
+func (s *twoFactorService) BeginEnrollment(ctx context.Context, user *domain.User) (string, string, error) {
+	if existing, err := s.repo.Get(ctx, user.ID); err == nil && existing.Enabled() {
+		return "", "", domain.ErrConflict
+	} else if err != nil && !domain.IsNotFoundError(err) {
+		return "", "", err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.repo.SavePending(ctx, &domain.TOTPSecret{UserID: user.ID, Secret: secret, CreatedAt: time.Now()}); err != nil {
+		return "", "", err
+	}
+	return secret, totp.URI(s.issuer, user.Email, secret), nil
+}
+
+// PendingEnrollment returns the secret and otpauth:// URI of an enrollment that has been
+// started but not confirmed, so the setup page can be shown again. It returns
+// domain.ErrNotFound if there is none.
+func (s *twoFactorService) PendingEnrollment(ctx context.Context, user *domain.User) (string, string, error) {
+	secret, err := s.repo.Get(ctx, user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if secret.Enabled() {
+		return "", "", domain.ErrNotFound
+	}
+	return secret.Secret, totp.URI(s.issuer, user.Email, secret.Secret), nil
+}
+
+// ConfirmEnrollment checks the first code from the user's authenticator against their
+// pending secret, turns two-factor authentication on and returns a fresh set of recovery
+// codes. The codes are stored hashed, so this is the only time they can be shown.
+func (s *twoFactorService) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			return nil, domain.ErrInvalidTOTPCode
+		}
+		return nil, err
+	}
+	if secret.Enabled() {
+		return nil, domain.ErrConflict
+	}
+
+	step, ok := totp.Validate(secret.Secret, code, time.Now())
+	if !ok {
+		return nil, domain.ErrInvalidTOTPCode
+	}
+	if err := s.repo.Enable(ctx, userID, step); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// Verify accepts either a current code from the user's authenticator or one of their
+// unused recovery codes, which is then used up. Anything else fails with
+// domain.ErrInvalidTOTPCode, as does a code that was already accepted once.
+func (s *twoFactorService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			return domain.ErrInvalidTOTPCode
+		}
+		return err
+	}
+	if !secret.Enabled() {
+		return domain.ErrInvalidTOTPCode
+	}
+
+	if step, ok := totp.Validate(secret.Secret, code, time.Now()); ok {
+		used, err := s.repo.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return domain.ErrInvalidTOTPCode
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != 8 {
+		return domain.ErrInvalidTOTPCode
+	}
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashResetToken(normalized))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes verifies code and replaces the user's recovery codes with a new set.
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// Disable verifies code and removes the user's secret and recovery codes.
+func (s *twoFactorService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, userID)
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes, stores their hashes and
+// returns the codes formatted for display.
+func (s *twoFactorService) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, domain.RecoveryCodeCount)
+	hashes := make([]string, domain.RecoveryCodeCount)
+	for i := range codes {
+		raw, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashResetToken(raw)
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// randomRecoveryCode returns 8 random characters from recoveryCodeAlphabet. Bytes at or
+// above the largest multiple of the alphabet size are skipped, so every character is
+// equally likely.
+func randomRecoveryCode() (string, error) {
+	limit := 256 - 256%len(recoveryCodeAlphabet)
+	code := make([]byte, 0, 8)
+	buf := make([]byte, 16)
+	for len(code) < 8 {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < 8 {
+				code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// normalizeRecoveryCode accepts codes typed with or without the dash, in any case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -3,22 +3,42 @@ package service
 
 import (
 	"context"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/noruj-official/full-stack-go-template/internal/domain"
+	"github.com/noruj-official/full-stack-go-template/internal/pkg/password"
 	"github.com/noruj-official/full-stack-go-template/internal/repository"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // userService implements the UserService interface.
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo        repository.UserRepository
+	oauthRepo       repository.OAuthRepository
+	emailChangeRepo repository.EmailChangeRepository
+	sessionStore    repository.SessionStore
+	sessionCache    *SessionCache
+	activityService ActivityService
+	settingsService SettingsService
+	featureService  FeatureService
+	emailService    EmailService
+	hasher          password.Hasher
 }
 
 // NewUserService creates a new user service.
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, oauthRepo repository.OAuthRepository, emailChangeRepo repository.EmailChangeRepository, sessionStore repository.SessionStore, sessionCache *SessionCache, activityService ActivityService, settingsService SettingsService, featureService FeatureService, emailService EmailService, hasher password.Hasher) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:        userRepo,
+		oauthRepo:       oauthRepo,
+		emailChangeRepo: emailChangeRepo,
+		sessionStore:    sessionStore,
+		sessionCache:    sessionCache,
+		activityService: activityService,
+		settingsService: settingsService,
+		featureService:  featureService,
+		emailService:    emailService,
+		hasher:          hasher,
 	}
 }
 
@@ -28,6 +48,9 @@ func (s *userService) CreateUser(ctx context.Context, input *domain.CreateUserIn
 	if err := input.Validate(); err != nil {
 		return nil, err
 	}
+	if err := currentPasswordPolicy(ctx, s.settingsService).Check("password", input.Password); err != nil {
+		return nil, err
+	}
 
 	// Check if email already exists
 	existing, err := s.userRepo.GetByEmail(ctx, input.Email)
@@ -36,13 +59,13 @@ func (s *userService) CreateUser(ctx context.Context, input *domain.CreateUserIn
 	}
 
 	// Hash password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	passwordHash, err := s.hasher.Hash(input.Password)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create new user
-	user := domain.NewUser(input.Email, input.Name, string(passwordHash), input.Role)
+	user := domain.NewUser(input.Email, input.Name, passwordHash, input.Role)
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
@@ -56,7 +79,32 @@ func (s *userService) GetUser(ctx context.Context, id uuid.UUID) (*domain.User,
 	return s.userRepo.GetByID(ctx, id)
 }
 
-// ListUsers retrieves all users with pagination.
+// GetUserDetails retrieves a user together with their OAuth links and last login.
+func (s *userService) GetUserDetails(ctx context.Context, id uuid.UUID) (*domain.UserDetails, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.oauthRepo.ListByUserID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	lastLogin, err := s.activityService.GetLastLogin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UserDetails{
+		User:       user,
+		OAuthLinks: links,
+		LastLogin:  lastLogin,
+	}, nil
+}
+
+// ListUsers retrieves all users with pagination. Callers cap pageSize at the
+// configured MAX_PAGE_SIZE, so it is not clamped again here.
 func (s *userService) ListUsers(ctx context.Context, page, pageSize int) ([]*domain.User, int64, error) {
 	if page < 1 {
 		page = 1
@@ -64,9 +112,6 @@ func (s *userService) ListUsers(ctx context.Context, page, pageSize int) ([]*dom
 	if pageSize < 1 {
 		pageSize = 10
 	}
-	if pageSize > 100 {
-		pageSize = 100
-	}
 
 	offset := (page - 1) * pageSize
 
@@ -83,6 +128,11 @@ func (s *userService) ListUsers(ctx context.Context, page, pageSize int) ([]*dom
 	return users, total, nil
 }
 
+// ListActiveByRole retrieves active users with exactly the given role.
+func (s *userService) ListActiveByRole(ctx context.Context, role domain.Role) ([]*domain.User, error) {
+	return s.userRepo.ListActiveByRole(ctx, role)
+}
+
 // UpdateUser updates an existing user.
 func (s *userService) UpdateUser(ctx context.Context, id uuid.UUID, input *domain.UpdateUserInput) (*domain.User, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
@@ -91,12 +141,30 @@ func (s *userService) UpdateUser(ctx context.Context, id uuid.UUID, input *domai
 	}
 
 	// Apply updates
+	var newEmail string
 	if input.Email != nil {
-		user.Email = *input.Email
+		email := domain.NormalizeEmail(*input.Email)
+		if email == "" {
+			return nil, domain.ErrValidation{Field: "email", Message: "email is required"}
+		}
+		if email != user.Email {
+			newEmail = email
+		}
 	}
 	if input.Name != nil {
 		user.Name = *input.Name
 	}
+	if input.Username != nil {
+		if *input.Username == "" {
+			user.Username = nil
+		} else {
+			username := *input.Username
+			user.Username = &username
+		}
+	}
+	if input.ActivityDigest != nil {
+		user.ActivityDigestEnabled = *input.ActivityDigest
+	}
 	if input.Role != nil {
 		user.Role = *input.Role
 	}
@@ -104,15 +172,112 @@ func (s *userService) UpdateUser(ctx context.Context, id uuid.UUID, input *domai
 		user.ProfileMediaID = input.ProfileMediaID
 	}
 
+	// With email verification on, a new address only replaces the current one once the
+	// link sent to it is followed (see ConfirmEmailChange).
+	confirmEmail := newEmail != "" && s.emailVerificationEnabled(ctx)
+	if newEmail != "" && !confirmEmail {
+		user.Email = newEmail
+	}
+
 	// Validate updated user
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
 
+	if confirmEmail {
+		if _, err := s.userRepo.GetByEmail(ctx, newEmail); err == nil {
+			return nil, domain.ErrConflict
+		} else if !domain.IsNotFoundError(err) {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	s.sessionCache.deleteUser(id)
+
+	if confirmEmail {
+		if err := s.requestEmailChange(ctx, user, newEmail); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// emailVerificationEnabled reports whether email addresses must be verified, failing
+// closed if the feature flag can't be read.
+func (s *userService) emailVerificationEnabled(ctx context.Context) bool {
+	enabled, err := s.featureService.IsEnabled(ctx, domain.FeatureEmailVerification)
+	return err != nil || enabled
+}
+
+// requestEmailChange records newEmail as the user's pending address and emails it a
+// confirmation link, replacing any earlier pending change.
+func (s *userService) requestEmailChange(ctx context.Context, user *domain.User, newEmail string) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	if err := s.emailChangeRepo.Save(ctx, domain.NewPendingEmailChange(user.ID, newEmail, hashResetToken(token))); err != nil {
+		return err
+	}
+
+	name := user.Name
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.emailService.SendEmailChangeEmail(sendCtx, newEmail, name, token); err != nil {
+			log.Printf("Failed to send email change confirmation: %v", err)
+		}
+	}()
+	return nil
+}
+
+// PendingEmailChange returns the user's unconfirmed new email address, if any.
+func (s *userService) PendingEmailChange(ctx context.Context, id uuid.UUID) (*domain.PendingEmailChange, error) {
+	change, err := s.emailChangeRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change.IsExpired() {
+		return nil, domain.ErrNotFound
+	}
+	return change, nil
+}
+
+// ConfirmEmailChange swaps in the new email address the token was sent to, which is
+// verified by following the link.
+func (s *userService) ConfirmEmailChange(ctx context.Context, token string) (*domain.User, error) {
+	change, err := s.emailChangeRepo.GetByHash(ctx, hashResetToken(token))
+	if err != nil {
+		if domain.IsNotFoundError(err) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+	if change.IsExpired() {
+		_ = s.emailChangeRepo.Delete(ctx, change.UserID)
+		return nil, domain.ErrTokenExpired
+	}
+
+	user, err := s.userRepo.GetByID(ctx, change.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Email = change.NewEmail
+	user.EmailVerified = true
+	// A conflict here means another account took the address after the change was requested
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, err
 	}
+	s.sessionCache.deleteUser(user.ID)
 
+	if err := s.emailChangeRepo.Delete(ctx, user.ID); err != nil {
+		log.Printf("Failed to clear confirmed email change for user %s: %v", user.ID, err)
+	}
 	return user, nil
 }
 
@@ -124,11 +289,84 @@ func (s *userService) UpdateStatus(ctx context.Context, id uuid.UUID, status dom
 	}
 
 	user.Status = status
+	s.sessionCache.deleteUser(id)
 	return s.userRepo.Update(ctx, user)
 }
 
+// SecureAccount revokes all of the user's sessions and outstanding tokens in one step.
+func (s *userService) SecureAccount(ctx context.Context, id uuid.UUID) error {
+	if err := s.userRepo.RevokeCredentials(ctx, id); err != nil {
+		return err
+	}
+	s.sessionCache.deleteUser(id)
+	// Sessions were deleted in the revoke transaction; this also clears any cached copies
+	return s.sessionStore.DeleteByUserID(ctx, id)
+}
+
+// RevokeOAuthLink removes the user's link to provider and, where the provider supports it,
+// revokes the stored token with them. The link is removed even if the provider call fails;
+// the result reports whether the provider confirmed the revocation.
+func (s *userService) RevokeOAuthLink(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) (bool, error) {
+	link, err := s.oauthRepo.GetUserOAuthByUserID(ctx, userID, provider)
+	if err != nil {
+		return false, err
+	}
+
+	revoked := false
+	if config, err := s.oauthRepo.GetProvider(ctx, provider); err != nil {
+		log.Printf("Failed to load %s provider to revoke tokens for user %s: %v", provider, userID, err)
+	} else if !config.DecryptionFailed {
+		if revoked, err = revokeOAuthToken(ctx, config, link); err != nil {
+			log.Printf("Failed to revoke %s tokens for user %s: %v", provider, userID, err)
+		}
+	}
+
+	if err := s.oauthRepo.DeleteUserOAuth(ctx, userID, provider); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// ListOAuthLinks returns the providers linked to the user, without their tokens.
+func (s *userService) ListOAuthLinks(ctx context.Context, userID uuid.UUID) ([]*domain.UserOAuth, error) {
+	return s.oauthRepo.ListByUserID(ctx, userID)
+}
+
+// UnlinkOAuth removes the user's link to provider, revoking its token where possible. A user
+// without a password must keep at least one other linked provider.
+func (s *userService) UnlinkOAuth(ctx context.Context, userID uuid.UUID, provider domain.OAuthProviderType) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	links, err := s.oauthRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	linked, others := false, 0
+	for _, link := range links {
+		if link.Provider == provider {
+			linked = true
+		} else {
+			others++
+		}
+	}
+	if !linked {
+		return domain.ErrNotFound
+	}
+	if user.PasswordHash == "" && others == 0 {
+		return domain.ErrLastSignInMethod
+	}
+
+	_, err = s.RevokeOAuthLink(ctx, userID, provider)
+	return err
+}
+
 // DeleteUser removes a user.
 func (s *userService) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.sessionCache.deleteUser(id)
 	return s.userRepo.Delete(ctx, id)
 }
 
@@ -138,6 +376,9 @@ func (s *userService) UpdatePassword(ctx context.Context, id uuid.UUID, input *d
 	if err := input.ValidateNewPassword(); err != nil {
 		return err
 	}
+	if err := currentPasswordPolicy(ctx, s.settingsService).Check("new_password", input.NewPassword); err != nil {
+		return err
+	}
 
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -149,18 +390,19 @@ func (s *userService) UpdatePassword(ctx context.Context, id uuid.UUID, input *d
 		if input.CurrentPassword == "" {
 			return domain.ErrValidation{Field: "current_password", Message: "current password is required"}
 		}
-		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.CurrentPassword)); err != nil {
+		if !s.hasher.Verify(user.PasswordHash, input.CurrentPassword) {
 			return domain.ErrInvalidCredentials
 		}
 	}
 
 	// Hash new password
-	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	newHash, err := s.hasher.Hash(input.NewPassword)
 	if err != nil {
 		return err
 	}
 
-	user.PasswordHash = string(newHash)
+	user.PasswordHash = newHash
 
+	s.sessionCache.deleteUser(id)
 	return s.userRepo.Update(ctx, user)
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
 )
 
 // DatabaseStorage implements the Service interface using PostgreSQL.
@@ -36,7 +37,7 @@ func (s *DatabaseStorage) StoreProfileImage(ctx context.Context, userID uuid.UUI
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return domain.ErrNotFound
 	}
 
 	return nil
@@ -78,7 +79,7 @@ func (s *DatabaseStorage) DeleteProfileImage(ctx context.Context, userID uuid.UU
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return domain.ErrNotFound
 	}
 
 	return nil
@@ -0,0 +1,64 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/noruj-official/full-stack-go-template/internal/domain"
+)
+
+// GetActivityDigestEmailContent returns the HTML content for the account activity digest email.
+func GetActivityDigestEmailContent(name string, digest *domain.ActivityDigest, settingsLink string) string {
+	return fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+			<h2>Your account activity</h2>
+			<p>Hi %s,</p>
+			<p>Here is a summary of activity on your account from %s to %s.</p>
+			<h3>Sign-ins (%d)</h3>
+			%s
+			<h3>New devices (%d)</h3>
+			%s
+			<h3>Profile changes (%d)</h3>
+			%s
+			<p>If you don't recognize any of this activity, change your password and sign out of all devices from your settings.</p>
+			<p style="color: #666; font-size: 12px;">You are receiving this because you turned on activity digests. You can turn them off in your <a href="%s">account settings</a>.</p>
+		</div>
+	`,
+		html.EscapeString(name),
+		digest.Since.Format("Jan 02, 2006"),
+		digest.Until.Format("Jan 02, 2006"),
+		len(digest.Logins), activityList(digest.Logins),
+		len(digest.NewDevices), activityList(digest.NewDevices),
+		len(digest.ProfileChanges), activityList(digest.ProfileChanges),
+		settingsLink,
+	)
+}
+
+// activityList renders activity entries as an HTML list, escaping user-supplied values.
+func activityList(logs []*domain.ActivityLog) string {
+	if len(logs) == 0 {
+		return `<p style="color: #666;">None</p>`
+	}
+
+	var b strings.Builder
+	b.WriteString("<ul>")
+	for _, log := range logs {
+		b.WriteString("<li>")
+		b.WriteString(log.CreatedAt.Format("Jan 02, 2006 15:04 MST"))
+		b.WriteString(" &mdash; ")
+		b.WriteString(html.EscapeString(log.Description))
+		if log.IPAddress != nil {
+			b.WriteString(" from ")
+			b.WriteString(html.EscapeString(*log.IPAddress))
+		}
+		if log.UserAgent != nil {
+			b.WriteString(`<br><span style="color: #666; font-size: 12px;">`)
+			b.WriteString(html.EscapeString(*log.UserAgent))
+			b.WriteString("</span>")
+		}
+		b.WriteString("</li>")
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
@@ -0,0 +1,20 @@
+package email
+
+import "fmt"
+
+// GetEmailChangeEmailContent returns the HTML content for the email confirming a new address.
+func GetEmailChangeEmailContent(name, confirmLink string) string {
+	return fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+			<h2>Confirm your new email address</h2>
+			<p>Hi %s,</p>
+			<p>We received a request to change your account's email address to this one. Your account keeps its current address until you confirm by clicking the link below:</p>
+			<p>
+				<a href="%s" style="background-color: #0070f3; color: white; padding: 12px 24px; text-decoration: none; border-radius: 5px; display: inline-block;">Confirm Email</a>
+			</p>
+			<p>Or copy and paste this link into your browser:</p>
+			<p>%s</p>
+			<p>This link will expire in 24 hours. If you didn't request this change, you can safely ignore this email.</p>
+		</div>
+	`, name, confirmLink, confirmLink)
+}
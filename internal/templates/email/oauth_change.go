@@ -0,0 +1,34 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// GetOAuthChangeAlertEmailContent returns the HTML content for the email telling super
+// admins that an OAuth provider's credentials or endpoints were changed.
+func GetOAuthChangeAlertEmailContent(name, provider, changedBy string, fields []string, settingsLink string) string {
+	var items strings.Builder
+	for _, field := range fields {
+		items.WriteString("<li>" + html.EscapeString(field) + "</li>")
+	}
+
+	return fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+			<h2>OAuth settings changed</h2>
+			<p>Hi %s,</p>
+			<p>%s changed these settings of the <strong>%s</strong> sign-in provider:</p>
+			<ul>%s</ul>
+			<p>Whoever controls these settings controls who can sign in with %s. If you don't recognize this change, review the provider in the <a href="%s">OAuth settings</a> and the audit log now.</p>
+			<p style="color: #666; font-size: 12px;">You are receiving this because you are a super admin.</p>
+		</div>
+	`,
+		html.EscapeString(name),
+		html.EscapeString(changedBy),
+		html.EscapeString(provider),
+		items.String(),
+		html.EscapeString(provider),
+		settingsLink,
+	)
+}
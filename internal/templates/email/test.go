@@ -0,0 +1,23 @@
+package email
+
+import (
+	"fmt"
+	"html"
+)
+
+// GetTestEmailContent returns the HTML content for the test email a super admin sends
+// from the system health page to check email delivery.
+func GetTestEmailContent(name, appURL string) string {
+	return fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+			<h2>Test email</h2>
+			<p>Hi %s,</p>
+			<p>This is a test email from <a href="%s">%s</a>. If you can read it, email delivery is working.</p>
+			<p style="color: #666; font-size: 12px;">You are receiving this because you sent a test email from the system health page.</p>
+		</div>
+	`,
+		html.EscapeString(name),
+		appURL,
+		html.EscapeString(appURL),
+	)
+}
@@ -0,0 +1,44 @@
+package email
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// DefaultWelcomeEmailBody is the welcome email body used until a super admin overrides it.
+// It is an html/template with the fields of WelcomeEmailData available.
+const DefaultWelcomeEmailBody = `<p>Hi {{.Name}},</p>
+<p>Your email address is verified and your {{.AppName}} account is ready to use.</p>
+<p><a href="{{.DashboardLink}}">Go to your dashboard</a> to finish setting up your profile.</p>`
+
+// WelcomeEmailFields lists the template fields for display in the admin editor.
+const WelcomeEmailFields = "{{.Name}}, {{.AppName}}, {{.DashboardLink}}"
+
+// WelcomeEmailData is the data available to the welcome email body template.
+type WelcomeEmailData struct {
+	Name          string
+	AppName       string
+	DashboardLink string
+}
+
+// ParseWelcomeEmailBody parses a welcome email body template, reporting syntax errors.
+func ParseWelcomeEmailBody(body string) (*template.Template, error) {
+	return template.New("welcome").Parse(body)
+}
+
+// GetWelcomeEmailContent returns the HTML content for the welcome email, rendering body
+// as a template. Values are HTML-escaped by html/template.
+func GetWelcomeEmailContent(body string, data WelcomeEmailData) (string, error) {
+	tmpl, err := ParseWelcomeEmailBody(body)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">`)
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	buf.WriteString(`</div>`)
+	return buf.String(), nil
+}
@@ -0,0 +1,34 @@
+// Sends the CSRF token from the csrf_token cookie with every state-changing request:
+// as the X-CSRF-Token header on HTMX requests, and as a hidden _csrf field on plain
+// form posts that don't already carry one (e.g. pages served from the page cache).
+(function () {
+    function csrfToken() {
+        const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]+)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    window.csrfToken = csrfToken;
+
+    document.addEventListener('htmx:configRequest', function (e) {
+        const token = csrfToken();
+        if (token && e.detail.verb !== 'get') {
+            e.detail.headers['X-CSRF-Token'] = token;
+        }
+    });
+
+    document.addEventListener('submit', function (e) {
+        const form = e.target;
+        const token = csrfToken();
+        if (!token || !(form instanceof HTMLFormElement) || form.method.toLowerCase() !== 'post') {
+            return;
+        }
+        let field = form.querySelector('input[name="_csrf"]');
+        if (!field) {
+            field = document.createElement('input');
+            field.type = 'hidden';
+            field.name = '_csrf';
+            form.appendChild(field);
+        }
+        field.value = token;
+    }, true);
+})();
@@ -64330,6 +64330,7 @@ ${nextLine.slice(indentLevel + 2)}`;
       try {
         const response = await fetch("/api/media/upload", {
           method: "POST",
+          headers: { "X-CSRF-Token": window.csrfToken?.() ?? "" },
           body: formData
         });
         if (!response.ok) {
@@ -76,6 +76,7 @@ const MenuBar = ({ editor }) => {
         try {
             const response = await fetch('/api/media/upload', {
                 method: 'POST',
+                headers: { 'X-CSRF-Token': window.csrfToken?.() ?? '' },
                 body: formData,
             })
 